@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/gndm/ytToDeemix/internal/deemix"
+	"github.com/gndm/ytToDeemix/internal/sync"
+)
+
+// Exit codes for the sync subcommand.
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// batchOptions configures a headless batch run.
+type batchOptions struct {
+	Bitrate         int
+	CheckNavidrome  bool
+	DryRun          bool
+	CreatePlaylists bool
+}
+
+// batchResult is the outcome of one URL in a batch run.
+type batchResult struct {
+	URL         string
+	Status      string
+	Error       string
+	Found       int
+	NeedsReview int
+	Skipped     int
+	NotFound    int
+	Queued      int
+	Failed      int
+	Unavailable int
+}
+
+// runSync implements "ytToDeemix sync": analyze each URL, queue the tracks
+// at or above the confidence threshold and print a summary. Returns the
+// process exit code.
+func runSync(args []string) int {
+	fs := flag.NewFlagSet("sync", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s sync [flags] [URL...]\n\nFlags:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	file := fs.String("file", "", "read URLs from `path`, one per line (- for stdin)")
+	bitrate := fs.String("bitrate", "128", "download quality: 128, 320 or flac")
+	checkNavidrome := fs.Bool("navidrome", os.Getenv("NAVIDROME_SKIP_DEFAULT") == "true", "skip tracks already in Navidrome")
+	dryRun := fs.Bool("dry-run", false, "analyze only, don't queue downloads")
+	cookies := fs.String("cookies", "", "sign in to YouTube with the cookies.txt at `path` (default $YTDLP_COOKIES)")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	opts := batchOptions{CheckNavidrome: *checkNavidrome, DryRun: *dryRun}
+	switch strings.ToLower(*bitrate) {
+	case "128":
+		opts.Bitrate = deemix.Bitrate128
+	case "320":
+		opts.Bitrate = deemix.Bitrate320
+	case "flac":
+		opts.Bitrate = deemix.BitrateFLAC
+	default:
+		fmt.Fprintf(os.Stderr, "invalid bitrate %q\n", *bitrate)
+		return exitUsage
+	}
+
+	urls := fs.Args()
+	if *file != "" {
+		fromFile, err := readURLs(*file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		urls = append(urls, fromFile...)
+	}
+	if len(urls) == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	if *cookies != "" {
+		if _, err := os.Stat(*cookies); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	pipeline, c := newPipeline()
+	if *cookies != "" {
+		c.yt.CookiesFile = *cookies
+	}
+	if opts.CheckNavidrome && !c.navidromeConfigured {
+		fmt.Fprintln(os.Stderr, "-navidrome needs NAVIDROME_URL, NAVIDROME_USER and NAVIDROME_PASSWORD")
+		return exitUsage
+	}
+	if c.createPlaylists {
+		// The process exits after the batch, so the playlists are synced
+		// in runOne instead of in the background.
+		pipeline.SetCreatePlaylists(false)
+		opts.CreatePlaylists = true
+	}
+
+	results := runBatch(ctx, pipeline, urls, opts)
+	if err := c.cache.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to save search cache: %v\n", err)
+	}
+	printSummary(os.Stdout, results)
+
+	for _, r := range results {
+		if r.Error != "" || r.Failed > 0 {
+			return exitFailure
+		}
+	}
+	return exitOK
+}
+
+// readURLs reads one URL per line. Blank lines and lines starting with #
+// are ignored.
+func readURLs(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading URL file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var urls []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading URL file: %w", err)
+	}
+	return urls, nil
+}
+
+// runBatch analyzes the URLs one after another and, unless DryRun is set,
+// downloads the selected tracks of each. Stops early if ctx is canceled.
+func runBatch(ctx context.Context, pipeline *sync.Pipeline, urls []string, opts batchOptions) []batchResult {
+	results := make([]batchResult, 0, len(urls))
+	for _, url := range urls {
+		if ctx.Err() != nil {
+			results = append(results, batchResult{URL: url, Status: sync.StatusCanceled, Error: "interrupted"})
+			continue
+		}
+		if !isValidYouTubeURL(url) {
+			results = append(results, batchResult{URL: url, Status: sync.StatusError, Error: "invalid YouTube URL"})
+			continue
+		}
+		results = append(results, runOne(ctx, pipeline, url, opts))
+	}
+	return results
+}
+
+func runOne(ctx context.Context, pipeline *sync.Pipeline, url string, opts batchOptions) batchResult {
+	id := pipeline.Analyze(context.Background(), url, opts.Bitrate, opts.CheckNavidrome)
+	session, err := pipeline.WaitSettled(ctx, id)
+	if err != nil {
+		pipeline.CancelSession(id)
+		return batchResult{URL: url, Status: sync.StatusCanceled, Error: "interrupted"}
+	}
+
+	if session.Status == sync.StatusReady && !opts.DryRun {
+		if err := pipeline.Download(ctx, id); err != nil {
+			return batchResult{URL: url, Status: sync.StatusError, Error: err.Error()}
+		}
+		if opts.CreatePlaylists {
+			if err := pipeline.SyncPlaylistAfterDownload(ctx, id); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: failed to sync the Navidrome playlist of %s: %v\n", url, err)
+			}
+		}
+		session, _ = pipeline.GetSession(id)
+	}
+
+	result := batchResult{URL: url, Status: session.Status, Error: session.Error, Queued: session.Progress.Queued}
+	if session.Status != sync.StatusReady && session.Status != sync.StatusDone && result.Error == "" {
+		result.Error = session.Status
+	}
+	for _, t := range session.Tracks {
+		switch t.Status {
+		case sync.TrackFound, sync.TrackQueued, sync.TrackDownloading, sync.TrackDownloaded:
+			result.Found++
+		case sync.TrackError:
+			// Search errors have no match; download errors do.
+			if t.DeezerMatch != nil {
+				result.Found++
+			}
+			result.Failed++
+		case sync.TrackNeedsReview:
+			result.NeedsReview++
+		case sync.TrackSkipped:
+			result.Skipped++
+		case sync.TrackNotFound:
+			result.NotFound++
+		case sync.TrackUnavailable:
+			result.Unavailable++
+		}
+	}
+	return result
+}
+
+// printSummary writes one row per URL, a total row, and any errors.
+func printSummary(w io.Writer, results []batchResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FOUND\tREVIEW\tSKIPPED\tNOT FOUND\tUNAVAILABLE\tQUEUED\tFAILED\tSTATUS\tURL")
+
+	var total batchResult
+	for _, r := range results {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%d\t%d\t%d\t%s\t%s\n", r.Found, r.NeedsReview, r.Skipped, r.NotFound, r.Unavailable, r.Queued, r.Failed, r.Status, r.URL)
+		total.Found += r.Found
+		total.NeedsReview += r.NeedsReview
+		total.Skipped += r.Skipped
+		total.NotFound += r.NotFound
+		total.Unavailable += r.Unavailable
+		total.Queued += r.Queued
+		total.Failed += r.Failed
+	}
+	fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%d\t%d\t%d\t\tTOTAL\n", total.Found, total.NeedsReview, total.Skipped, total.NotFound, total.Unavailable, total.Queued, total.Failed)
+	tw.Flush()
+
+	for _, r := range results {
+		if r.Error != "" {
+			fmt.Fprintf(w, "error: %s: %s\n", r.URL, r.Error)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gndm/ytToDeemix/internal/deemix"
+	"github.com/gndm/ytToDeemix/internal/sync"
+)
+
+func TestRunBatch(t *testing.T) {
+	pipeline := testPipeline()
+	pipeline.SetConfidenceThreshold(0)
+
+	results := runBatch(context.Background(), pipeline, []string{
+		"https://youtube.com/playlist?list=test",
+		"https://example.com/not-youtube",
+	}, batchOptions{Bitrate: deemix.Bitrate128})
+
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if r := results[0]; r.Status != sync.StatusDone || r.Found != 1 || r.Queued != 1 || r.Error != "" {
+		t.Errorf("first result = %+v, want one track queued", r)
+	}
+	if r := results[1]; r.Error == "" {
+		t.Errorf("second result = %+v, want invalid URL error", r)
+	}
+
+	var buf bytes.Buffer
+	printSummary(&buf, results)
+	out := buf.String()
+	for _, want := range []string{"FOUND", "TOTAL", "error: https://example.com/not-youtube: invalid YouTube URL"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("summary missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunBatchDryRun(t *testing.T) {
+	pipeline := testPipeline()
+	pipeline.SetConfidenceThreshold(0)
+
+	results := runBatch(context.Background(), pipeline, []string{"https://youtube.com/playlist?list=test"}, batchOptions{Bitrate: deemix.Bitrate128, DryRun: true})
+	if r := results[0]; r.Status != sync.StatusReady || r.Found != 1 || r.Queued != 0 {
+		t.Errorf("result = %+v, want analyzed but not queued", r)
+	}
+}
+
+func TestReadURLs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.txt")
+	os.WriteFile(path, []byte("# weekly\nhttps://youtube.com/playlist?list=a\n\n  https://youtube.com/playlist?list=b  \n"), 0o644)
+
+	urls, err := readURLs(path)
+	if err != nil {
+		t.Fatalf("readURLs() error = %v", err)
+	}
+	if len(urls) != 2 || urls[1] != "https://youtube.com/playlist?list=b" {
+		t.Errorf("urls = %q", urls)
+	}
+	if _, err := readURLs(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("readURLs() of missing file should fail")
+	}
+}
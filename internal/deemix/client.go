@@ -11,6 +11,8 @@ import (
 	"net/http/cookiejar"
 	"net/url"
 	"strconv"
+	"sync"
+	"time"
 )
 
 // Client defines the interface for interacting with Deemix.
@@ -20,11 +22,50 @@ type Client interface {
 	AddToQueue(ctx context.Context, deezerURL string, bitrate int) error
 }
 
+// QueueClient is a Client that can also report the state of Deemix's
+// download queue, so callers can follow a download past queue acceptance.
+type QueueClient interface {
+	Client
+	Queue(ctx context.Context) (map[string]QueueItem, error)
+}
+
+// ISRCClient is a Client that can also look up a track by its ISRC, which
+// identifies one recording exactly.
+type ISRCClient interface {
+	Client
+	// SearchISRC returns the Deezer track with the given ISRC, or nil if
+	// Deezer has none.
+	SearchISRC(ctx context.Context, isrc string) (*SearchResult, error)
+}
+
+// DefaultDeezerAPI is Deezer's public API, used for lookups Deemix doesn't
+// offer.
+const DefaultDeezerAPI = "https://api.deezer.com"
+
+// QueueID returns the UUID Deemix gives a single track queued at the given
+// bitrate.
+func QueueID(trackID int64, bitrate int) string {
+	return "track_" + strconv.FormatInt(trackID, 10) + "_" + strconv.Itoa(bitrate)
+}
+
 // HTTPClient implements Client using Deemix's HTTP API.
+// Requests that Deemix rejects as unauthenticated trigger a new login with
+// the ARL and are retried once, unless Deezer already rejected that ARL.
+// Safe for concurrent use.
 type HTTPClient struct {
-	BaseURL    string
-	ARL        string
+	BaseURL string
+	ARL     string
+	// DeezerAPI is the base URL of Deezer's public API, for ISRC lookups.
+	// Defaults to DefaultDeezerAPI.
+	DeezerAPI  string
 	HTTPClient *http.Client
+
+	loginMu  sync.Mutex // serializes re-logins
+	authMu   sync.Mutex
+	auth     AuthStatus
+	loginGen int // incremented on every successful login
+	// rejectedARL is the ARL Deezer rejected, while the state is AuthInvalid.
+	rejectedARL string
 }
 
 // NewClient creates a new Deemix HTTPClient.
@@ -33,10 +74,48 @@ func NewClient(baseURL, arl string) *HTTPClient {
 	return &HTTPClient{
 		BaseURL:    baseURL,
 		ARL:        arl,
+		DeezerAPI:  DefaultDeezerAPI,
 		HTTPClient: &http.Client{Jar: jar},
 	}
 }
 
+// Auth returns the last known state of the Deemix login.
+func (c *HTTPClient) Auth() AuthStatus {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	if c.auth.State == "" {
+		return AuthStatus{State: AuthUnknown}
+	}
+	return c.auth
+}
+
+func (c *HTTPClient) setAuth(state string, err error) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	c.auth = AuthStatus{State: state, CheckedAt: time.Now()}
+	if err != nil {
+		c.auth.Error = err.Error()
+	}
+	switch state {
+	case AuthOK:
+		c.loginGen++
+		c.rejectedARL = ""
+	case AuthInvalid:
+		c.rejectedARL = c.ARL
+	}
+}
+
+// expire marks the login as expired, unless Deezer rejected the ARL, which
+// stays the more useful state to report.
+func (c *HTTPClient) expire() {
+	c.authMu.Lock()
+	invalid := c.auth.State == AuthInvalid
+	c.authMu.Unlock()
+	if !invalid {
+		c.setAuth(AuthExpired, ErrNotLoggedIn)
+	}
+}
+
 // Login authenticates with the Deemix instance using the ARL token.
 func (c *HTTPClient) Login(ctx context.Context) error {
 	log.Printf("[deemix] logging in to %s", c.BaseURL)
@@ -46,35 +125,121 @@ func (c *HTTPClient) Login(ctx context.Context) error {
 		return fmt.Errorf("creating login request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	// Logging in twice is harmless, so let the transport retry it.
+	req.Header["Idempotency-Key"] = nil
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
 		log.Printf("[deemix] login request failed: %v", err)
-		return fmt.Errorf("login request failed: %w", err)
+		err = fmt.Errorf("login request failed: %w", err)
+		c.setAuth(AuthUnknown, err)
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
 		log.Printf("[deemix] login failed (status %d): %s", resp.StatusCode, string(respBody))
-		return fmt.Errorf("login failed (status %d): %s", resp.StatusCode, string(respBody))
+		err := fmt.Errorf("login failed (status %d): %s", resp.StatusCode, string(respBody))
+		c.setAuth(AuthUnknown, err)
+		return err
 	}
 
 	var result struct {
 		Status int `json:"status"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return fmt.Errorf("decoding login response: %w", err)
+		err = fmt.Errorf("decoding login response: %w", err)
+		c.setAuth(AuthUnknown, err)
+		return err
 	}
 	if result.Status == 0 {
 		log.Printf("[deemix] login failed: invalid ARL token")
-		return fmt.Errorf("login failed: invalid ARL token")
+		err := fmt.Errorf("login failed: invalid ARL token")
+		c.setAuth(AuthInvalid, err)
+		return err
 	}
 
+	c.setAuth(AuthOK, nil)
 	log.Printf("[deemix] login successful")
 	return nil
 }
 
+// relogin logs in again after a request was rejected as unauthenticated.
+// gen is the login generation seen before that request; if another request
+// has logged in since, relogin returns at once. It doesn't try an ARL Deezer
+// already rejected: that takes a new ARL, or a successful Login.
+func (c *HTTPClient) relogin(ctx context.Context, gen int) error {
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+
+	c.authMu.Lock()
+	current := c.loginGen
+	rejected := c.auth.State == AuthInvalid && c.rejectedARL == c.ARL
+	c.authMu.Unlock()
+	if current != gen {
+		return nil
+	}
+	if rejected {
+		return fmt.Errorf("login failed: invalid ARL token")
+	}
+
+	c.setAuth(AuthExpired, ErrNotLoggedIn)
+	log.Printf("[deemix] session expired, logging in again")
+	return c.Login(ctx)
+}
+
+// do sends the request built by newReq and returns the response status and
+// body. If Deemix answers as unauthenticated, it logs in again and retries
+// once. newReq is called for every attempt so request bodies can be re-read.
+func (c *HTTPClient) do(ctx context.Context, newReq func() (*http.Request, error)) (int, []byte, error) {
+	for attempt := 0; ; attempt++ {
+		c.authMu.Lock()
+		gen := c.loginGen
+		c.authMu.Unlock()
+
+		req, err := newReq()
+		if err != nil {
+			return 0, nil, err
+		}
+		resp, err := c.HTTPClient.Do(req)
+		if err != nil {
+			return 0, nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if !isAuthFailure(resp.StatusCode, body) {
+			return resp.StatusCode, body, nil
+		}
+		if attempt > 0 {
+			c.expire()
+			return 0, nil, ErrNotLoggedIn
+		}
+		if err := c.relogin(ctx, gen); err != nil {
+			return 0, nil, fmt.Errorf("%w: %v", ErrNotLoggedIn, err)
+		}
+	}
+}
+
+// isAuthFailure reports whether a Deemix response means the session is not
+// logged in: an HTTP 401/403, or Deemix's NotLoggedIn error ID.
+func isAuthFailure(status int, body []byte) bool {
+	if status == http.StatusUnauthorized || status == http.StatusForbidden {
+		return true
+	}
+	var result struct {
+		ErrID string `json:"errid"`
+	}
+	if json.Unmarshal(body, &result) != nil {
+		return false
+	}
+	return result.ErrID == "NotLoggedIn"
+}
+
 // Search queries Deemix for tracks matching the given query string.
 func (c *HTTPClient) Search(ctx context.Context, query string) ([]SearchResult, error) {
 	log.Printf("[deemix] searching: %s", query)
@@ -84,21 +249,21 @@ func (c *HTTPClient) Search(ctx context.Context, query string) ([]SearchResult,
 		"nb":   {"5"},
 	}.Encode()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating search request: %w", err)
-	}
-
-	resp, err := c.HTTPClient.Do(req)
+	status, body, err := c.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, fmt.Errorf("creating search request: %w", err)
+		}
+		return req, nil
+	})
 	if err != nil {
 		log.Printf("[deemix] search request failed: %v", err)
 		return nil, fmt.Errorf("search request failed: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("[deemix] search failed with status %d for query: %s", resp.StatusCode, query)
-		return nil, fmt.Errorf("search failed (status %d)", resp.StatusCode)
+	if status != http.StatusOK {
+		log.Printf("[deemix] search failed with status %d for query: %s", status, query)
+		return nil, fmt.Errorf("search failed (status %d)", status)
 	}
 
 	var apiResp struct {
@@ -115,7 +280,7 @@ func (c *HTTPClient) Search(ctx context.Context, query string) ([]SearchResult,
 			Link     string `json:"link"`
 		} `json:"data"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+	if err := json.Unmarshal(body, &apiResp); err != nil {
 		return nil, fmt.Errorf("decoding search response: %w", err)
 	}
 
@@ -139,6 +304,77 @@ func (c *HTTPClient) Search(ctx context.Context, query string) ([]SearchResult,
 	return results, nil
 }
 
+// SearchISRC looks a track up by ISRC in Deezer's public API. Deemix has no
+// such endpoint, but needs no login for it either.
+func (c *HTTPClient) SearchISRC(ctx context.Context, isrc string) (*SearchResult, error) {
+	log.Printf("[deemix] looking up ISRC: %s", isrc)
+	api := c.DeezerAPI
+	if api == "" {
+		api = DefaultDeezerAPI
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, api+"/track/isrc:"+url.PathEscape(isrc), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating ISRC request: %w", err)
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		log.Printf("[deemix] ISRC request failed: %v", err)
+		return nil, fmt.Errorf("ISRC request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ISRC lookup failed (status %d)", resp.StatusCode)
+	}
+
+	// Deezer answers 200 with an error object when the ISRC is unknown.
+	var track struct {
+		ID     int64  `json:"id"`
+		Title  string `json:"title"`
+		Artist struct {
+			Name string `json:"name"`
+		} `json:"artist"`
+		Album struct {
+			Title string `json:"title"`
+		} `json:"album"`
+		Duration int    `json:"duration"`
+		Link     string `json:"link"`
+		Error    *struct {
+			Message string `json:"message"`
+			Code    int    `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&track); err != nil {
+		return nil, fmt.Errorf("decoding ISRC response: %w", err)
+	}
+	if track.Error != nil {
+		if track.Error.Code == deezerNotFound {
+			log.Printf("[deemix] ISRC not on Deezer: %s", isrc)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ISRC lookup failed: %s", track.Error.Message)
+	}
+	if track.ID == 0 {
+		return nil, nil
+	}
+
+	link := track.Link
+	if link == "" {
+		link = "https://www.deezer.com/track/" + strconv.FormatInt(track.ID, 10)
+	}
+	return &SearchResult{
+		ID:       track.ID,
+		Title:    track.Title,
+		Artist:   track.Artist.Name,
+		Album:    track.Album.Title,
+		Duration: track.Duration,
+		Link:     link,
+	}, nil
+}
+
+// deezerNotFound is the error code of Deezer's API for a missing object.
+const deezerNotFound = 800
+
 // AddToQueue adds a track to the Deemix download queue.
 func (c *HTTPClient) AddToQueue(ctx context.Context, deezerURL string, bitrate int) error {
 	log.Printf("[deemix] adding to queue: %s (bitrate: %d)", deezerURL, bitrate)
@@ -147,25 +383,112 @@ func (c *HTTPClient) AddToQueue(ctx context.Context, deezerURL string, bitrate i
 		"bitrate": bitrate,
 	})
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/api/addToQueue", bytes.NewReader(body))
-	if err != nil {
-		return fmt.Errorf("creating queue request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.HTTPClient.Do(req)
+	status, respBody, err := c.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/api/addToQueue", bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("creating queue request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
 	if err != nil {
 		log.Printf("[deemix] queue request failed: %v", err)
 		return fmt.Errorf("queue request failed: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		log.Printf("[deemix] queue failed (status %d) for %s: %s", resp.StatusCode, deezerURL, string(respBody))
-		return fmt.Errorf("queue failed (status %d): %s", resp.StatusCode, string(respBody))
+	if status != http.StatusOK {
+		log.Printf("[deemix] queue failed (status %d) for %s: %s", status, deezerURL, string(respBody))
+		return fmt.Errorf("queue failed (status %d): %s", status, string(respBody))
 	}
 
 	log.Printf("[deemix] queued successfully: %s", deezerURL)
 	return nil
 }
+
+// Queue returns every item in Deemix's download queue, keyed by UUID.
+// Finished items stay in the queue until they are cleared in Deemix.
+func (c *HTTPClient) Queue(ctx context.Context) (map[string]QueueItem, error) {
+	status, body, err := c.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/api/getQueue", nil)
+		if err != nil {
+			return nil, fmt.Errorf("creating queue status request: %w", err)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("queue status request failed: %w", err)
+	}
+
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("queue status failed (status %d)", status)
+	}
+
+	var apiResp struct {
+		Queue   map[string]queueObject `json:"queue"`
+		Current *queueObject           `json:"current"`
+	}
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, fmt.Errorf("decoding queue response: %w", err)
+	}
+
+	items := make(map[string]QueueItem, len(apiResp.Queue))
+	for uuid, obj := range apiResp.Queue {
+		if obj.UUID == "" {
+			obj.UUID = uuid
+		}
+		items[obj.UUID] = obj.item()
+	}
+	// The current download carries fresher counters than its queue entry.
+	if cur := apiResp.Current; cur != nil && cur.UUID != "" {
+		item := cur.item()
+		if !item.Finished() {
+			item.Status = QueueDownloading
+		}
+		items[cur.UUID] = item
+	}
+	return items, nil
+}
+
+// queueObject is a download object as returned by Deemix's getQueue.
+type queueObject struct {
+	UUID       string  `json:"uuid"`
+	Status     string  `json:"status"`
+	Size       int     `json:"size"`
+	Downloaded int     `json:"downloaded"`
+	Failed     int     `json:"failed"`
+	Progress   float64 `json:"progress"`
+	Errors     []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (o queueObject) item() QueueItem {
+	item := QueueItem{
+		UUID:       o.UUID,
+		Status:     o.Status,
+		Size:       o.Size,
+		Downloaded: o.Downloaded,
+		Failed:     o.Failed,
+		Progress:   o.Progress,
+	}
+	if len(o.Errors) > 0 {
+		item.Error = o.Errors[0].Message
+	}
+
+	// Older Deemix versions leave status unset; derive it from the counters.
+	if item.Status == "" {
+		switch {
+		case item.Size > 0 && item.Downloaded+item.Failed >= item.Size && item.Failed == 0:
+			item.Status = QueueCompleted
+		case item.Size > 0 && item.Downloaded+item.Failed >= item.Size && item.Downloaded == 0:
+			item.Status = QueueFailed
+		case item.Size > 0 && item.Downloaded+item.Failed >= item.Size:
+			item.Status = QueueWithErrors
+		case item.Progress > 0:
+			item.Status = QueueDownloading
+		default:
+			item.Status = QueueInQueue
+		}
+	}
+	return item
+}
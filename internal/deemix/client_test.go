@@ -3,8 +3,10 @@ package deemix
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 )
 
@@ -114,6 +116,46 @@ func TestSearchError(t *testing.T) {
 	}
 }
 
+func TestSearchISRC(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/track/isrc:USUM71814888":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"id":       1234,
+				"title":    "Sunflower",
+				"artist":   map[string]string{"name": "Post Malone"},
+				"album":    map[string]string{"title": "Hollywood's Bleeding"},
+				"duration": 158,
+			})
+		case "/track/isrc:BROKEN000001":
+			w.Write([]byte(`{"error":{"type":"Exception","message":"Quota limit exceeded","code":4}}`))
+		default:
+			w.Write([]byte(`{"error":{"type":"DataException","message":"no data","code":800}}`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("http://deemix.invalid", "token")
+	client.DeezerAPI = server.URL
+
+	result, err := client.SearchISRC(context.Background(), "USUM71814888")
+	if err != nil {
+		t.Fatalf("SearchISRC() error = %v", err)
+	}
+	if result == nil || result.ID != 1234 || result.Artist != "Post Malone" || result.Link != "https://www.deezer.com/track/1234" {
+		t.Errorf("SearchISRC() = %+v, want track 1234 with a generated link", result)
+	}
+
+	result, err = client.SearchISRC(context.Background(), "XX0000000000")
+	if err != nil || result != nil {
+		t.Errorf("SearchISRC(unknown) = %+v, %v, want nil, nil", result, err)
+	}
+
+	if _, err := client.SearchISRC(context.Background(), "BROKEN000001"); err == nil {
+		t.Error("SearchISRC() should fail on an API error other than not found")
+	}
+}
+
 func TestAddToQueue(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/api/addToQueue" {
@@ -154,3 +196,136 @@ func TestAddToQueueError(t *testing.T) {
 		t.Fatal("expected error for 400 response")
 	}
 }
+
+func TestQueue(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/getQueue" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.Write([]byte(`{
+			"queue": {
+				"track_1_3": {"uuid": "track_1_3", "status": "completed", "size": 1, "downloaded": 1},
+				"track_2_3": {"uuid": "track_2_3", "size": 1, "failed": 1, "errors": [{"message": "Track not available in your country"}]},
+				"track_3_3": {"uuid": "track_3_3", "status": "inQueue", "size": 1},
+				"track_4_3": {"uuid": "track_4_3", "status": "inQueue", "size": 1}
+			},
+			"queueOrder": ["track_3_3", "track_4_3"],
+			"current": {"uuid": "track_3_3", "size": 1, "progress": 40}
+		}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "token")
+	items, err := client.Queue(context.Background())
+	if err != nil {
+		t.Fatalf("Queue() error = %v", err)
+	}
+
+	tests := []struct {
+		uuid   string
+		status string
+		error  string
+	}{
+		{"track_1_3", QueueCompleted, ""},
+		{"track_2_3", QueueFailed, "Track not available in your country"},
+		{"track_3_3", QueueDownloading, ""},
+		{"track_4_3", QueueInQueue, ""},
+	}
+	for _, tt := range tests {
+		item, ok := items[tt.uuid]
+		if !ok {
+			t.Errorf("%s missing from queue", tt.uuid)
+			continue
+		}
+		if item.Status != tt.status {
+			t.Errorf("%s status = %q, want %q", tt.uuid, item.Status, tt.status)
+		}
+		if item.Error != tt.error {
+			t.Errorf("%s error = %q, want %q", tt.uuid, item.Error, tt.error)
+		}
+	}
+	if got := QueueID(1, Bitrate320); got != "track_1_3" {
+		t.Errorf("QueueID = %q, want track_1_3", got)
+	}
+}
+
+func TestReloginOnAuthFailure(t *testing.T) {
+	var logins, queued atomic.Int32
+	var loggedIn atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/loginArl":
+			logins.Add(1)
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["arl"] != "valid-token" {
+				json.NewEncoder(w).Encode(map[string]int{"status": 0})
+				return
+			}
+			loggedIn.Store(true)
+			json.NewEncoder(w).Encode(map[string]int{"status": 1})
+		case "/api/addToQueue":
+			if !loggedIn.Load() {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"result":false,"errid":"NotLoggedIn"}`))
+				return
+			}
+			queued.Add(1)
+		}
+	}))
+	defer server.Close()
+
+	t.Run("expired session", func(t *testing.T) {
+		client := NewClient(server.URL, "valid-token")
+		if got := client.Auth().State; got != AuthUnknown {
+			t.Errorf("initial state = %q, want %q", got, AuthUnknown)
+		}
+		if err := client.AddToQueue(context.Background(), "https://www.deezer.com/track/1", Bitrate320); err != nil {
+			t.Fatalf("AddToQueue() error = %v", err)
+		}
+		if logins.Load() != 1 || queued.Load() != 1 {
+			t.Errorf("logins = %d, queued = %d, want 1 and 1", logins.Load(), queued.Load())
+		}
+		if got := client.Auth().State; got != AuthOK {
+			t.Errorf("state = %q, want %q", got, AuthOK)
+		}
+	})
+
+	t.Run("invalid ARL", func(t *testing.T) {
+		loggedIn.Store(false)
+		logins.Store(0)
+		client := NewClient(server.URL, "bad-token")
+		err := client.AddToQueue(context.Background(), "https://www.deezer.com/track/1", Bitrate320)
+		if !errors.Is(err, ErrNotLoggedIn) {
+			t.Fatalf("AddToQueue() error = %v, want ErrNotLoggedIn", err)
+		}
+		if logins.Load() != 1 {
+			t.Errorf("logins = %d, want 1", logins.Load())
+		}
+		auth := client.Auth()
+		if auth.State != AuthInvalid || auth.Error == "" {
+			t.Errorf("auth = %+v, want invalid with an error", auth)
+		}
+
+		// The rejected ARL is not tried again, and the state stays invalid.
+		err = client.AddToQueue(context.Background(), "https://www.deezer.com/track/1", Bitrate320)
+		if !errors.Is(err, ErrNotLoggedIn) {
+			t.Fatalf("AddToQueue() error = %v, want ErrNotLoggedIn", err)
+		}
+		if logins.Load() != 1 {
+			t.Errorf("logins = %d, want 1", logins.Load())
+		}
+		if got := client.Auth().State; got != AuthInvalid {
+			t.Errorf("state = %q, want %q", got, AuthInvalid)
+		}
+
+		// A new ARL is tried.
+		client.ARL = "valid-token"
+		if err := client.AddToQueue(context.Background(), "https://www.deezer.com/track/1", Bitrate320); err != nil {
+			t.Fatalf("AddToQueue() with a new ARL error = %v", err)
+		}
+		if got := client.Auth().State; got != AuthOK {
+			t.Errorf("state = %q, want %q", got, AuthOK)
+		}
+	})
+}
@@ -1,5 +1,29 @@
 package deemix
 
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotLoggedIn is returned when Deemix still rejects a request as
+// unauthenticated after logging in again.
+var ErrNotLoggedIn = errors.New("deemix is not logged in")
+
+// Auth states reported by HTTPClient.Auth.
+const (
+	AuthUnknown = "unknown" // no login attempted yet, or Deemix unreachable
+	AuthOK      = "ok"
+	AuthExpired = "expired" // session lost, re-login pending or failed
+	AuthInvalid = "invalid" // Deezer rejected the ARL
+)
+
+// AuthStatus is the last known state of the Deemix login.
+type AuthStatus struct {
+	State     string    `json:"state"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at,omitzero"`
+}
+
 // SearchResult represents a track found on Deezer via Deemix.
 type SearchResult struct {
 	ID       int64  `json:"id"`
@@ -16,3 +40,33 @@ const (
 	Bitrate320  = 3 // MP3 320kbps
 	Bitrate128  = 1 // MP3 128kbps
 )
+
+// QueueItem is the state of one download in Deemix's queue.
+type QueueItem struct {
+	UUID       string  `json:"uuid"`
+	Status     string  `json:"status"`
+	Size       int     `json:"size"`
+	Downloaded int     `json:"downloaded"`
+	Failed     int     `json:"failed"`
+	Progress   float64 `json:"progress"`
+	// Error is the reason Deemix gave for the first failed track, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// Queue status constants, as reported by Deemix.
+const (
+	QueueInQueue     = "inQueue"
+	QueueDownloading = "downloading"
+	QueueCompleted   = "completed"
+	QueueWithErrors  = "withErrors"
+	QueueFailed      = "failed"
+)
+
+// Finished reports whether Deemix is done with the item.
+func (q QueueItem) Finished() bool {
+	switch q.Status {
+	case QueueCompleted, QueueWithErrors, QueueFailed:
+		return true
+	}
+	return false
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
 )
 
@@ -15,6 +17,18 @@ type Client interface {
 	Search(ctx context.Context, artist, title string) ([]SearchResult, error)
 }
 
+// PlaylistClient manages playlists and library scans on a Navidrome/Subsonic
+// instance.
+type PlaylistClient interface {
+	Client
+	StartScan(ctx context.Context) error
+	Scanning(ctx context.Context) (bool, error)
+	Playlists(ctx context.Context) ([]Playlist, error)
+	PlaylistSongs(ctx context.Context, id string) ([]string, error)
+	CreatePlaylist(ctx context.Context, name string, songIDs []string) (string, error)
+	UpdatePlaylist(ctx context.Context, id string, addSongIDs []string) error
+}
+
 // HTTPClient implements Client using the Subsonic REST API.
 type HTTPClient struct {
 	BaseURL   string
@@ -26,13 +40,25 @@ type HTTPClient struct {
 
 // subsonicResponse represents the outer JSON envelope from the Subsonic API.
 type subsonicResponse struct {
-	SubsonicResponse struct {
-		Status        string                    `json:"status"`
-		Error         *struct{ Message string } `json:"error,omitempty"`
-		SearchResult2 struct {
-			Song []subsonicSong `json:"song"`
-		} `json:"searchResult2"`
-	} `json:"subsonic-response"`
+	SubsonicResponse subsonicBody `json:"subsonic-response"`
+}
+
+type subsonicBody struct {
+	Status        string                    `json:"status"`
+	Error         *struct{ Message string } `json:"error,omitempty"`
+	SearchResult2 struct {
+		Song []subsonicSong `json:"song"`
+	} `json:"searchResult2"`
+	Playlists struct {
+		Playlist []subsonicPlaylist `json:"playlist"`
+	} `json:"playlists"`
+	Playlist struct {
+		subsonicPlaylist
+		Entry []subsonicSong `json:"entry"`
+	} `json:"playlist"`
+	ScanStatus struct {
+		Scanning bool `json:"scanning"`
+	} `json:"scanStatus"`
 }
 
 type subsonicSong struct {
@@ -43,24 +69,50 @@ type subsonicSong struct {
 	Duration int    `json:"duration"`
 }
 
-func (c *HTTPClient) Search(ctx context.Context, artist, title string) ([]SearchResult, error) {
-	query := artist + " " + title
-	log.Printf("[navidrome] checking library: %s - %s", artist, title)
-	params := url.Values{
-		"query":     {query},
-		"songCount": {"5"},
-		"f":         {"json"},
-		"v":         {"1.16.1"},
-		"c":         {"ytToDeemix"},
-		"u":         {c.User},
-		"p":         {c.Password},
+type subsonicPlaylist struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	SongCount int    `json:"songCount"`
+}
+
+// call performs a Subsonic API request and returns the decoded response body.
+// Authentication and format parameters are added to params.
+func (c *HTTPClient) call(ctx context.Context, endpoint string, params url.Values) (*subsonicBody, error) {
+	return c.send(ctx, http.MethodGet, endpoint, params)
+}
+
+// post is call with the parameters sent as a form body instead of in the
+// URL, for requests whose parameters can outgrow a URL, such as a long list
+// of song IDs, and that should keep the password out of access logs.
+func (c *HTTPClient) post(ctx context.Context, endpoint string, params url.Values) (*subsonicBody, error) {
+	return c.send(ctx, http.MethodPost, endpoint, params)
+}
+
+// send performs a Subsonic API request with the given method.
+func (c *HTTPClient) send(ctx context.Context, method, endpoint string, params url.Values) (*subsonicBody, error) {
+	if params == nil {
+		params = url.Values{}
 	}
+	params.Set("f", "json")
+	params.Set("v", "1.16.1")
+	params.Set("c", "ytToDeemix")
+	params.Set("u", c.User)
+	params.Set("p", c.Password)
 
-	reqURL := strings.TrimRight(c.BaseURL, "/") + "/rest/search2?" + params.Encode()
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	reqURL := strings.TrimRight(c.BaseURL, "/") + "/rest/" + endpoint
+	var body io.Reader
+	if method == http.MethodGet {
+		reqURL += "?" + params.Encode()
+	} else {
+		body = strings.NewReader(params.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, body)
 	if err != nil {
 		return nil, fmt.Errorf("navidrome: build request: %w", err)
 	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
 
 	httpClient := c.Client
 	if httpClient == nil {
@@ -92,6 +144,21 @@ func (c *HTTPClient) Search(ctx context.Context, artist, title string) ([]Search
 		log.Printf("[navidrome] API error: %s", msg)
 		return nil, fmt.Errorf("navidrome: API error: %s", msg)
 	}
+	return &sr.SubsonicResponse, nil
+}
+
+func (c *HTTPClient) Search(ctx context.Context, artist, title string) ([]SearchResult, error) {
+	// Search the library without featured artists, which are tagged in
+	// too many ways to be part of the query.
+	query := parseCredit(artist).main + " " + parseCredit(title).main
+	log.Printf("[navidrome] checking library: %s - %s", artist, title)
+	body, err := c.call(ctx, "search2", url.Values{
+		"query":     {query},
+		"songCount": {"5"},
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	// Filter results using the configured match mode.
 	mode := c.MatchMode
@@ -100,7 +167,7 @@ func (c *HTTPClient) Search(ctx context.Context, artist, title string) ([]Search
 	}
 
 	var results []SearchResult
-	for _, song := range sr.SubsonicResponse.SearchResult2.Song {
+	for _, song := range body.SearchResult2.Song {
 		if matchSong(mode, song.Artist, song.Title, artist, title) {
 			results = append(results, SearchResult{
 				ID:       song.ID,
@@ -112,9 +179,78 @@ func (c *HTTPClient) Search(ctx context.Context, artist, title string) ([]Search
 		}
 	}
 
+	// Songs crediting the wanted featured artists come first.
+	want := parseCredit(artist)
+	want.featured = append(want.featured, parseCredit(title).featured...)
+	if len(want.featured) > 0 {
+		sort.SliceStable(results, func(i, j int) bool {
+			return featuredOverlap(results[i].Artist, results[i].Title, want) >
+				featuredOverlap(results[j].Artist, results[j].Title, want)
+		})
+	}
+
 	if len(results) > 0 {
 		log.Printf("[navidrome] found in library: %s - %s", artist, title)
 	}
 
 	return results, nil
 }
+
+// StartScan asks the server to rescan its music library.
+func (c *HTTPClient) StartScan(ctx context.Context) error {
+	log.Printf("[navidrome] starting library scan")
+	_, err := c.call(ctx, "startScan", nil)
+	return err
+}
+
+// Scanning reports whether a library scan is in progress.
+func (c *HTTPClient) Scanning(ctx context.Context) (bool, error) {
+	body, err := c.call(ctx, "getScanStatus", nil)
+	if err != nil {
+		return false, err
+	}
+	return body.ScanStatus.Scanning, nil
+}
+
+// Playlists returns the playlists visible to the user.
+func (c *HTTPClient) Playlists(ctx context.Context) ([]Playlist, error) {
+	body, err := c.call(ctx, "getPlaylists", nil)
+	if err != nil {
+		return nil, err
+	}
+	playlists := make([]Playlist, 0, len(body.Playlists.Playlist))
+	for _, p := range body.Playlists.Playlist {
+		playlists = append(playlists, Playlist{ID: p.ID, Name: p.Name, SongCount: p.SongCount})
+	}
+	return playlists, nil
+}
+
+// PlaylistSongs returns the song IDs of a playlist, in order.
+func (c *HTTPClient) PlaylistSongs(ctx context.Context, id string) ([]string, error) {
+	body, err := c.call(ctx, "getPlaylist", url.Values{"id": {id}})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(body.Playlist.Entry))
+	for _, e := range body.Playlist.Entry {
+		ids = append(ids, e.ID)
+	}
+	return ids, nil
+}
+
+// CreatePlaylist creates a playlist with the given songs and returns its ID.
+func (c *HTTPClient) CreatePlaylist(ctx context.Context, name string, songIDs []string) (string, error) {
+	log.Printf("[navidrome] creating playlist %q with %d songs", name, len(songIDs))
+	body, err := c.post(ctx, "createPlaylist", url.Values{"name": {name}, "songId": songIDs})
+	if err != nil {
+		return "", err
+	}
+	return body.Playlist.ID, nil
+}
+
+// UpdatePlaylist appends songs to an existing playlist.
+func (c *HTTPClient) UpdatePlaylist(ctx context.Context, id string, addSongIDs []string) error {
+	log.Printf("[navidrome] adding %d songs to playlist %s", len(addSongIDs), id)
+	_, err := c.post(ctx, "updatePlaylist", url.Values{"playlistId": {id}, "songIdToAdd": addSongIDs})
+	return err
+}
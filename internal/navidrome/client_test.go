@@ -263,3 +263,143 @@ func TestSearch_FuzzyMode(t *testing.T) {
 		t.Errorf("ID = %q, want %q", results[0].ID, "1")
 	}
 }
+
+func TestSearch_FeaturedArtists(t *testing.T) {
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("query")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"subsonic-response": {
+				"status": "ok",
+				"searchResult2": {
+					"song": [
+						{"id": "1", "title": "Sunflower", "artist": "Post Malone", "album": "Demo", "duration": 158},
+						{"id": "2", "title": "Sunflower (feat. Swae Lee)", "artist": "Post Malone", "album": "Hollywood's Bleeding", "duration": 158}
+					]
+				}
+			}
+		}`))
+	}))
+	defer srv.Close()
+
+	client := &HTTPClient{
+		BaseURL:   srv.URL,
+		User:      "user",
+		Password:  "pass",
+		MatchMode: MatchExact,
+		Client:    srv.Client(),
+	}
+
+	results, err := client.Search(context.Background(), "Post Malone feat. Swae Lee", "Sunflower")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if query != "Post Malone Sunflower" {
+		t.Errorf("query = %q, want the primary artist and title only", query)
+	}
+	// Both match; the one crediting Swae Lee comes first.
+	if len(results) != 2 || results[0].ID != "2" {
+		t.Fatalf("results = %+v, want 2 then 1", results)
+	}
+}
+
+func TestPlaylists(t *testing.T) {
+	var created, added []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("u") != "user" || r.FormValue("f") != "json" {
+			t.Errorf("missing auth or format params: %s", r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/rest/getPlaylists":
+			w.Write([]byte(`{"subsonic-response": {"status": "ok", "playlists": {"playlist": [
+				{"id": "p1", "name": "Road Trip", "songCount": 2}
+			]}}}`))
+		case "/rest/getPlaylist":
+			if r.URL.Query().Get("id") != "p1" {
+				t.Errorf("getPlaylist id = %q", r.URL.Query().Get("id"))
+			}
+			w.Write([]byte(`{"subsonic-response": {"status": "ok", "playlist": {"id": "p1", "name": "Road Trip", "entry": [
+				{"id": "s1"}, {"id": "s2"}
+			]}}}`))
+		case "/rest/createPlaylist":
+			// Song lists and the password go in a form body.
+			if r.Method != http.MethodPost || r.URL.RawQuery != "" || r.PostFormValue("p") != "pass" {
+				t.Errorf("createPlaylist sent as %s with query %q", r.Method, r.URL.RawQuery)
+			}
+			created = r.PostForm["songId"]
+			w.Write([]byte(`{"subsonic-response": {"status": "ok", "playlist": {"id": "p2", "name": "` + r.PostFormValue("name") + `"}}}`))
+		case "/rest/updatePlaylist":
+			if r.Method != http.MethodPost || r.URL.RawQuery != "" {
+				t.Errorf("updatePlaylist sent as %s with query %q", r.Method, r.URL.RawQuery)
+			}
+			added = r.PostForm["songIdToAdd"]
+			w.Write([]byte(`{"subsonic-response": {"status": "ok"}}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	client := &HTTPClient{BaseURL: srv.URL, User: "user", Password: "pass", Client: srv.Client()}
+	ctx := context.Background()
+
+	playlists, err := client.Playlists(ctx)
+	if err != nil {
+		t.Fatalf("Playlists() error = %v", err)
+	}
+	if len(playlists) != 1 || playlists[0] != (Playlist{ID: "p1", Name: "Road Trip", SongCount: 2}) {
+		t.Errorf("playlists = %+v", playlists)
+	}
+
+	songs, err := client.PlaylistSongs(ctx, "p1")
+	if err != nil || len(songs) != 2 || songs[1] != "s2" {
+		t.Errorf("PlaylistSongs() = %v, %v", songs, err)
+	}
+
+	id, err := client.CreatePlaylist(ctx, "New", []string{"a", "b"})
+	if err != nil || id != "p2" {
+		t.Errorf("CreatePlaylist() = %q, %v, want p2", id, err)
+	}
+	if len(created) != 2 || created[0] != "a" {
+		t.Errorf("created with songs %v", created)
+	}
+
+	if err := client.UpdatePlaylist(ctx, "p1", []string{"c"}); err != nil {
+		t.Errorf("UpdatePlaylist() error = %v", err)
+	}
+	if len(added) != 1 || added[0] != "c" {
+		t.Errorf("added songs %v", added)
+	}
+}
+
+func TestScan(t *testing.T) {
+	scanning := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/rest/startScan":
+			w.Write([]byte(`{"subsonic-response": {"status": "ok", "scanStatus": {"scanning": true}}}`))
+		case "/rest/getScanStatus":
+			if scanning {
+				w.Write([]byte(`{"subsonic-response": {"status": "ok", "scanStatus": {"scanning": true, "count": 10}}}`))
+			} else {
+				w.Write([]byte(`{"subsonic-response": {"status": "ok", "scanStatus": {"scanning": false}}}`))
+			}
+		}
+	}))
+	defer srv.Close()
+
+	client := &HTTPClient{BaseURL: srv.URL, User: "user", Password: "pass", Client: srv.Client()}
+	if err := client.StartScan(context.Background()); err != nil {
+		t.Fatalf("StartScan() error = %v", err)
+	}
+	if got, err := client.Scanning(context.Background()); err != nil || !got {
+		t.Errorf("Scanning() = %v, %v, want true", got, err)
+	}
+	scanning = false
+	if got, _ := client.Scanning(context.Background()); got {
+		t.Error("Scanning() = true after scan finished")
+	}
+}
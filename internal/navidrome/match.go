@@ -1,6 +1,11 @@
 package navidrome
 
-import "strings"
+import (
+	"strings"
+	"unicode"
+
+	"github.com/gndm/ytToDeemix/internal/parser"
+)
 
 // MatchMode determines how Navidrome results are compared to the search query.
 const (
@@ -11,19 +16,93 @@ const (
 
 const fuzzySimilarityThreshold = 0.8
 
+// credit is an artist or title with its featured artists split off.
+type credit struct {
+	main     string   // without featured artists
+	names    []string // main split into single artists
+	featured []string
+}
+
+// parseCredit splits the featured artists off s, the way the parser does
+// for YouTube titles.
+func parseCredit(s string) credit {
+	main, featured := parser.StripFeatured(s)
+	return credit{main: main, names: parser.SplitArtists(main), featured: featured}
+}
+
 // matchSong returns true if the song matches the given artist/title according to mode.
+// Titles are compared without featured artists. Artists are compared as
+// whole names after normalizing, never as substrings, and match when the
+// primary credits or any two single primary artists do: "Drake" matches
+// "Drake & Future", but "Fire" from "Earth, Wind & Fire" is not
+// "Arcade Fire". Featured artists only rank matches, see featuredOverlap.
 func matchSong(mode, songArtist, songTitle, queryArtist, queryTitle string) bool {
+	if !matchText(mode, parseCredit(songTitle).main, parseCredit(queryTitle).main) {
+		return false
+	}
+	song, query := parseCredit(songArtist), parseCredit(queryArtist)
+	for _, s := range append([]string{song.main}, song.names...) {
+		for _, q := range append([]string{query.main}, query.names...) {
+			if matchArtist(mode, s, q) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchArtist compares two single artists or credits as whole names. Fuzzy
+// mode tolerates small differences; the other modes want equal names.
+func matchArtist(mode, got, want string) bool {
+	got, want = normalizeName(got), normalizeName(want)
+	if got == "" || want == "" {
+		return false
+	}
+	if mode == MatchFuzzy {
+		return similarity(got, want) >= fuzzySimilarityThreshold
+	}
+	return got == want
+}
+
+// normalizeName lowercases s and reduces punctuation and spacing to single
+// spaces, so "AC/DC" and "ac dc" are the same name.
+func normalizeName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// matchText compares a library value to a queried one according to mode.
+func matchText(mode, got, want string) bool {
 	switch mode {
 	case MatchExact:
-		return strings.EqualFold(songTitle, queryTitle) &&
-			strings.EqualFold(songArtist, queryArtist)
+		return strings.EqualFold(got, want)
 	case MatchFuzzy:
-		return similarity(strings.ToLower(songTitle), strings.ToLower(queryTitle)) >= fuzzySimilarityThreshold &&
-			similarity(strings.ToLower(songArtist), strings.ToLower(queryArtist)) >= fuzzySimilarityThreshold
+		return similarity(strings.ToLower(got), strings.ToLower(want)) >= fuzzySimilarityThreshold
 	default: // substring
-		return strings.Contains(strings.ToLower(songTitle), strings.ToLower(queryTitle)) &&
-			strings.Contains(strings.ToLower(songArtist), strings.ToLower(queryArtist))
+		return strings.Contains(strings.ToLower(got), strings.ToLower(want))
+	}
+}
+
+// featuredOverlap counts the featured artists of query that a song credits,
+// as a featured or a main artist.
+func featuredOverlap(songArtist, songTitle string, query credit) int {
+	artist, title := parseCredit(songArtist), parseCredit(songTitle)
+	names := append(append(artist.names, artist.featured...), title.featured...)
+	n := 0
+	for _, f := range query.featured {
+		for _, name := range names {
+			if normalizeName(f) == normalizeName(name) {
+				n++
+				break
+			}
+		}
 	}
+	return n
 }
 
 // similarity returns a normalized similarity score [0.0, 1.0] using Levenshtein distance.
@@ -107,3 +107,36 @@ func TestMatchSong_Fuzzy(t *testing.T) {
 		}
 	}
 }
+
+func TestMatchSong_Featured(t *testing.T) {
+	tests := []struct {
+		mode                    string
+		songArtist, songTitle   string
+		queryArtist, queryTitle string
+		want                    bool
+	}{
+		// Featured artists are ignored on both sides.
+		{MatchExact, "Post Malone", "Sunflower (feat. Swae Lee)", "Post Malone feat. Swae Lee", "Sunflower", true},
+		{MatchExact, "Post Malone feat. Swae Lee", "Sunflower", "Post Malone", "Sunflower", true},
+		{MatchFuzzy, "Calvin Harris", "This Is What You Came For (with Rihanna)", "Calvin Harris", "This Is What You Came For", true},
+		// Any primary artist matches a credit of several.
+		{MatchExact, "Drake", "Jumpman", "Drake & Future", "Jumpman", true},
+		{MatchExact, "Skrillex; Diplo", "Where Are Ü Now", "Diplo", "Where Are Ü Now", true},
+		{MatchSubstring, "Future", "Jumpman", "Drake x Future", "Jumpman", true},
+		// A featured artist alone is not the artist.
+		{MatchExact, "Swae Lee", "Sunflower", "Post Malone feat. Swae Lee", "Sunflower", false},
+		{MatchSubstring, "Swae Lee", "Sunflower", "Post Malone feat. Swae Lee", "Sunflower", false},
+		// Artists are whole names, not substrings of each other.
+		{MatchSubstring, "Arcade Fire", "September", "Earth, Wind & Fire", "September", false},
+		{MatchSubstring, "Sia", "Alive", "Siamese", "Alive", false},
+		{MatchSubstring, "Earth, Wind & Fire", "September", "Earth, Wind & Fire", "September", true},
+		{MatchExact, "AC/DC", "Thunderstruck", "ac dc", "Thunderstruck", true},
+	}
+	for _, tt := range tests {
+		got := matchSong(tt.mode, tt.songArtist, tt.songTitle, tt.queryArtist, tt.queryTitle)
+		if got != tt.want {
+			t.Errorf("matchSong(%s, %q/%q, %q/%q) = %v, want %v",
+				tt.mode, tt.songArtist, tt.songTitle, tt.queryArtist, tt.queryTitle, got, tt.want)
+		}
+	}
+}
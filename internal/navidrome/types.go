@@ -8,3 +8,10 @@ type SearchResult struct {
 	Album    string
 	Duration int
 }
+
+// Playlist represents a Navidrome playlist.
+type Playlist struct {
+	ID        string
+	Name      string
+	SongCount int
+}
@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// featBracket matches a bracketed featured-artist credit: (feat. X),
+// [ft. X], (featuring X) or (with X).
+var featBracket = regexp.MustCompile(`(?i)\s*[\(\[](?:feat\.?|ft\.?|featuring|with)\s+([^\)\]]+)[\)\]]`)
+
+// featInline matches an unbracketed credit, up to the next bracket or the
+// end: "Song ft. X".
+var featInline = regexp.MustCompile(`(?i)\s+(?:feat\.?|ft\.?|featuring)\s+([^\(\[]+)`)
+
+// featInlineWith is featInline that also accepts "with", for artist
+// credits only: in a song title it is usually part of the name.
+var featInlineWith = regexp.MustCompile(`(?i)\s+(?:feat\.?|ft\.?|featuring|with)\s+([^\(\[]+)`)
+
+// artistSeparator splits a credit into single artists: commas, semicolons,
+// "&", "+", " x " and "vs.".
+var artistSeparator = regexp.MustCompile(`(?i)\s*(?:[,;&+]|\s(?:x|×|vs\.?|versus)\s)\s*`)
+
+// SplitArtists splits an artist credit such as "Drake & Future" or
+// "Skrillex x Diplo" into single names. Band names with "&" are split too;
+// matching should also try the whole credit.
+func SplitArtists(credit string) []string {
+	var artists []string
+	for _, a := range artistSeparator.Split(credit, -1) {
+		if a = strings.TrimSpace(a); a != "" {
+			artists = append(artists, a)
+		}
+	}
+	return artists
+}
+
+// StripFeatured removes featured-artist credits from a song title and
+// returns the title with the featured artists: "Titanium (feat. Sia)"
+// gives "Titanium" and ["Sia"].
+func StripFeatured(title string) (string, []string) {
+	return stripFeatured(title, false)
+}
+
+// stripFeatured removes bracketed credits, then one inline credit.
+// withInline also treats an inline "with" as a credit.
+func stripFeatured(s string, withInline bool) (string, []string) {
+	var featured []string
+	s = featBracket.ReplaceAllStringFunc(s, func(m string) string {
+		featured = append(featured, SplitArtists(featBracket.FindStringSubmatch(m)[1])...)
+		return ""
+	})
+
+	inline := featInline
+	if withInline {
+		inline = featInlineWith
+	}
+	if m := inline.FindStringSubmatchIndex(s); m != nil {
+		featured = append(featured, SplitArtists(s[m[2]:m[3]])...)
+		s = s[:m[0]] + " " + s[m[1]:]
+	}
+	return strings.TrimSpace(extraWhitespace.ReplaceAllString(s, " ")), featured
+}
@@ -0,0 +1,48 @@
+package parser
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseArtists(t *testing.T) {
+	tests := []struct {
+		title        string
+		wantArtist   string
+		wantArtists  []string
+		wantFeatured []string
+		wantSong     string
+	}{
+		{"Calvin Harris - This Is What You Came For (feat. Rihanna)", "Calvin Harris", []string{"Calvin Harris"}, []string{"Rihanna"}, "This Is What You Came For"},
+		{"Post Malone - Sunflower ft. Swae Lee", "Post Malone", []string{"Post Malone"}, []string{"Swae Lee"}, "Sunflower"},
+		{"Mark Ronson feat. Bruno Mars - Uptown Funk", "Mark Ronson", []string{"Mark Ronson"}, []string{"Bruno Mars"}, "Uptown Funk"},
+		{"Drake & Future - Jumpman", "Drake & Future", []string{"Drake", "Future"}, nil, "Jumpman"},
+		{"Skrillex x Diplo - Where Are Ü Now (with Justin Bieber)", "Skrillex x Diplo", []string{"Skrillex", "Diplo"}, []string{"Justin Bieber"}, "Where Are Ü Now"},
+		{"Armin van Buuren vs. Vini Vici - Great Spirit", "Armin van Buuren vs. Vini Vici", []string{"Armin van Buuren", "Vini Vici"}, nil, "Great Spirit"},
+		{"Marshmello with Bastille - Happier", "Marshmello", []string{"Marshmello"}, []string{"Bastille"}, "Happier"},
+		{"Billy Idol - Dancing with Myself", "Billy Idol", []string{"Billy Idol"}, nil, "Dancing with Myself"},
+		{"Malcolm X - Speech", "Malcolm X", []string{"Malcolm X"}, nil, "Speech"},
+		{"DJ Khaled - Wild Thoughts ft. Rihanna, Bryson Tiller (Official Video)", "DJ Khaled", []string{"DJ Khaled"}, []string{"Rihanna", "Bryson Tiller"}, "Wild Thoughts"},
+		{"Titanium feat. Sia", "", nil, []string{"Sia"}, "Titanium"},
+	}
+
+	for _, tt := range tests {
+		got := Parse(tt.title)
+		if got.Artist != tt.wantArtist || got.Song != tt.wantSong {
+			t.Errorf("Parse(%q) = %q / %q, want %q / %q", tt.title, got.Artist, got.Song, tt.wantArtist, tt.wantSong)
+		}
+		if !slices.Equal(got.Artists, tt.wantArtists) {
+			t.Errorf("Parse(%q) artists = %q, want %q", tt.title, got.Artists, tt.wantArtists)
+		}
+		if !slices.Equal(got.Featured, tt.wantFeatured) {
+			t.Errorf("Parse(%q) featured = %q, want %q", tt.title, got.Featured, tt.wantFeatured)
+		}
+	}
+}
+
+func TestStripFeatured(t *testing.T) {
+	title, featured := StripFeatured("Titanium (feat. Sia) [David Guetta Remix]")
+	if title != "Titanium [David Guetta Remix]" || !slices.Equal(featured, []string{"Sia"}) {
+		t.Errorf("StripFeatured = %q, %q, want the title without the credit and Sia", title, featured)
+	}
+}
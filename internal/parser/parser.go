@@ -6,7 +6,7 @@ import (
 )
 
 // suffixPatterns matches common noise suffixes in YouTube music titles.
-var suffixPatterns = regexp.MustCompile(`(?i)\s*[\(\[](official\s*(music\s*|lyric\s*)?video|official\s*audio|lyrics?\s*(video)?|audio|hd|hq|4k|music\s*video|lyric\s*video|mv|visuali[sz]er|live|remix|feat\.?[^\)\]]*|ft\.?[^\)\]]*|prod\.?[^\)\]]*|video\s*oficial)[\)\]]`)
+var suffixPatterns = regexp.MustCompile(`(?i)\s*[\(\[](official\s*(music\s*|lyric\s*)?video|official\s*audio|lyrics?\s*(video)?|audio|hd|hq|4k|music\s*video|lyric\s*video|mv|visuali[sz]er|prod\.?[^\)\]]*|video\s*oficial)[\)\]]`)
 
 // trailingNoise matches trailing markers not in brackets.
 var trailingNoise = regexp.MustCompile(`(?i)\s*[-–—|]\s*(official\s*(music\s*)?video|official\s*audio|lyrics?\s*(video)?|audio|hd|hq|4k|music\s*video|mv|visuali[sz]er)\s*$`)
@@ -14,9 +14,6 @@ var trailingNoise = regexp.MustCompile(`(?i)\s*[-–—|]\s*(official\s*(music\s
 // topicSuffix matches " - Topic" channel name artifacts.
 var topicSuffix = regexp.MustCompile(`(?i)\s*-\s*topic\s*$`)
 
-// featPattern normalizes featured artist notation within the song title.
-var featPattern = regexp.MustCompile(`(?i)\s*\b(feat\.?|ft\.?)\s+`)
-
 // delimiters in priority order.
 var delimiters = []string{" - ", " – ", " — ", " | ", " ~ "}
 
@@ -29,12 +26,47 @@ var byPattern = regexp.MustCompile(`(?i)^(.+?)\s+by\s+(.+)$`)
 // extraWhitespace collapses multiple spaces.
 var extraWhitespace = regexp.MustCompile(`\s{2,}`)
 
-// Parse extracts artist and song from a YouTube video title.
-// Returns (artist, song). If parsing fails, artist is empty and song
-// is the cleaned title (still usable as a search query).
-func Parse(title string) (artist, song string) {
-	cleaned := clean(title)
+// Result is what Parse extracts from a video title.
+type Result struct {
+	// Artist is the primary artist credit as written, such as
+	// "Drake & Future", without featured artists.
+	Artist string
+	// Artists is Artist split into single names.
+	Artists []string
+	// Featured lists the featured artists, from "feat.", "ft.",
+	// "featuring" or "(with ...)" credits in the artist or the song.
+	Featured []string
+	Song     string
+	// Version is the recording the title names, such as a live or remix
+	// version. Its tag is removed from Song.
+	Version Version
+}
 
+// Parse extracts artists, song and version from a YouTube video title.
+// If no artist can be found, Artist is empty and Song is the cleaned title
+// (still usable as a search query).
+func Parse(title string) Result {
+	return ParseTrack(split(clean(title)))
+}
+
+// ParseTrack builds a Result from an artist and song that are already
+// separated, as in YouTube Music metadata: featured artists and the
+// version tag are moved out of both.
+func ParseTrack(artist, song string) Result {
+	artist, featured := stripFeatured(artist, true)
+	song, songFeatured := stripFeatured(song, false)
+	song, version := StripVersion(song)
+	return Result{
+		Artist:   artist,
+		Artists:  SplitArtists(artist),
+		Featured: append(featured, songFeatured...),
+		Song:     song,
+		Version:  version,
+	}
+}
+
+// split separates a cleaned title into artist and song.
+func split(cleaned string) (artist, song string) {
 	// Try delimiter-based splitting.
 	for _, delim := range delimiters {
 		if idx := strings.Index(cleaned, delim); idx > 0 {
@@ -42,7 +74,7 @@ func Parse(title string) (artist, song string) {
 			s := strings.TrimSpace(cleaned[idx+len(delim):])
 			if a != "" && s != "" {
 				a = topicSuffix.ReplaceAllString(a, "")
-				return normalizeFeat(strings.TrimSpace(a)), normalizeFeat(s)
+				return strings.TrimSpace(a), s
 			}
 		}
 	}
@@ -52,7 +84,7 @@ func Parse(title string) (artist, song string) {
 		a := strings.TrimSpace(matches[1])
 		s := strings.TrimSpace(matches[2])
 		if a != "" && s != "" {
-			return normalizeFeat(a), normalizeFeat(s)
+			return a, s
 		}
 	}
 
@@ -61,12 +93,12 @@ func Parse(title string) (artist, song string) {
 		s := strings.TrimSpace(matches[1])
 		a := strings.TrimSpace(matches[2])
 		if a != "" && s != "" {
-			return normalizeFeat(a), normalizeFeat(s)
+			return a, s
 		}
 	}
 
 	// Fallback: return cleaned title as song, no artist.
-	return "", normalizeFeat(cleaned)
+	return "", cleaned
 }
 
 // clean removes noise from a title.
@@ -79,8 +111,3 @@ func clean(title string) string {
 	s = strings.TrimSpace(s)
 	return s
 }
-
-// normalizeFeat standardizes "feat." and "ft." to "feat.".
-func normalizeFeat(s string) string {
-	return strings.TrimSpace(featPattern.ReplaceAllString(s, " feat. "))
-}
@@ -4,10 +4,11 @@ import "testing"
 
 func TestParse(t *testing.T) {
 	tests := []struct {
-		name       string
-		title      string
-		wantArtist string
-		wantSong   string
+		name        string
+		title       string
+		wantArtist  string
+		wantSong    string
+		wantVersion Version
 	}{
 		// Standard delimiter formats
 		{
@@ -114,7 +115,7 @@ func TestParse(t *testing.T) {
 			name:       "ft in song",
 			title:      "Post Malone - Sunflower ft. Swae Lee",
 			wantArtist: "Post Malone",
-			wantSong:   "Sunflower feat. Swae Lee",
+			wantSong:   "Sunflower",
 		},
 
 		// "by" pattern
@@ -175,6 +176,77 @@ func TestParse(t *testing.T) {
 			wantSong:   "HUMBLE.",
 		},
 
+		// Version tags
+		{
+			name:        "live at venue with noise",
+			title:       "Nirvana - Lake of Fire (Live On MTV Unplugged) [Official Video]",
+			wantArtist:  "Nirvana",
+			wantSong:    "Lake of Fire",
+			wantVersion: Version{Kind: VersionLive},
+		},
+		{
+			name:        "live tag",
+			title:       "Radiohead - Creep (Live)",
+			wantArtist:  "Radiohead",
+			wantSong:    "Creep",
+			wantVersion: Version{Kind: VersionLive},
+		},
+		{
+			name:        "live at venue after dash",
+			title:       "Queen - Bohemian Rhapsody - Live at Wembley 1986",
+			wantArtist:  "Queen",
+			wantSong:    "Bohemian Rhapsody",
+			wantVersion: Version{Kind: VersionLive},
+		},
+		{
+			name:        "remix with remixer",
+			title:       "Avicii - Levels (Skrillex Remix) [HD]",
+			wantArtist:  "Avicii",
+			wantSong:    "Levels",
+			wantVersion: Version{Kind: VersionRemix, Remixer: "Skrillex"},
+		},
+		{
+			name:        "bare remix",
+			title:       "Daft Punk - One More Time [Remix]",
+			wantArtist:  "Daft Punk",
+			wantSong:    "One More Time",
+			wantVersion: Version{Kind: VersionRemix},
+		},
+		{
+			name:        "acoustic",
+			title:       "Ed Sheeran - Perfect (Acoustic Version)",
+			wantArtist:  "Ed Sheeran",
+			wantSong:    "Perfect",
+			wantVersion: Version{Kind: VersionAcoustic},
+		},
+		{
+			name:        "remastered with year",
+			title:       "The Beatles - Let It Be (Remastered 2009)",
+			wantArtist:  "The Beatles",
+			wantSong:    "Let It Be",
+			wantVersion: Version{Kind: VersionRemastered},
+		},
+		{
+			name:        "radio edit",
+			title:       "Darude - Sandstorm (Radio Edit)",
+			wantArtist:  "Darude",
+			wantSong:    "Sandstorm",
+			wantVersion: Version{Kind: VersionRadioEdit},
+		},
+		{
+			name:        "extended mix",
+			title:       "Eric Prydz - Call On Me (Extended Mix)",
+			wantArtist:  "Eric Prydz",
+			wantSong:    "Call On Me",
+			wantVersion: Version{Kind: VersionExtended},
+		},
+		{
+			name:       "song named live",
+			title:      "Alicia Keys - Live",
+			wantArtist: "Alicia Keys",
+			wantSong:   "Live",
+		},
+
 		// Edge cases
 		{
 			name:       "extra whitespace",
@@ -192,12 +264,15 @@ func TestParse(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotArtist, gotSong := Parse(tt.title)
-			if gotArtist != tt.wantArtist {
-				t.Errorf("Parse(%q) artist = %q, want %q", tt.title, gotArtist, tt.wantArtist)
+			got := Parse(tt.title)
+			if got.Artist != tt.wantArtist {
+				t.Errorf("Parse(%q) artist = %q, want %q", tt.title, got.Artist, tt.wantArtist)
+			}
+			if got.Song != tt.wantSong {
+				t.Errorf("Parse(%q) song = %q, want %q", tt.title, got.Song, tt.wantSong)
 			}
-			if gotSong != tt.wantSong {
-				t.Errorf("Parse(%q) song = %q, want %q", tt.title, gotSong, tt.wantSong)
+			if got.Version != tt.wantVersion {
+				t.Errorf("Parse(%q) version = %+v, want %+v", tt.title, got.Version, tt.wantVersion)
 			}
 		})
 	}
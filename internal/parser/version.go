@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Version kinds. An empty Kind is the original studio recording.
+const (
+	VersionLive       = "live"
+	VersionRemix      = "remix"
+	VersionAcoustic   = "acoustic"
+	VersionRemastered = "remastered"
+	VersionRadioEdit  = "radio_edit"
+	VersionExtended   = "extended"
+)
+
+// Version describes which recording of a song a title refers to.
+type Version struct {
+	Kind string `json:"kind,omitempty"`
+	// Remixer is who made a remix, when the title names them.
+	Remixer string `json:"remixer,omitempty"`
+}
+
+// IsZero reports whether v is the original recording.
+func (v Version) IsZero() bool {
+	return v.Kind == ""
+}
+
+// String returns the version as words for a search query, such as "live"
+// or "Skrillex remix". The original recording is "".
+func (v Version) String() string {
+	switch v.Kind {
+	case "":
+		return ""
+	case VersionRemix:
+		return strings.TrimSpace(v.Remixer + " remix")
+	case VersionRadioEdit:
+		return "radio edit"
+	default:
+		return v.Kind
+	}
+}
+
+// versionPatterns recognise the text of a version tag, without brackets.
+// The first capture group, if any, is the remixer. A tag of kind "" names
+// the original recording, as in "(Original Mix)".
+var versionPatterns = []struct {
+	kind    string
+	pattern *regexp.Regexp
+}{
+	{"", regexp.MustCompile(`(?i)^original(\s+(mix|version|edit))?$`)},
+	{VersionRadioEdit, regexp.MustCompile(`(?i)^radio\s+(edit|version|mix)$`)},
+	{VersionExtended, regexp.MustCompile(`(?i)^extended(\s+(mix|version|edit))?$`)},
+	{VersionRemastered, regexp.MustCompile(`(?i)^(\d{4}\s+)?(digital(ly)?\s+)?remaster(ed)?(\s+\d{4})?(\s+version)?$`)},
+	{VersionAcoustic, regexp.MustCompile(`(?i)^(acoustic(\s+(version|session|mix))?|unplugged)$`)},
+	{VersionLive, regexp.MustCompile(`(?i)^live(\s+(at|from|in|on)\s.*|\s+version|\s+session)?$`)},
+	{VersionRemix, regexp.MustCompile(`(?i)^(?:(.+?)\s+)?(?:remix|rmx)$`)},
+	{VersionRemix, regexp.MustCompile(`(?i)^remixed\s+by\s+(.+)$`)},
+}
+
+// bracketTag matches a (...) or [...] segment.
+var bracketTag = regexp.MustCompile(`\s*[\(\[]([^\(\)\[\]]+)[\)\]]`)
+
+// dashTag matches a trailing " - tag", as in "Song - Remastered 2011".
+var dashTag = regexp.MustCompile(`\s+[-–—]\s+([^-–—]+)$`)
+
+// versionOfTag returns the version a tag names, if it names one.
+func versionOfTag(tag string) (Version, bool) {
+	tag = strings.TrimSpace(tag)
+	for _, vp := range versionPatterns {
+		if m := vp.pattern.FindStringSubmatch(tag); m != nil {
+			v := Version{Kind: vp.kind}
+			if len(m) > 1 && vp.kind == VersionRemix {
+				v.Remixer = remixerName(m[1])
+			}
+			return v, true
+		}
+	}
+	return Version{}, false
+}
+
+// remixWords describe a remix rather than name who made it: "Club Remix",
+// "Skrillex VIP Remix".
+var remixWords = map[string]bool{
+	"official": true,
+	"club":     true,
+	"extended": true,
+	"radio":    true,
+	"original": true,
+	"dub":      true,
+	"vip":      true,
+}
+
+// remixerName drops the descriptive words around the remixer in a remix
+// tag. Returns "" when nothing else is left.
+func remixerName(s string) string {
+	words := strings.Fields(s)
+	for len(words) > 0 && remixWords[strings.ToLower(words[0])] {
+		words = words[1:]
+	}
+	for len(words) > 0 && remixWords[strings.ToLower(words[len(words)-1])] {
+		words = words[:len(words)-1]
+	}
+	return strings.Join(words, " ")
+}
+
+// StripVersion removes a version tag from a song title, bracketed or after
+// a trailing dash, and returns the remaining title with the version. Only
+// the first tag found is removed; other brackets are kept. An "Original
+// Mix" tag is removed too, and gives the original recording.
+func StripVersion(s string) (string, Version) {
+	for _, m := range bracketTag.FindAllStringSubmatchIndex(s, -1) {
+		if v, ok := versionOfTag(s[m[2]:m[3]]); ok {
+			return strings.TrimSpace(extraWhitespace.ReplaceAllString(s[:m[0]]+s[m[1]:], " ")), v
+		}
+	}
+	if m := dashTag.FindStringSubmatchIndex(s); m != nil {
+		if v, ok := versionOfTag(s[m[2]:m[3]]); ok {
+			return strings.TrimSpace(s[:m[0]]), v
+		}
+	}
+	return s, Version{}
+}
+
+// VersionOf returns the version a Deezer track or album title names, such
+// as "Creep (Acoustic)" or "Live at Wembley". It accepts a bare tag as the
+// whole title, which StripVersion does not.
+func VersionOf(title string) Version {
+	if _, v := StripVersion(title); !v.IsZero() {
+		return v
+	}
+	v, _ := versionOfTag(title)
+	return v
+}
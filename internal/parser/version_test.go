@@ -0,0 +1,56 @@
+package parser
+
+import "testing"
+
+func TestStripVersion(t *testing.T) {
+	tests := []struct {
+		in          string
+		wantTitle   string
+		wantVersion Version
+	}{
+		{"Creep", "Creep", Version{}},
+		{"Creep (Acoustic)", "Creep", Version{Kind: VersionAcoustic}},
+		{"Let It Be - Remastered 2009", "Let It Be", Version{Kind: VersionRemastered}},
+		{"Let It Be - 2009 Remaster", "Let It Be", Version{Kind: VersionRemastered}},
+		{"Titanium (feat. Sia) [David Guetta Remix]", "Titanium (feat. Sia)", Version{Kind: VersionRemix, Remixer: "David Guetta"}},
+		{"Levels (Official Remix)", "Levels", Version{Kind: VersionRemix}},
+		{"Sandstorm - Radio Edit", "Sandstorm", Version{Kind: VersionRadioEdit}},
+		{"Call On Me (Extended)", "Call On Me", Version{Kind: VersionExtended}},
+		{"Smells Like Teen Spirit (Live at Reading)", "Smells Like Teen Spirit", Version{Kind: VersionLive}},
+		{"Live Forever", "Live Forever", Version{}},
+		{"Hey Jude (Remixed by Giles Martin)", "Hey Jude", Version{Kind: VersionRemix, Remixer: "Giles Martin"}},
+		{"Song (Demo)", "Song (Demo)", Version{}},
+		// Descriptive words are not the remixer.
+		{"Levels (Club Remix)", "Levels", Version{Kind: VersionRemix}},
+		{"Levels (Extended Remix)", "Levels", Version{Kind: VersionRemix}},
+		{"Bangarang (Skrillex VIP Remix)", "Bangarang", Version{Kind: VersionRemix, Remixer: "Skrillex"}},
+		{"Strobe (Dub Remix)", "Strobe", Version{Kind: VersionRemix}},
+		// An original mix is the original recording.
+		{"Strobe (Original Mix)", "Strobe", Version{}},
+		{"Opus - Original Version", "Opus", Version{}},
+	}
+	for _, tt := range tests {
+		title, version := StripVersion(tt.in)
+		if title != tt.wantTitle || version != tt.wantVersion {
+			t.Errorf("StripVersion(%q) = %q, %+v, want %q, %+v", tt.in, title, version, tt.wantTitle, tt.wantVersion)
+		}
+	}
+}
+
+func TestVersionOf(t *testing.T) {
+	tests := []struct {
+		title string
+		want  Version
+	}{
+		{"Live at Wembley Stadium", Version{Kind: VersionLive}},
+		{"MTV Unplugged in New York", Version{}},
+		{"Pablo Honey", Version{}},
+		{"Abbey Road (Remastered)", Version{Kind: VersionRemastered}},
+		{"Strobe (Original Mix)", Version{}},
+	}
+	for _, tt := range tests {
+		if got := VersionOf(tt.title); got != tt.want {
+			t.Errorf("VersionOf(%q) = %+v, want %+v", tt.title, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Package ratelimit paces and retries HTTP calls to external services.
+// Transport wraps an http.RoundTripper, so adapters get rate limiting and
+// retries by being handed an http.Client, without importing this package.
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket: it allows Rate calls per second on average,
+// with bursts of up to Burst calls. Safe for concurrent use.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter creates a Limiter that starts with a full bucket. A rate of 0
+// or less disables limiting.
+func NewLimiter(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+// Wait blocks until a call is allowed or ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil || l.rate <= 0 {
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	// Take the token now, even if it goes negative, so waiters queue up
+	// in order instead of racing for the next refill.
+	l.tokens--
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if err := sleep(ctx, delay); err != nil {
+		// Give the token back so a canceled caller doesn't slow others.
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Policy decides how failed calls are retried.
+type Policy struct {
+	// MaxAttempts is the total number of tries, including the first.
+	// Values below 1 mean a single try.
+	MaxAttempts int
+	// BaseDelay is the backoff before the first retry. It doubles on each
+	// retry, up to MaxDelay. The actual delay is picked at random between
+	// half and all of it, so clients that failed together spread out.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultPolicy retries up to three times, starting at 500ms.
+var DefaultPolicy = Policy{MaxAttempts: 4, BaseDelay: 500 * time.Millisecond, MaxDelay: 10 * time.Second}
+
+// Backoff returns the delay before retry number n (1 for the first retry).
+func (p Policy) Backoff(n int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < n && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// Retryable reports whether a response status is worth retrying: 429 and
+// the 5xx codes that signal a temporary condition.
+func Retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Transport is an http.RoundTripper that waits for the Limiter before every
+// attempt and retries network errors and Retryable statuses according to
+// Policy. A Retry-After header in seconds overrides the backoff delay, up
+// to Policy.MaxDelay.
+// Requests with a body are only retried if they set GetBody. Requests that
+// are not idempotent (see Idempotent) are only retried on 429, which the
+// server sends before acting on them.
+type Transport struct {
+	Base    http.RoundTripper // http.DefaultTransport if nil
+	Limiter *Limiter
+	Policy  Policy
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	ctx := req.Context()
+
+	for attempt := 1; ; attempt++ {
+		if err := t.Limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		resp, err := base.RoundTrip(req)
+		if err == nil && !Retryable(resp.StatusCode) {
+			return resp, nil
+		}
+		if attempt >= t.Policy.MaxAttempts || ctx.Err() != nil || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+		if !Idempotent(req) && (err != nil || resp.StatusCode != http.StatusTooManyRequests) {
+			return resp, err
+		}
+
+		delay := t.Policy.Backoff(attempt)
+		if resp != nil {
+			if after := retryAfter(resp); after > 0 {
+				delay = min(after, max(t.Policy.MaxDelay, delay))
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+	}
+}
+
+// Idempotent reports whether req can be sent again after a failure that
+// may have come after the server acted on it: its method is idempotent, or
+// the caller opted in with an Idempotency-Key or X-Idempotency-Key header.
+// As with net/http's Transport, a nil header value opts in without sending
+// the header.
+func Idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	_, key := req.Header["Idempotency-Key"]
+	_, xkey := req.Header["X-Idempotency-Key"]
+	return key || xkey
+}
+
+// retryAfter parses a Retry-After header given in seconds.
+func retryAfter(resp *http.Response) time.Duration {
+	secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || secs <= 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
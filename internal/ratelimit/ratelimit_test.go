@@ -0,0 +1,171 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiterWait(t *testing.T) {
+	l := NewLimiter(100, 2)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait: %v", err)
+		}
+	}
+	// Two calls come from the burst, two more need 10ms each.
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("4 calls took %v, want at least 15ms", elapsed)
+	}
+}
+
+func TestLimiterWaitCanceled(t *testing.T) {
+	l := NewLimiter(0.1, 1)
+	l.Wait(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); err == nil {
+		t.Fatal("expected error for canceled context")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	tests := []struct {
+		n        int
+		min, max time.Duration
+	}{
+		{1, 50 * time.Millisecond, 100 * time.Millisecond},
+		{2, 100 * time.Millisecond, 200 * time.Millisecond},
+		{3, 150 * time.Millisecond, 300 * time.Millisecond},
+		{10, 150 * time.Millisecond, 300 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if d := p.Backoff(tt.n); d < tt.min || d > tt.max {
+				t.Errorf("Backoff(%d) = %v, want between %v and %v", tt.n, d, tt.min, tt.max)
+			}
+		}
+	}
+}
+
+func TestTransportRetries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "payload" {
+			t.Errorf("attempt %d body = %q, want payload", calls.Load()+1, body)
+		}
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &Transport{
+		Limiter: NewLimiter(1000, 1),
+		Policy:  Policy{MaxAttempts: 3, BaseDelay: time.Millisecond},
+	}}
+	// The POST opts in to retries.
+	req, _ := http.NewRequest(http.MethodPost, server.URL, bytes.NewBufferString("payload"))
+	req.Header["Idempotency-Key"] = nil
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("calls = %d, want 3", calls.Load())
+	}
+}
+
+func TestTransportNonIdempotent(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &Transport{
+		Limiter: NewLimiter(1000, 1),
+		Policy:  Policy{MaxAttempts: 3, BaseDelay: time.Millisecond},
+	}}
+	// A plain POST is retried after a 429 but not after a 502, which may
+	// come after the server acted on it.
+	resp, err := client.Post(server.URL, "text/plain", bytes.NewBufferString("payload"))
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", resp.StatusCode)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want 2", calls.Load())
+	}
+}
+
+func TestTransportGivesUp(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &Transport{Policy: Policy{MaxAttempts: 2, BaseDelay: time.Millisecond}}}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", resp.StatusCode)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want 2", calls.Load())
+	}
+}
+
+func TestTransportNoRetryOnClientError(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &Transport{Policy: DefaultPolicy}}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+}
@@ -0,0 +1,463 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gndm/ytToDeemix/internal/deemix"
+)
+
+// DefaultCacheTTL is how long cached search results stay valid.
+const DefaultCacheTTL = 30 * 24 * time.Hour
+
+// cacheFlushDelay batches cache writes: changes are saved to disk this long
+// after the first unsaved one.
+const cacheFlushDelay = 5 * time.Second
+
+// cachedSearch is the Deezer results for one normalized query.
+type cachedSearch struct {
+	Results  []deemix.SearchResult `json:"results"`
+	CachedAt time.Time             `json:"cached_at"`
+}
+
+// cachedVideo is what a YouTube video resolved to: the results of the last
+// search made for it, automatic or manual, and the match the user picked
+// among them, if any.
+type cachedVideo struct {
+	Query   string                `json:"query"`
+	Results []deemix.SearchResult `json:"results"`
+	// Strategies maps result IDs to the search strategy that found them.
+	Strategies map[int64]string `json:"strategies,omitempty"`
+	PickedID   int64            `json:"picked_id,omitempty"`
+	CachedAt   time.Time        `json:"cached_at"`
+}
+
+// candidates returns the cached results, unscored, with their strategies.
+func (v cachedVideo) candidates() []Candidate {
+	candidates := make([]Candidate, len(v.Results))
+	for i, r := range v.Results {
+		candidates[i] = Candidate{SearchResult: r, Strategy: v.Strategies[r.ID]}
+	}
+	return candidates
+}
+
+// cacheFile is the on-disk layout of a SearchCache.
+type cacheFile struct {
+	Queries map[string]cachedSearch `json:"queries"`
+	Videos  map[string]cachedVideo  `json:"videos"`
+}
+
+// SearchCache remembers Deezer search results across sessions, keyed by
+// normalized query, and which results each YouTube video resolved to.
+// Entries expire after the TTL. With a path, the cache is kept in a single
+// JSON file, saved shortly after each change. Safe for concurrent use; a
+// nil *SearchCache never hits.
+type SearchCache struct {
+	path string
+	ttl  time.Duration
+
+	mu     sync.Mutex
+	data   cacheFile
+	hits   int
+	misses int
+	timer  *time.Timer // pending flush, nil if the file is up to date
+	saveMu sync.Mutex  // serializes writes to the file
+}
+
+// NewSearchCache creates an empty cache. path may be empty to keep the cache
+// in memory only; ttl of 0 or less uses DefaultCacheTTL.
+func NewSearchCache(path string, ttl time.Duration) *SearchCache {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	return &SearchCache{
+		path: path,
+		ttl:  ttl,
+		data: cacheFile{Queries: make(map[string]cachedSearch), Videos: make(map[string]cachedVideo)},
+	}
+}
+
+// Load reads the cache file, dropping expired entries. A missing file
+// means an empty cache.
+func (c *SearchCache) Load() error {
+	if c.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading search cache: %w", err)
+	}
+
+	var file cacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("decoding search cache: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for query, entry := range file.Queries {
+		if !c.expired(entry.CachedAt) {
+			c.data.Queries[query] = entry
+		}
+	}
+	for id, entry := range file.Videos {
+		if !c.expired(entry.CachedAt) {
+			c.data.Videos[id] = entry
+		}
+	}
+	return nil
+}
+
+// Flush writes pending changes to disk now (temp file + rename).
+func (c *SearchCache) Flush() error {
+	if c == nil || c.path == "" {
+		return nil
+	}
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+
+	c.mu.Lock()
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	data, err := json.Marshal(c.data)
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("encoding search cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("creating cache directory: %w", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("writing search cache: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("renaming search cache: %w", err)
+	}
+	return nil
+}
+
+// changed schedules a flush for the changes just made.
+// Must be called with c.mu held.
+func (c *SearchCache) changed() {
+	if c.path == "" || c.timer != nil {
+		return
+	}
+	c.timer = time.AfterFunc(cacheFlushDelay, func() {
+		if err := c.Flush(); err != nil {
+			log.Printf("[sync] failed to save search cache: %v", err)
+		}
+	})
+}
+
+// expired reports whether an entry cached at t is past the TTL.
+func (c *SearchCache) expired(t time.Time) bool {
+	return time.Since(t) > c.ttl
+}
+
+// normalizeQuery makes queries that differ only in case or spacing share
+// a cache entry.
+func normalizeQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+// Search returns the cached results for query, if any.
+func (c *SearchCache) Search(query string) ([]deemix.SearchResult, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.data.Queries[normalizeQuery(query)]
+	if !ok || c.expired(entry.CachedAt) {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	return entry.Results, true
+}
+
+// has reports whether query is cached, without counting a hit or miss.
+func (c *SearchCache) has(query string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.data.Queries[normalizeQuery(query)]
+	return ok && !c.expired(entry.CachedAt)
+}
+
+// putSearch caches the results of a search. Empty results are not cached:
+// they are as likely to be a Deemix hiccup as a real miss.
+func (c *SearchCache) putSearch(query string, results []deemix.SearchResult) {
+	if c == nil || len(results) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data.Queries[normalizeQuery(query)] = cachedSearch{Results: results, CachedAt: time.Now()}
+	c.changed()
+}
+
+// video returns what a YouTube video resolved to, if cached.
+func (c *SearchCache) video(videoID string) (cachedVideo, bool) {
+	if c == nil || videoID == "" {
+		return cachedVideo{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.data.Videos[videoID]
+	if !ok || c.expired(entry.CachedAt) {
+		c.misses++
+		return cachedVideo{}, false
+	}
+	c.hits++
+	return entry, true
+}
+
+// putVideo records the candidates found for a video, forgetting any
+// earlier pick. query is the search that found the best of them.
+func (c *SearchCache) putVideo(videoID, query string, candidates []Candidate) {
+	if c == nil || videoID == "" || len(candidates) == 0 {
+		return
+	}
+	entry := cachedVideo{Query: query, Strategies: make(map[int64]string), CachedAt: time.Now()}
+	for _, cand := range candidates {
+		entry.Results = append(entry.Results, cand.SearchResult)
+		entry.Strategies[cand.ID] = cand.Strategy
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data.Videos[videoID] = entry
+	c.changed()
+}
+
+// addVideo merges the candidates of a search made for a video into its
+// entry, keeping the entry's query and the user's pick.
+func (c *SearchCache) addVideo(videoID, query string, candidates []Candidate) {
+	if c == nil || videoID == "" || len(candidates) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.data.Videos[videoID]
+	if !ok || c.expired(entry.CachedAt) {
+		entry = cachedVideo{Query: query}
+	}
+	if entry.Strategies == nil {
+		entry.Strategies = make(map[int64]string)
+	}
+	for _, cand := range candidates {
+		if !slices.ContainsFunc(entry.Results, func(r deemix.SearchResult) bool { return r.ID == cand.ID }) {
+			entry.Results = append(entry.Results, cand.SearchResult)
+			entry.Strategies[cand.ID] = cand.Strategy
+		}
+	}
+	entry.CachedAt = time.Now()
+	c.data.Videos[videoID] = entry
+	c.changed()
+}
+
+// pickVideo records the match the user chose for a video.
+func (c *SearchCache) pickVideo(videoID string, deezerID int64) {
+	if c == nil || videoID == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.data.Videos[videoID]
+	if !ok {
+		return
+	}
+	entry.PickedID = deezerID
+	entry.CachedAt = time.Now()
+	c.data.Videos[videoID] = entry
+	c.changed()
+}
+
+// CacheEntry describes one cached query or video.
+type CacheEntry struct {
+	// Key is the normalized query or the YouTube video ID.
+	Key string `json:"key"`
+	// Query is the search a video's results came from. Empty for queries.
+	Query     string    `json:"query,omitempty"`
+	Results   int       `json:"results"`
+	PickedID  int64     `json:"picked_id,omitempty"`
+	CachedAt  time.Time `json:"cached_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CacheInfo is a snapshot of a SearchCache.
+type CacheInfo struct {
+	Hits    int          `json:"hits"`
+	Misses  int          `json:"misses"`
+	Queries []CacheEntry `json:"queries"`
+	Videos  []CacheEntry `json:"videos"`
+}
+
+// Info lists the live entries, sorted by key, with hit and miss counts
+// since startup.
+func (c *SearchCache) Info() CacheInfo {
+	if c == nil {
+		return CacheInfo{Queries: []CacheEntry{}, Videos: []CacheEntry{}}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	info := CacheInfo{Hits: c.hits, Misses: c.misses, Queries: []CacheEntry{}, Videos: []CacheEntry{}}
+	for query, entry := range c.data.Queries {
+		if !c.expired(entry.CachedAt) {
+			info.Queries = append(info.Queries, CacheEntry{
+				Key:       query,
+				Results:   len(entry.Results),
+				CachedAt:  entry.CachedAt,
+				ExpiresAt: entry.CachedAt.Add(c.ttl),
+			})
+		}
+	}
+	for id, entry := range c.data.Videos {
+		if !c.expired(entry.CachedAt) {
+			info.Videos = append(info.Videos, CacheEntry{
+				Key:       id,
+				Query:     entry.Query,
+				Results:   len(entry.Results),
+				PickedID:  entry.PickedID,
+				CachedAt:  entry.CachedAt,
+				ExpiresAt: entry.CachedAt.Add(c.ttl),
+			})
+		}
+	}
+	sort.Slice(info.Queries, func(i, j int) bool { return info.Queries[i].Key < info.Queries[j].Key })
+	sort.Slice(info.Videos, func(i, j int) bool { return info.Videos[i].Key < info.Videos[j].Key })
+	return info
+}
+
+// Purge empties the cache.
+func (c *SearchCache) Purge() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data.Queries = make(map[string]cachedSearch)
+	c.data.Videos = make(map[string]cachedVideo)
+	c.changed()
+}
+
+// PurgeQuery drops one query. Returns false if it was not cached.
+func (c *SearchCache) PurgeQuery(query string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := normalizeQuery(query)
+	if _, ok := c.data.Queries[key]; !ok {
+		return false
+	}
+	delete(c.data.Queries, key)
+	c.changed()
+	return true
+}
+
+// PurgeVideo drops what one video resolved to. Returns false if it was not
+// cached.
+func (c *SearchCache) PurgeVideo(videoID string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.data.Videos[videoID]; !ok {
+		return false
+	}
+	delete(c.data.Videos, videoID)
+	c.changed()
+	return true
+}
+
+// SetSearchCache makes the pipeline search through cache and reuse what
+// each video resolved to in earlier sessions. Call before starting sessions.
+func (p *Pipeline) SetSearchCache(cache *SearchCache) {
+	p.cache = cache
+	p.deemixClient = NewCachedClient(p.deemixClient, cache)
+}
+
+// CachedClient is a deemix.Client that answers repeated searches from a
+// SearchCache and only asks Deemix on a miss.
+type CachedClient struct {
+	deemix.Client
+	Cache *SearchCache
+}
+
+// NewCachedClient wraps dx with cache. If dx can report the Deemix queue,
+// so can the returned client.
+func NewCachedClient(dx deemix.Client, cache *SearchCache) deemix.Client {
+	cached := &CachedClient{Client: dx, Cache: cache}
+	if queue, ok := dx.(deemix.QueueClient); ok {
+		return &cachedQueueClient{CachedClient: cached, queue: queue}
+	}
+	return cached
+}
+
+// Search implements deemix.Client.
+func (c *CachedClient) Search(ctx context.Context, query string) ([]deemix.SearchResult, error) {
+	if results, ok := c.Cache.Search(query); ok {
+		return results, nil
+	}
+	results, err := c.Client.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	c.Cache.putSearch(query, results)
+	return results, nil
+}
+
+// SearchISRC implements deemix.ISRCClient, caching found tracks like
+// searches. It returns nil, as for an unknown ISRC, when the wrapped client
+// can't look ISRCs up.
+func (c *CachedClient) SearchISRC(ctx context.Context, isrc string) (*deemix.SearchResult, error) {
+	lookup, ok := c.Client.(deemix.ISRCClient)
+	if !ok {
+		return nil, nil
+	}
+	query := isrcQuery(isrc)
+	if results, ok := c.Cache.Search(query); ok {
+		return &results[0], nil
+	}
+	result, err := lookup.SearchISRC(ctx, isrc)
+	if err != nil || result == nil {
+		return nil, err
+	}
+	c.Cache.putSearch(query, []deemix.SearchResult{*result})
+	return result, nil
+}
+
+// cachedQueueClient is a CachedClient over a deemix.QueueClient.
+type cachedQueueClient struct {
+	*CachedClient
+	queue deemix.QueueClient
+}
+
+// Queue implements deemix.QueueClient.
+func (c *cachedQueueClient) Queue(ctx context.Context) (map[string]deemix.QueueItem, error) {
+	return c.queue.Queue(ctx)
+}
@@ -0,0 +1,178 @@
+package sync
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gndm/ytToDeemix/internal/deemix"
+	"github.com/gndm/ytToDeemix/internal/ytdlp"
+)
+
+// countingDeemixClient counts the searches that reach Deemix.
+type countingDeemixClient struct {
+	mockDeemixClient
+	searches atomic.Int32
+}
+
+func (m *countingDeemixClient) Search(ctx context.Context, query string) ([]deemix.SearchResult, error) {
+	m.searches.Add(1)
+	return m.mockDeemixClient.Search(ctx, query)
+}
+
+func TestCachedClient(t *testing.T) {
+	dx := &countingDeemixClient{mockDeemixClient: mockDeemixClient{
+		searchResults: map[string][]deemix.SearchResult{
+			"Radiohead Creep": {{ID: 1, Title: "Creep", Artist: "Radiohead"}},
+		},
+	}}
+	client := NewCachedClient(dx, NewSearchCache("", time.Hour))
+
+	for _, query := range []string{"Radiohead Creep", "radiohead  creep", "Radiohead Creep"} {
+		results, err := client.Search(context.Background(), query)
+		if err != nil || len(results) != 1 {
+			t.Fatalf("Search(%q) = %v, %v", query, results, err)
+		}
+	}
+	if dx.searches.Load() != 1 {
+		t.Errorf("Deemix searches = %d, want 1", dx.searches.Load())
+	}
+
+	// Misses are not cached.
+	client.Search(context.Background(), "Unknown")
+	client.Search(context.Background(), "Unknown")
+	if dx.searches.Load() != 3 {
+		t.Errorf("Deemix searches = %d, want 3", dx.searches.Load())
+	}
+
+	if _, ok := client.(deemix.QueueClient); ok {
+		t.Error("cached client claims queue support its inner client lacks")
+	}
+	if _, ok := NewCachedClient(&mockQueueClient{}, nil).(deemix.QueueClient); !ok {
+		t.Error("cached client hides the inner client's queue support")
+	}
+}
+
+func TestSearchCachePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	results := []deemix.SearchResult{{ID: 1, Title: "Creep", Artist: "Radiohead"}}
+
+	cache := NewSearchCache(path, time.Hour)
+	cache.putSearch("Radiohead Creep", results)
+	cache.putVideo("abc", "Radiohead Creep", []Candidate{{SearchResult: results[0]}})
+	cache.pickVideo("abc", 1)
+	if err := cache.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	loaded := NewSearchCache(path, time.Hour)
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got, ok := loaded.Search("radiohead creep"); !ok || len(got) != 1 {
+		t.Errorf("Search after load = %v, %v", got, ok)
+	}
+	if video, ok := loaded.video("abc"); !ok || video.PickedID != 1 {
+		t.Errorf("video after load = %+v, %v, want picked 1", video, ok)
+	}
+
+	// Entries past the TTL are dropped.
+	expired := NewSearchCache(path, time.Nanosecond)
+	if err := expired.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if info := expired.Info(); len(info.Queries) != 0 || len(info.Videos) != 0 {
+		t.Errorf("expired cache has %d queries and %d videos", len(info.Queries), len(info.Videos))
+	}
+}
+
+func TestSearchCachePurge(t *testing.T) {
+	cache := NewSearchCache("", time.Hour)
+	results := []deemix.SearchResult{{ID: 1}}
+	cache.putSearch("a", results)
+	cache.putSearch("b", results)
+	cache.putVideo("abc", "a", []Candidate{{SearchResult: results[0]}})
+
+	if !cache.PurgeQuery("A") || cache.PurgeQuery("A") {
+		t.Error("PurgeQuery should drop a cached query once")
+	}
+	if !cache.PurgeVideo("abc") || cache.PurgeVideo("abc") {
+		t.Error("PurgeVideo should drop a cached video once")
+	}
+	cache.Purge()
+	if info := cache.Info(); len(info.Queries) != 0 || len(info.Videos) != 0 {
+		t.Errorf("after Purge: %d queries and %d videos", len(info.Queries), len(info.Videos))
+	}
+
+	// A disabled cache has nothing to report or purge.
+	var disabled *SearchCache
+	disabled.Purge()
+	if disabled.PurgeQuery("a") || disabled.PurgeVideo("abc") {
+		t.Error("nil cache purged an entry")
+	}
+	if info := disabled.Info(); info.Hits != 0 || len(info.Queries) != 0 {
+		t.Errorf("nil cache Info() = %+v", info)
+	}
+}
+
+func TestSearchCacheCountsVideoLookups(t *testing.T) {
+	cache := NewSearchCache("", time.Hour)
+	cache.putVideo("abc", "a", []Candidate{{SearchResult: deemix.SearchResult{ID: 1}}})
+
+	cache.video("abc")
+	cache.video("def")
+	cache.video("def")
+	if info := cache.Info(); info.Hits != 1 || info.Misses != 2 {
+		t.Errorf("hits %d, misses %d, want 1 and 2", info.Hits, info.Misses)
+	}
+}
+
+func TestPipelineReusesResolution(t *testing.T) {
+	yt := &mockYTClient{entries: []ytdlp.PlaylistEntry{
+		{Title: "Radiohead - Creep", VideoID: "abc"},
+		{Title: "Muse - Uprising", VideoID: "def"},
+	}}
+	dx := &countingDeemixClient{mockDeemixClient: mockDeemixClient{
+		searchResults: map[string][]deemix.SearchResult{
+			"Radiohead Creep": {
+				{ID: 1, Title: "Creep", Artist: "Radiohead"},
+				{ID: 2, Title: "Creep (Acoustic)", Artist: "Radiohead"},
+			},
+			"Muse Uprising": {{ID: 3, Title: "Uprising", Artist: "Muse"}},
+		},
+	}}
+
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.searchDelay = 0
+	pipeline.SetSearchCache(NewSearchCache("", time.Hour))
+
+	first := pipeline.Analyze(context.Background(), "url", deemix.Bitrate320, false)
+	pipeline.WaitSettled(context.Background(), first)
+	if err := pipeline.SetTrackMatch(context.Background(), first, 0, 2); err != nil {
+		t.Fatalf("SetTrackMatch: %v", err)
+	}
+	// Each track tries the structured query, then the combined one.
+	if dx.searches.Load() != 4 {
+		t.Fatalf("Deemix searches = %d, want 4", dx.searches.Load())
+	}
+
+	second := pipeline.Analyze(context.Background(), "url", deemix.Bitrate320, false)
+	session, err := pipeline.WaitSettled(context.Background(), second)
+	if err != nil {
+		t.Fatalf("WaitSettled: %v", err)
+	}
+	if dx.searches.Load() != 4 {
+		t.Errorf("Deemix searches = %d after re-analysing, want 4", dx.searches.Load())
+	}
+	// The user's pick carries over.
+	track := session.Tracks[0]
+	if track.DeezerMatch == nil || track.DeezerMatch.ID != 2 || track.Status != TrackFound || !track.Selected {
+		t.Errorf("track 0: match %+v, status %q, selected %v, want picked ID 2 found and selected",
+			track.DeezerMatch, track.Status, track.Selected)
+	}
+	if session.Tracks[1].DeezerMatch == nil || session.Tracks[1].DeezerMatch.ID != 3 {
+		t.Errorf("track 1 matched %+v, want ID 3", session.Tracks[1].DeezerMatch)
+	}
+}
@@ -1,6 +1,180 @@
 package sync
 
-import "strings"
+import (
+	"sort"
+	"strings"
+
+	"github.com/gndm/ytToDeemix/internal/deemix"
+	"github.com/gndm/ytToDeemix/internal/parser"
+)
+
+// Duration penalty: gaps up to durationTolerance seconds are free, then every
+// durationPenaltyStep seconds costs one point, up to maxDurationPenalty.
+const (
+	durationTolerance   = 10
+	durationPenaltyStep = 2
+	maxDurationPenalty  = 50
+)
+
+// Version scoring: a result naming the same version as the video (live,
+// acoustic, a remix by the same remixer...) gains versionBonus points, one
+// naming another version loses versionPenalty. Remasters and radio edits
+// count as the original recording.
+const (
+	versionBonus   = 10
+	versionPenalty = 40
+)
+
+// featuredBonus is added when a result credits one of the video's featured
+// artists. A missing featured artist costs nothing: Deezer often leaves
+// them out of the title.
+const featuredBonus = 5
+
+// matchTarget is what Deezer results are scored against.
+type matchTarget struct {
+	artist, song string   // parsed from the YouTube title
+	artists      []string // artist split into single names
+	featured     []string
+	version      parser.Version
+	isrc         string
+	title        string // the full YouTube title
+	duration     int    // YouTube length in seconds, 0 if unknown
+}
+
+// targetOf returns the match target for a track.
+func targetOf(t *Track) matchTarget {
+	return matchTarget{
+		artist:   t.ParsedArtist,
+		artists:  t.Artists,
+		featured: t.Featured,
+		song:     t.ParsedSong,
+		version:  t.Version,
+		isrc:     t.ISRC,
+		title:    t.YouTubeTitle,
+		duration: t.Duration,
+	}
+}
+
+// rankCandidates scores every result against the target and returns them
+// best first, ties in Deezer's order. Featured artists only add a bonus.
+// Results far off the video's length, karaoke, covers and tributes, and
+// other versions of the song are penalised.
+func (p *Pipeline) rankCandidates(target matchTarget, results []deemix.SearchResult) []Candidate {
+	candidates := make([]Candidate, len(results))
+	for i, r := range results {
+		candidates[i] = Candidate{SearchResult: r}
+	}
+	return p.rankResults(target, candidates)
+}
+
+// rankResults is rankCandidates for results that already carry a strategy.
+// It scores a copy and leaves candidates untouched.
+func (p *Pipeline) rankResults(target matchTarget, candidates []Candidate) []Candidate {
+	ranked := make([]Candidate, len(candidates))
+	for i, c := range candidates {
+		title, version := resultVersion(c.SearchResult)
+		title, featured := parser.StripFeatured(title)
+		confidence := target.confidence(c.Artist, title)
+		if target.creditsAny(append(featured, parser.SplitArtists(c.Artist)...)) {
+			confidence += featuredBonus
+		}
+		confidence += versionScore(target.version, version)
+		var delta *int
+		if target.duration > 0 && c.Duration > 0 {
+			d := c.Duration - target.duration
+			delta = &d
+			confidence -= durationPenalty(d)
+		}
+		confidence -= p.quality.penalty(target.title, c.SearchResult)
+		if c.Strategy == StrategyISRC {
+			// Same ISRC, same recording, whatever the titles say.
+			confidence = 100
+		}
+		ranked[i] = Candidate{
+			SearchResult:  c.SearchResult,
+			Confidence:    min(max(confidence, 0), 100),
+			DurationDelta: delta,
+			Strategy:      c.Strategy,
+			Version:       version,
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Confidence > ranked[j].Confidence
+	})
+	return ranked
+}
+
+// confidence returns the best calculateConfidence of a result against the
+// whole artist credit and each primary artist alone, since Deezer usually
+// credits only the first artist of "Drake & Future".
+func (t matchTarget) confidence(resultArtist, resultTitle string) int {
+	best := calculateConfidence(t.artist, t.song, resultArtist, resultTitle)
+	if len(t.artists) > 1 {
+		for _, a := range t.artists {
+			best = max(best, calculateConfidence(a, t.song, resultArtist, resultTitle))
+		}
+	}
+	return best
+}
+
+// creditsAny reports whether one of the target's featured artists is among
+// names, ignoring case.
+func (t matchTarget) creditsAny(names []string) bool {
+	for _, f := range t.featured {
+		for _, name := range names {
+			if strings.EqualFold(strings.TrimSpace(f), strings.TrimSpace(name)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resultVersion returns a Deezer result's title without its version tag,
+// and the version named by the title or, failing that, by the album.
+func resultVersion(r deemix.SearchResult) (string, parser.Version) {
+	title, version := parser.StripVersion(r.Title)
+	if version.IsZero() {
+		version = parser.VersionOf(r.Album)
+	}
+	return title, version
+}
+
+// versionScore returns the confidence points to add for a result of version
+// got when the video is version want.
+func versionScore(want, got parser.Version) int {
+	if want.Kind == got.Kind {
+		if want.IsZero() {
+			return 0
+		}
+		if want.Remixer != "" && got.Remixer != "" && !strings.EqualFold(want.Remixer, got.Remixer) {
+			return -versionPenalty
+		}
+		return versionBonus
+	}
+	if originalRecording(want) && originalRecording(got) {
+		return 0
+	}
+	return -versionPenalty
+}
+
+// originalRecording reports whether v is the studio recording, possibly
+// remastered or cut for radio.
+func originalRecording(v parser.Version) bool {
+	return v.IsZero() || v.Kind == parser.VersionRemastered || v.Kind == parser.VersionRadioEdit
+}
+
+// durationPenalty returns the confidence points to subtract for a duration
+// gap of delta seconds.
+func durationPenalty(delta int) int {
+	if delta < 0 {
+		delta = -delta
+	}
+	if delta <= durationTolerance {
+		return 0
+	}
+	return min((delta-durationTolerance)/durationPenaltyStep, maxDurationPenalty)
+}
 
 // calculateConfidence returns a confidence score (0-100) for a Deezer match.
 // Higher score = more confident the match is correct.
@@ -1,6 +1,11 @@
 package sync
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/gndm/ytToDeemix/internal/deemix"
+	"github.com/gndm/ytToDeemix/internal/parser"
+)
 
 func TestCalculateConfidence(t *testing.T) {
 	tests := []struct {
@@ -118,3 +123,152 @@ func TestLevenshtein(t *testing.T) {
 		}
 	}
 }
+
+func TestRankCandidates(t *testing.T) {
+	pipeline := NewPipeline(nil, nil, nil)
+	results := []deemix.SearchResult{
+		{ID: 1, Title: "Creep (Karaoke)", Artist: "Sing Along Band"},
+		{ID: 2, Title: "Creep", Artist: "Radiohead"},
+		{ID: 3, Title: "Creep", Artist: "Radiohead"},
+	}
+
+	candidates := pipeline.rankCandidates(matchTarget{artist: "Radiohead", song: "Creep"}, results)
+	if len(candidates) != 3 {
+		t.Fatalf("expected 3 candidates, got %d", len(candidates))
+	}
+	// Best match first; ties keep Deezer's order.
+	if candidates[0].ID != 2 || candidates[1].ID != 3 || candidates[2].ID != 1 {
+		t.Errorf("order = %d, %d, %d, want 2, 3, 1", candidates[0].ID, candidates[1].ID, candidates[2].ID)
+	}
+	if candidates[0].Confidence != 100 {
+		t.Errorf("best confidence = %d, want 100", candidates[0].Confidence)
+	}
+	if candidates[2].Confidence >= candidates[1].Confidence {
+		t.Errorf("karaoke confidence %d should be below %d", candidates[2].Confidence, candidates[1].Confidence)
+	}
+
+	if got := pipeline.rankCandidates(matchTarget{artist: "Radiohead", song: "Creep"}, nil); len(got) != 0 {
+		t.Errorf("expected no candidates for no results, got %d", len(got))
+	}
+}
+
+func TestDurationPenalty(t *testing.T) {
+	tests := []struct {
+		delta int
+		want  int
+	}{
+		{0, 0},
+		{10, 0},
+		{-10, 0},
+		{30, 10},
+		{-60, 25},
+		{390, 50},
+	}
+	for _, tc := range tests {
+		if got := durationPenalty(tc.delta); got != tc.want {
+			t.Errorf("durationPenalty(%d) = %d, want %d", tc.delta, got, tc.want)
+		}
+	}
+}
+
+func TestRankCandidatesDuration(t *testing.T) {
+	pipeline := NewPipeline(nil, nil, nil)
+	results := []deemix.SearchResult{
+		{ID: 1, Title: "Strings of Life", Artist: "Rhythim Is Rhythim", Duration: 600},
+		{ID: 2, Title: "Strings of Life", Artist: "Rhythim Is Rhythim", Duration: 212},
+		{ID: 3, Title: "Strings of Life", Artist: "Rhythim Is Rhythim"},
+	}
+
+	candidates := pipeline.rankCandidates(matchTarget{artist: "Rhythim Is Rhythim", song: "Strings of Life", duration: 210}, results)
+	// The radio edit matches the video; unknown durations are not penalised.
+	if candidates[0].ID != 2 || candidates[1].ID != 3 || candidates[2].ID != 1 {
+		t.Errorf("order = %d, %d, %d, want 2, 3, 1", candidates[0].ID, candidates[1].ID, candidates[2].ID)
+	}
+	if candidates[0].DurationDelta == nil || *candidates[0].DurationDelta != 2 {
+		t.Errorf("radio edit delta = %v, want 2", candidates[0].DurationDelta)
+	}
+	if candidates[1].DurationDelta != nil {
+		t.Errorf("unknown duration delta = %d, want nil", *candidates[1].DurationDelta)
+	}
+	if candidates[2].Confidence != 50 {
+		t.Errorf("extended mix confidence = %d, want 50", candidates[2].Confidence)
+	}
+
+	// Without a YouTube duration nothing is penalised.
+	for _, c := range pipeline.rankCandidates(matchTarget{artist: "Rhythim Is Rhythim", song: "Strings of Life"}, results) {
+		if c.Confidence != 100 || c.DurationDelta != nil {
+			t.Errorf("candidate %d = %d%%, delta %v, want 100%% and no delta", c.ID, c.Confidence, c.DurationDelta)
+		}
+	}
+}
+
+func TestVersionScore(t *testing.T) {
+	original := parser.Version{}
+	live := parser.Version{Kind: parser.VersionLive}
+	remaster := parser.Version{Kind: parser.VersionRemastered}
+	tests := []struct {
+		name      string
+		want, got parser.Version
+		score     int
+	}{
+		{"both original", original, original, 0},
+		{"same version", live, live, versionBonus},
+		{"live wanted, studio found", live, original, -versionPenalty},
+		{"studio wanted, live found", original, live, -versionPenalty},
+		{"remaster is the original", original, remaster, 0},
+		{"same remixer", parser.Version{Kind: parser.VersionRemix, Remixer: "Skrillex"}, parser.Version{Kind: parser.VersionRemix, Remixer: "skrillex"}, versionBonus},
+		{"unnamed remixer", parser.Version{Kind: parser.VersionRemix}, parser.Version{Kind: parser.VersionRemix, Remixer: "Tiësto"}, versionBonus},
+		{"other remixer", parser.Version{Kind: parser.VersionRemix, Remixer: "Skrillex"}, parser.Version{Kind: parser.VersionRemix, Remixer: "Tiësto"}, -versionPenalty},
+	}
+	for _, tt := range tests {
+		if got := versionScore(tt.want, tt.got); got != tt.score {
+			t.Errorf("%s: versionScore = %d, want %d", tt.name, got, tt.score)
+		}
+	}
+}
+
+func TestRankCandidatesVersion(t *testing.T) {
+	results := []deemix.SearchResult{
+		{ID: 1, Title: "Creep", Artist: "Radiohead", Album: "Pablo Honey"},
+		{ID: 2, Title: "Creep (Live)", Artist: "Radiohead"},
+		{ID: 3, Title: "Creep", Artist: "Radiohead", Album: "Live at the Astoria"},
+	}
+	pipeline := NewPipeline(nil, nil, nil)
+
+	live := pipeline.rankCandidates(matchTarget{artist: "Radiohead", song: "Creep", version: parser.Version{Kind: parser.VersionLive}}, results)
+	if live[0].ID != 2 || live[1].ID != 3 || live[0].Confidence != 100 {
+		t.Errorf("live video: order %d, %d at %d%%, want the live results 2 and 3 first at 100%%", live[0].ID, live[1].ID, live[0].Confidence)
+	}
+	if live[0].Version.Kind != parser.VersionLive {
+		t.Errorf("candidate version = %+v, want live", live[0].Version)
+	}
+
+	studio := pipeline.rankCandidates(matchTarget{artist: "Radiohead", song: "Creep"}, results)
+	if studio[0].ID != 1 || studio[1].Confidence >= pipeline.confidenceThreshold {
+		t.Errorf("studio video: best %d, runner-up %d%%, want 1 and the live versions below the threshold", studio[0].ID, studio[1].Confidence)
+	}
+}
+
+func TestRankCandidatesFeatured(t *testing.T) {
+	results := []deemix.SearchResult{
+		{ID: 1, Title: "Jumpman", Artist: "Drake"},
+		{ID: 2, Title: "Sunflower (Spider-Man: Into the Spider-Verse) (feat. Swae Lee)", Artist: "Post Malone"},
+		{ID: 3, Title: "Sunflower (Spider-Man: Into the Spider-Verse)", Artist: "Post Malone"},
+	}
+	pipeline := NewPipeline(nil, nil, nil)
+
+	// Deezer credits the first of several primary artists.
+	duo := pipeline.rankCandidates(matchTarget{artist: "Drake & Future", artists: []string{"Drake", "Future"}, song: "Jumpman"}, results)
+	if duo[0].ID != 1 || duo[0].Confidence != 100 {
+		t.Errorf("Drake & Future: best %d at %d%%, want 1 at 100%%", duo[0].ID, duo[0].Confidence)
+	}
+
+	// The featured credit is stripped from the title and adds a bonus.
+	target := matchTarget{artist: "Post Malone", artists: []string{"Post Malone"}, featured: []string{"Swae Lee"},
+		song: "Sunflower (Spider-Man: Into the Spider-Verse)"}
+	feat := pipeline.rankCandidates(target, results)
+	if feat[0].ID != 2 || feat[1].ID != 3 || feat[1].Confidence != 100 {
+		t.Errorf("featured: order %d (%d%%), %d (%d%%), want 2 then 3 at 100%%",
+			feat[0].ID, feat[0].Confidence, feat[1].ID, feat[1].Confidence)
+	}
+}
@@ -0,0 +1,122 @@
+package sync
+
+// Event types pushed to session subscribers.
+const (
+	EventStatus   = "status"
+	EventTrack    = "track"
+	EventProgress = "progress"
+)
+
+// eventBuffer is how many events a subscriber may fall behind before it is
+// dropped.
+const eventBuffer = 256
+
+// Event is a change to a session. Data is a StatusEvent, a TrackEvent or a
+// Progress, depending on Type.
+type Event struct {
+	Type string
+	Data any
+}
+
+// StatusEvent carries the session's status and the other session fields
+// that change while it runs. It is sent whenever any of them changes.
+type StatusEvent struct {
+	Status            string `json:"status"`
+	Error             string `json:"error,omitempty"`
+	QueuePosition     int    `json:"queue_position,omitempty"`
+	Title             string `json:"title,omitempty"`
+	Fetching          bool   `json:"fetching,omitempty"`
+	NavidromePlaylist string `json:"navidrome_playlist,omitempty"`
+	Interrupted       string `json:"interrupted,omitempty"`
+}
+
+// TrackEvent carries the new state of one track.
+type TrackEvent struct {
+	Index int   `json:"index"`
+	Track Track `json:"track"`
+}
+
+// Subscribe returns a channel of changes to a session and a function that
+// ends the subscription. The channel is closed when the subscriber falls
+// more than eventBuffer events behind or the session is deleted; callers
+// should then reload the session and subscribe again.
+func (p *Pipeline) Subscribe(sessionID string) (<-chan Event, func(), error) {
+	p.mu.RLock()
+	_, ok := p.sessions[sessionID]
+	p.mu.RUnlock()
+	if !ok {
+		return nil, nil, ErrSessionNotFound
+	}
+
+	ch := make(chan Event, eventBuffer)
+	p.subMu.Lock()
+	if p.subscribers[sessionID] == nil {
+		p.subscribers[sessionID] = make(map[chan Event]struct{})
+	}
+	p.subscribers[sessionID][ch] = struct{}{}
+	p.subMu.Unlock()
+
+	unsubscribe := func() {
+		p.subMu.Lock()
+		defer p.subMu.Unlock()
+		if _, ok := p.subscribers[sessionID][ch]; ok {
+			delete(p.subscribers[sessionID], ch)
+			close(ch)
+		}
+	}
+	return ch, unsubscribe, nil
+}
+
+// publish sends an event to every subscriber of a session without blocking.
+// Subscribers whose buffer is full are dropped.
+func (p *Pipeline) publish(sessionID string, ev Event) {
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+
+	for ch := range p.subscribers[sessionID] {
+		select {
+		case ch <- ev:
+		default:
+			delete(p.subscribers[sessionID], ch)
+			close(ch)
+		}
+	}
+}
+
+// closeSubscribers ends every subscription to a session.
+func (p *Pipeline) closeSubscribers(sessionID string) {
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+
+	for ch := range p.subscribers[sessionID] {
+		close(ch)
+	}
+	delete(p.subscribers, sessionID)
+}
+
+// emitStatus publishes the session's current status and session fields.
+// Must be called with p.mu held, so events keep the order of the changes.
+func (p *Pipeline) emitStatus(session *Session) {
+	p.publish(session.ID, Event{Type: EventStatus, Data: StatusEvent{
+		Status:            session.Status,
+		Error:             session.Error,
+		QueuePosition:     session.QueuePosition,
+		Title:             session.Title,
+		Fetching:          session.Fetching,
+		NavidromePlaylist: session.NavidromePlaylist,
+		Interrupted:       session.Interrupted,
+	}})
+}
+
+// emitProgress publishes the session's progress counters.
+// Must be called with p.mu held.
+func (p *Pipeline) emitProgress(session *Session) {
+	p.publish(session.ID, Event{Type: EventProgress, Data: session.Progress})
+}
+
+// emitTrack publishes a track's new state followed by the progress counters.
+// Must be called with p.mu held.
+func (p *Pipeline) emitTrack(session *Session, index int) {
+	p.publish(session.ID, Event{Type: EventTrack, Data: TrackEvent{Index: index, Track: session.Tracks[index]}})
+	p.emitProgress(session)
+}
@@ -0,0 +1,113 @@
+package sync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gndm/ytToDeemix/internal/deemix"
+	"github.com/gndm/ytToDeemix/internal/ytdlp"
+)
+
+// nextEvent returns the next event, failing the test if none arrives.
+func nextEvent(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		if !ok {
+			t.Fatal("event channel closed")
+		}
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+	return Event{}
+}
+
+func TestSubscribeEvents(t *testing.T) {
+	yt := &mockYTClient{entries: []ytdlp.PlaylistEntry{{Title: "Artist - Song", VideoID: "abc"}}}
+	dx := &mockDeemixClient{
+		searchResults: map[string][]deemix.SearchResult{
+			"Artist Song": {{ID: 1, Title: "Song", Artist: "Artist", Link: "https://www.deezer.com/track/1"}},
+		},
+	}
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.searchDelay = 0
+	pipeline.queueDelay = 0
+
+	if _, _, err := pipeline.Subscribe("missing"); err != ErrSessionNotFound {
+		t.Errorf("Subscribe() of missing session error = %v, want ErrSessionNotFound", err)
+	}
+
+	id := pipeline.Analyze(context.Background(), "url", deemix.Bitrate320, false)
+	pipeline.WaitSettled(context.Background(), id)
+
+	events, unsubscribe, err := pipeline.Subscribe(id)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	defer unsubscribe()
+
+	pipeline.SetTrackSelected(id, 0, false)
+	ev := nextEvent(t, events)
+	if te, ok := ev.Data.(TrackEvent); ev.Type != EventTrack || !ok || te.Index != 0 || te.Track.Selected {
+		t.Fatalf("event = %+v, want deselected track 0", ev)
+	}
+	ev = nextEvent(t, events)
+	if p, ok := ev.Data.(Progress); ev.Type != EventProgress || !ok || p.Selected != 0 {
+		t.Fatalf("event = %+v, want progress with 0 selected", ev)
+	}
+
+	pipeline.SetTrackSelected(id, 0, true)
+	nextEvent(t, events)
+	nextEvent(t, events)
+
+	if err := pipeline.Download(context.Background(), id); err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	want := []string{EventStatus, EventTrack, EventProgress, EventStatus}
+	for i, typ := range want {
+		ev = nextEvent(t, events)
+		if ev.Type != typ {
+			t.Fatalf("event %d type = %q, want %q", i, ev.Type, typ)
+		}
+	}
+	if se, ok := ev.Data.(StatusEvent); !ok || se.Status != StatusDone {
+		t.Errorf("last event = %+v, want status done", ev)
+	}
+}
+
+func TestSubscribeDropsSlowSubscriber(t *testing.T) {
+	yt := &mockYTClient{entries: []ytdlp.PlaylistEntry{{Title: "Artist - Song", VideoID: "abc"}}}
+	pipeline := NewPipeline(yt, &mockDeemixClient{}, nil)
+	pipeline.searchDelay = 0
+
+	id := pipeline.Analyze(context.Background(), "url", deemix.Bitrate320, false)
+	pipeline.WaitSettled(context.Background(), id)
+
+	events, unsubscribe, _ := pipeline.Subscribe(id)
+	defer unsubscribe()
+
+	// Never read: the subscription is dropped once the buffer is full.
+	session, _ := pipeline.GetSession(id)
+	for i := 0; i <= eventBuffer; i++ {
+		pipeline.publish(id, Event{Type: EventStatus, Data: StatusEvent{Status: session.Status}})
+	}
+	n := 0
+	for range events {
+		n++
+	}
+	if n != eventBuffer {
+		t.Errorf("received %d events before close, want %d", n, eventBuffer)
+	}
+
+	// Deleting the session ends remaining subscriptions.
+	other, unsubscribeOther, _ := pipeline.Subscribe(id)
+	defer unsubscribeOther()
+	if err := pipeline.DeleteSession(id); err != nil {
+		t.Fatalf("DeleteSession() error = %v", err)
+	}
+	if _, ok := <-other; ok {
+		t.Error("subscription still open after delete")
+	}
+}
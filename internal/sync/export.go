@@ -0,0 +1,125 @@
+package sync
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gndm/ytToDeemix/internal/deemix"
+)
+
+// ExportRow is one track of an exported session.
+type ExportRow struct {
+	YouTubeTitle string `json:"youtube_title"`
+	ParsedArtist string `json:"parsed_artist"`
+	ParsedSong   string `json:"parsed_song"`
+	DeezerID     int64  `json:"deezer_id,omitempty"`
+	DeezerArtist string `json:"deezer_artist,omitempty"`
+	DeezerTitle  string `json:"deezer_title,omitempty"`
+	DeezerAlbum  string `json:"deezer_album,omitempty"`
+	DeezerLink   string `json:"deezer_link,omitempty"`
+	Confidence   int    `json:"confidence"`
+	Status       string `json:"status"`
+	Selected     bool   `json:"selected"`
+}
+
+// ExportRows flattens the session's tracks for export.
+func ExportRows(session *Session) []ExportRow {
+	rows := make([]ExportRow, len(session.Tracks))
+	for i, t := range session.Tracks {
+		rows[i] = ExportRow{
+			YouTubeTitle: t.YouTubeTitle,
+			ParsedArtist: t.ParsedArtist,
+			ParsedSong:   t.ParsedSong,
+			Confidence:   t.Confidence,
+			Status:       t.Status,
+			Selected:     t.Selected,
+		}
+		if m := t.DeezerMatch; m != nil {
+			rows[i].DeezerID = m.ID
+			rows[i].DeezerArtist = m.Artist
+			rows[i].DeezerTitle = m.Title
+			rows[i].DeezerAlbum = m.Album
+			rows[i].DeezerLink = m.Link
+		}
+	}
+	return rows
+}
+
+// WriteCSV writes the session's tracks as CSV with a header row.
+func WriteCSV(w io.Writer, session *Session) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"youtube_title", "parsed_artist", "parsed_song",
+		"deezer_id", "deezer_artist", "deezer_title", "deezer_album", "deezer_link",
+		"confidence", "status", "selected",
+	})
+	for _, r := range ExportRows(session) {
+		var id string
+		if r.DeezerID != 0 {
+			id = strconv.FormatInt(r.DeezerID, 10)
+		}
+		cw.Write([]string{
+			r.YouTubeTitle, r.ParsedArtist, r.ParsedSong,
+			id, r.DeezerArtist, r.DeezerTitle, r.DeezerAlbum, r.DeezerLink,
+			strconv.Itoa(r.Confidence), r.Status, strconv.FormatBool(r.Selected),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteM3U8 writes an extended M3U playlist of the tracks that are
+// downloaded or selected for download, and of those skipped because the
+// library already has them. Tracks whose download failed are left out. Paths follow Deemix's default track name template
+// ("%artist% - %title%") relative to the download folder, so the file
+// resolves once placed next to the downloads. That folder is usually the
+// Navidrome library, so the skipped tracks resolve when Deemix named them.
+func WriteM3U8(w io.Writer, session *Session) error {
+	ext := ".mp3"
+	if session.Bitrate == deemix.BitrateFLAC {
+		ext = ".flac"
+	}
+
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	for _, t := range session.Tracks {
+		m := t.DeezerMatch
+		if m == nil || !inPlaylist(t) {
+			continue
+		}
+		duration := m.Duration
+		if duration == 0 {
+			duration = -1
+		}
+		name := m.Artist + " - " + m.Title
+		fmt.Fprintf(&b, "#EXTINF:%d,%s\n%s%s\n", duration, name, sanitizeFilename(name), ext)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// inPlaylist reports whether WriteM3U8 lists a track: downloaded, skipped,
+// or selected and not failed.
+func inPlaylist(t Track) bool {
+	switch t.Status {
+	case TrackDownloaded, TrackSkipped:
+		return true
+	case TrackError:
+		return false
+	}
+	return t.Selected
+}
+
+// sanitizeFilename replaces the characters Deemix strips from file names.
+func sanitizeFilename(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,107 @@
+package sync
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/gndm/ytToDeemix/internal/deemix"
+)
+
+func exportSession() *Session {
+	return &Session{
+		ID:      "abc",
+		Bitrate: deemix.Bitrate320,
+		Tracks: []Track{
+			{
+				YouTubeTitle: "AC/DC - Thunderstruck (Official Video)",
+				ParsedArtist: "AC/DC",
+				ParsedSong:   "Thunderstruck",
+				DeezerMatch:  &deemix.SearchResult{ID: 1, Title: "Thunderstruck", Artist: "AC/DC", Album: "The Razors Edge", Duration: 292, Link: "https://www.deezer.com/track/1"},
+				Confidence:   100,
+				Status:       TrackDownloaded,
+				Selected:     true,
+			},
+			{
+				YouTubeTitle: "Somebody - Cover, \"Live\"",
+				ParsedArtist: "Somebody",
+				ParsedSong:   "Cover",
+				DeezerMatch:  &deemix.SearchResult{ID: 2, Title: "Cover", Artist: "Other"},
+				Confidence:   45,
+				Status:       TrackNeedsReview,
+			},
+			{
+				YouTubeTitle: "Unknown",
+				ParsedSong:   "Unknown",
+				Status:       TrackNotFound,
+			},
+		},
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, exportSession()); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid CSV: %v", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("got %d records, want header + 3", len(records))
+	}
+	if records[0][0] != "youtube_title" || records[0][9] != "status" {
+		t.Errorf("header = %v", records[0])
+	}
+	if records[1][3] != "1" || records[1][6] != "The Razors Edge" || records[1][8] != "100" || records[1][10] != "true" {
+		t.Errorf("first row = %v", records[1])
+	}
+	if records[2][0] != `Somebody - Cover, "Live"` {
+		t.Errorf("quoted title = %q", records[2][0])
+	}
+	if records[3][3] != "" || records[3][9] != TrackNotFound {
+		t.Errorf("unmatched row = %v", records[3])
+	}
+}
+
+func TestWriteM3U8(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteM3U8(&buf, exportSession()); err != nil {
+		t.Fatalf("WriteM3U8() error = %v", err)
+	}
+
+	want := "#EXTM3U\n#EXTINF:292,AC/DC - Thunderstruck\nAC_DC - Thunderstruck.mp3\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteM3U8() =\n%s\nwant\n%s", got, want)
+	}
+
+	// Tracks already in the library are listed too.
+	session := exportSession()
+	session.Tracks[1].Status = TrackSkipped
+	buf.Reset()
+	WriteM3U8(&buf, session)
+	if !strings.Contains(buf.String(), "#EXTINF:-1,Other - Cover\nOther - Cover.mp3\n") {
+		t.Errorf("playlist = %q, want the skipped track", buf.String())
+	}
+
+	// A selected track whose download failed is not.
+	session = exportSession()
+	session.Tracks[0].Status = TrackError
+	session.Tracks[0].Error = "not available in your country"
+	buf.Reset()
+	WriteM3U8(&buf, session)
+	if got := buf.String(); got != "#EXTM3U\n" {
+		t.Errorf("playlist = %q, want no failed track", got)
+	}
+
+	session = exportSession()
+	session.Bitrate = deemix.BitrateFLAC
+	buf.Reset()
+	WriteM3U8(&buf, session)
+	if !strings.Contains(buf.String(), "Thunderstruck.flac") {
+		t.Errorf("FLAC playlist = %q, want .flac paths", buf.String())
+	}
+}
@@ -0,0 +1,219 @@
+package sync
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gndm/ytToDeemix/internal/deemix"
+	"github.com/gndm/ytToDeemix/internal/navidrome"
+)
+
+// defaultPlaylistDelay is how long to wait after queueing before rescanning
+// Navidrome, to give Deemix time to finish the downloads when its queue
+// can't be followed.
+const defaultPlaylistDelay = 2 * time.Minute
+
+// scanPollInterval is how often SyncPlaylist checks whether a scan finished.
+const scanPollInterval = 2 * time.Second
+
+// SetCreatePlaylists enables building a Navidrome playlist in the background
+// after each download. Requires a Navidrome client that implements
+// navidrome.PlaylistClient.
+func (p *Pipeline) SetCreatePlaylists(enabled bool) {
+	p.createPlaylists = enabled
+}
+
+// syncPlaylistLater runs SyncPlaylistAfterDownload in the background.
+func (p *Pipeline) syncPlaylistLater(sessionID string) {
+	if err := p.SyncPlaylistAfterDownload(context.Background(), sessionID); err != nil {
+		log.Printf("[sync] session %s: playlist sync failed: %v", sessionID, err)
+	}
+}
+
+// SyncPlaylistAfterDownload runs SyncPlaylist for a session Download has
+// just finished. When the Deemix queue can't be followed, it first waits
+// for Deemix to finish the downloads. Blocks until the playlist is synced;
+// returns ctx.Err() if ctx is canceled while waiting.
+func (p *Pipeline) SyncPlaylistAfterDownload(ctx context.Context, sessionID string) error {
+	if _, tracking := p.deemixClient.(deemix.QueueClient); !tracking {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(p.playlistDelay):
+		}
+	}
+	return p.SyncPlaylist(ctx, sessionID)
+}
+
+// SyncPlaylist rescans the Navidrome library and makes sure a playlist named
+// after the YouTube playlist holds the session's tracks: the ones skipped as
+// already present and the ones that were downloaded. An existing playlist
+// with that name only gets the missing songs appended, so it is safe to run
+// again once more downloads have landed.
+// Only works when session is in StatusDone state.
+func (p *Pipeline) SyncPlaylist(ctx context.Context, sessionID string) error {
+	nav, ok := p.navidromeClient.(navidrome.PlaylistClient)
+	if !ok {
+		return ErrNoPlaylistSupport
+	}
+
+	p.mu.RLock()
+	session, ok := p.sessions[sessionID]
+	if !ok {
+		p.mu.RUnlock()
+		return ErrSessionNotFound
+	}
+	if session.Status != StatusDone {
+		p.mu.RUnlock()
+		return ErrSessionNotReady
+	}
+	title := session.Title
+	tracks := make([]Track, len(session.Tracks))
+	copy(tracks, session.Tracks)
+	p.mu.RUnlock()
+
+	if title == "" {
+		return ErrNoPlaylistTitle
+	}
+
+	if err := p.rescan(ctx, nav); err != nil {
+		return err
+	}
+
+	songIDs, missing := p.resolveSongs(ctx, nav, tracks)
+	log.Printf("[sync] session %s: %d tracks found in Navidrome, %d missing", sessionID, len(songIDs), missing)
+
+	playlistID, err := p.upsertPlaylist(ctx, nav, title, songIDs)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	session.NavidromePlaylist = playlistID
+	p.emitStatus(session)
+	p.mu.Unlock()
+	p.persist(session)
+
+	log.Printf("[sync] session %s: navidrome playlist %q synced", sessionID, title)
+	return nil
+}
+
+// rescan starts a library scan and waits for it to finish.
+func (p *Pipeline) rescan(ctx context.Context, nav navidrome.PlaylistClient) error {
+	if err := nav.StartScan(ctx); err != nil {
+		return err
+	}
+	for {
+		scanning, err := nav.Scanning(ctx)
+		if err != nil {
+			return err
+		}
+		if !scanning {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(p.scanPollInterval):
+		}
+	}
+}
+
+// resolveSongs looks up the Navidrome song ID of every skipped or downloaded
+// track, in playlist order. Returns the IDs and how many tracks were not found.
+func (p *Pipeline) resolveSongs(ctx context.Context, nav navidrome.Client, tracks []Track) ([]string, int) {
+	var ids []string
+	var missing int
+	for _, t := range tracks {
+		if t.Status != TrackSkipped && t.Status != TrackDownloaded {
+			continue
+		}
+
+		// Downloads are tagged with Deezer metadata; skipped tracks may have
+		// been found by their parsed YouTube title.
+		var id string
+		if t.DeezerMatch != nil {
+			id = findSong(ctx, nav, t.DeezerMatch.Artist, t.DeezerMatch.Title)
+		}
+		if id == "" && t.ParsedArtist != "" {
+			id = findSong(ctx, nav, artistCredit(t), t.ParsedSong)
+		}
+		if id == "" {
+			missing++
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, missing
+}
+
+func findSong(ctx context.Context, nav navidrome.Client, artist, title string) string {
+	results, err := nav.Search(ctx, artist, title)
+	if err != nil || len(results) == 0 {
+		return ""
+	}
+	return results[0].ID
+}
+
+// upsertPlaylist creates the named playlist, or appends the songs it lacks
+// if a playlist with that name already exists. Returns the playlist ID.
+func (p *Pipeline) upsertPlaylist(ctx context.Context, nav navidrome.PlaylistClient, name string, songIDs []string) (string, error) {
+	playlists, err := nav.Playlists(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	for _, pl := range playlists {
+		if pl.Name != name {
+			continue
+		}
+		existing, err := nav.PlaylistSongs(ctx, pl.ID)
+		if err != nil {
+			return "", err
+		}
+		have := make(map[string]bool, len(existing))
+		for _, id := range existing {
+			have[id] = true
+		}
+		var add []string
+		for _, id := range songIDs {
+			if !have[id] {
+				have[id] = true
+				add = append(add, id)
+			}
+		}
+		if len(add) > 0 {
+			if err := nav.UpdatePlaylist(ctx, pl.ID, add); err != nil {
+				return "", err
+			}
+		}
+		return pl.ID, nil
+	}
+
+	return nav.CreatePlaylist(ctx, name, dedupe(songIDs))
+}
+
+// dedupe removes repeated IDs, keeping the first occurrence.
+func dedupe(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	out := ids[:0:0]
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// artistCredit returns a track's parsed artists as a credit for Navidrome,
+// such as "Post Malone feat. Swae Lee". Navidrome compares the primary
+// artists and prefers songs that also credit the featured ones.
+func artistCredit(t Track) string {
+	if len(t.Featured) == 0 || t.ParsedArtist == "" {
+		return t.ParsedArtist
+	}
+	return t.ParsedArtist + " feat. " + strings.Join(t.Featured, ", ")
+}
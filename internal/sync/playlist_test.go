@@ -0,0 +1,181 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gndm/ytToDeemix/internal/deemix"
+	"github.com/gndm/ytToDeemix/internal/navidrome"
+	"github.com/gndm/ytToDeemix/internal/ytdlp"
+)
+
+// mockPlaylistClient implements navidrome.PlaylistClient for testing.
+type mockPlaylistClient struct {
+	mockNavidromeClient
+	scans     int
+	playlists []navidrome.Playlist
+	songs     map[string][]string
+	created   []string
+	added     []string
+}
+
+func (m *mockPlaylistClient) StartScan(context.Context) error {
+	m.scans++
+	return nil
+}
+
+func (m *mockPlaylistClient) Scanning(context.Context) (bool, error) {
+	return false, nil
+}
+
+func (m *mockPlaylistClient) Playlists(context.Context) ([]navidrome.Playlist, error) {
+	return m.playlists, nil
+}
+
+func (m *mockPlaylistClient) PlaylistSongs(_ context.Context, id string) ([]string, error) {
+	return m.songs[id], nil
+}
+
+func (m *mockPlaylistClient) CreatePlaylist(_ context.Context, name string, songIDs []string) (string, error) {
+	m.created = songIDs
+	m.playlists = append(m.playlists, navidrome.Playlist{ID: "pl-new", Name: name, SongCount: len(songIDs)})
+	return "pl-new", nil
+}
+
+func (m *mockPlaylistClient) UpdatePlaylist(_ context.Context, _ string, songIDs []string) error {
+	m.added = append(m.added, songIDs...)
+	return nil
+}
+
+func playlistTestPipeline(t *testing.T, nav navidrome.Client) (*Pipeline, string) {
+	t.Helper()
+	yt := &mockYTClient{
+		entries: []ytdlp.PlaylistEntry{
+			{Title: "Arctic Monkeys - Do I Wanna Know?", VideoID: "abc", PlaylistTitle: "Road Trip"},
+			{Title: "Radiohead - Creep", VideoID: "def", PlaylistTitle: "Road Trip"},
+			{Title: "Unknown - Nothing", VideoID: "ghi", PlaylistTitle: "Road Trip"},
+		},
+	}
+	dx := &mockDeemixClient{
+		searchResults: map[string][]deemix.SearchResult{
+			"Arctic Monkeys Do I Wanna Know?": {
+				{ID: 1, Title: "Do I Wanna Know?", Artist: "Arctic Monkeys"},
+			},
+			"Radiohead Creep": {
+				{ID: 2, Title: "Creep", Artist: "Radiohead"},
+			},
+		},
+	}
+
+	pipeline := NewPipeline(yt, dx, nav)
+	pipeline.searchDelay = 0
+	pipeline.queueDelay = 0
+	pipeline.checkDelay = 0
+	pipeline.scanPollInterval = 0
+
+	id := pipeline.Analyze(context.Background(), "https://youtube.com/playlist?list=test", deemix.Bitrate320, true)
+	if _, err := pipeline.WaitSettled(context.Background(), id); err != nil {
+		t.Fatalf("WaitSettled: %v", err)
+	}
+	return pipeline, id
+}
+
+func TestSyncPlaylist(t *testing.T) {
+	nav := &mockPlaylistClient{}
+	nav.existing = map[string][]navidrome.SearchResult{
+		"Arctic Monkeys|Do I Wanna Know?": {{ID: "42"}},
+	}
+	pipeline, id := playlistTestPipeline(t, nav)
+
+	if err := pipeline.SyncPlaylist(context.Background(), id); !errors.Is(err, ErrSessionNotReady) {
+		t.Fatalf("before download: err = %v, want ErrSessionNotReady", err)
+	}
+
+	if err := pipeline.Download(context.Background(), id); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+
+	// Deemix has finished "Creep" by the time of the rescan.
+	nav.existing["Radiohead|Creep"] = []navidrome.SearchResult{{ID: "43"}}
+
+	events, unsubscribe, err := pipeline.Subscribe(id)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer unsubscribe()
+
+	if err := pipeline.SyncPlaylist(context.Background(), id); err != nil {
+		t.Fatalf("SyncPlaylist: %v", err)
+	}
+	ev := nextEvent(t, events)
+	if se, ok := ev.Data.(StatusEvent); !ok || se.NavidromePlaylist != "pl-new" || se.Title != "Road Trip" {
+		t.Errorf("event = %+v, want status with playlist pl-new", ev)
+	}
+	if nav.scans != 1 {
+		t.Errorf("scans = %d, want 1", nav.scans)
+	}
+	if len(nav.created) != 2 || nav.created[0] != "42" || nav.created[1] != "43" {
+		t.Errorf("created with %v, want [42 43]", nav.created)
+	}
+
+	session, _ := pipeline.GetSession(id)
+	if session.Title != "Road Trip" {
+		t.Errorf("Title = %q, want Road Trip", session.Title)
+	}
+	if session.NavidromePlaylist != "pl-new" {
+		t.Errorf("NavidromePlaylist = %q, want pl-new", session.NavidromePlaylist)
+	}
+
+	// A second run appends only what is missing from the existing playlist.
+	nav.songs = map[string][]string{"pl-new": {"42"}}
+	if err := pipeline.SyncPlaylist(context.Background(), id); err != nil {
+		t.Fatalf("second SyncPlaylist: %v", err)
+	}
+	if len(nav.added) != 1 || nav.added[0] != "43" {
+		t.Errorf("added %v, want [43]", nav.added)
+	}
+}
+
+func TestSyncPlaylistAfterDownload(t *testing.T) {
+	nav := &mockPlaylistClient{}
+	nav.existing = map[string][]navidrome.SearchResult{
+		"Arctic Monkeys|Do I Wanna Know?": {{ID: "42"}},
+	}
+	pipeline, id := playlistTestPipeline(t, nav)
+	if err := pipeline.Download(context.Background(), id); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+
+	// Without queue tracking it waits for Deemix first.
+	pipeline.playlistDelay = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := pipeline.SyncPlaylistAfterDownload(ctx, id); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("canceled wait: err = %v, want deadline exceeded", err)
+	}
+	if nav.scans != 0 {
+		t.Errorf("scans = %d before the delay, want 0", nav.scans)
+	}
+
+	pipeline.playlistDelay = 0
+	if err := pipeline.SyncPlaylistAfterDownload(context.Background(), id); err != nil {
+		t.Fatalf("SyncPlaylistAfterDownload: %v", err)
+	}
+	if session, _ := pipeline.GetSession(id); session.NavidromePlaylist != "pl-new" {
+		t.Errorf("NavidromePlaylist = %q, want pl-new", session.NavidromePlaylist)
+	}
+}
+
+func TestSyncPlaylistErrors(t *testing.T) {
+	pipeline, id := playlistTestPipeline(t, &mockNavidromeClient{})
+	if err := pipeline.SyncPlaylist(context.Background(), id); !errors.Is(err, ErrNoPlaylistSupport) {
+		t.Errorf("plain client: err = %v, want ErrNoPlaylistSupport", err)
+	}
+
+	pipeline, _ = playlistTestPipeline(t, &mockPlaylistClient{})
+	if err := pipeline.SyncPlaylist(context.Background(), "missing"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("missing session: err = %v, want ErrSessionNotFound", err)
+	}
+}
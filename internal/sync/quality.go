@@ -0,0 +1,90 @@
+package sync
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gndm/ytToDeemix/internal/deemix"
+)
+
+// QualityFilter demotes Deezer results that look like karaoke versions,
+// covers or tributes rather than the original recording. A result whose
+// artist, title or album contains one of the keywords for that field loses
+// Penalty confidence points, unless the YouTube title contains the keyword
+// too: "Creep (Instrumental)" may still match an instrumental.
+// Keywords match whole words, case-insensitively.
+type QualityFilter struct {
+	Artist  []string
+	Title   []string
+	Album   []string
+	Penalty int
+}
+
+// DefaultQualityFilter catches the usual karaoke, tribute and sound-alike
+// releases. Its penalty takes a perfect title match below the default
+// confidence threshold.
+var DefaultQualityFilter = QualityFilter{
+	Artist: []string{"karaoke", "tribute", "in the style of", "cover band", "sound-alike", "soundalike"},
+	Title: []string{"karaoke", "instrumental", "in the style of", "made famous by", "originally performed by",
+		"tribute", "backing track", "cover"},
+	Album: []string{"karaoke", "instrumental", "in the style of", "made famous by", "originally performed by",
+		"tribute", "backing tracks", "sing-along"},
+	Penalty: 40,
+}
+
+// SetQualityFilter replaces the filter used to demote karaoke, cover and
+// tribute results. A zero QualityFilter disables demotion.
+func (p *Pipeline) SetQualityFilter(f QualityFilter) {
+	p.quality = f
+}
+
+// penalty returns the confidence points to subtract from r when matching a
+// video titled youtubeTitle. A result is penalised at most once.
+func (f QualityFilter) penalty(youtubeTitle string, r deemix.SearchResult) int {
+	fields := []struct {
+		text     string
+		keywords []string
+	}{
+		{r.Artist, f.Artist},
+		{r.Title, f.Title},
+		{r.Album, f.Album},
+	}
+	for _, field := range fields {
+		for _, keyword := range field.keywords {
+			if containsWord(field.text, keyword) && !containsWord(youtubeTitle, keyword) {
+				return f.Penalty
+			}
+		}
+	}
+	return 0
+}
+
+// containsWord reports whether phrase appears in s as whole words,
+// ignoring case: "cover" is found in "Creep (Cover)" but not in
+// "Discover".
+func containsWord(s, phrase string) bool {
+	s, phrase = strings.ToLower(s), strings.ToLower(strings.TrimSpace(phrase))
+	if phrase == "" {
+		return false
+	}
+	for offset := 0; ; {
+		i := strings.Index(s[offset:], phrase)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(phrase)
+		before, _ := utf8.DecodeLastRuneInString(s[:start])
+		after, _ := utf8.DecodeRuneInString(s[end:])
+		if !isWordRune(before) && !isWordRune(after) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+// isWordRune reports whether r is part of a word. utf8.RuneError, returned
+// at the ends of a string, is not.
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
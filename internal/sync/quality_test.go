@@ -0,0 +1,71 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/gndm/ytToDeemix/internal/deemix"
+)
+
+func TestContainsWord(t *testing.T) {
+	tests := []struct {
+		s, phrase string
+		want      bool
+	}{
+		{"Creep (Cover)", "cover", true},
+		{"Discover", "cover", false},
+		{"Covered", "cover", false},
+		{"Hits in the Style of Radiohead", "in the style of", true},
+		{"Karaoke", "KARAOKE", true},
+		{"Café Karaoke", "karaoke", true},
+		{"anything", "", false},
+	}
+	for _, tt := range tests {
+		if got := containsWord(tt.s, tt.phrase); got != tt.want {
+			t.Errorf("containsWord(%q, %q) = %v, want %v", tt.s, tt.phrase, got, tt.want)
+		}
+	}
+}
+
+func TestQualityFilterPenalty(t *testing.T) {
+	f := DefaultQualityFilter
+	tests := []struct {
+		name   string
+		title  string
+		result deemix.SearchResult
+		want   int
+	}{
+		{"original", "Radiohead - Creep", deemix.SearchResult{Artist: "Radiohead", Title: "Creep", Album: "Pablo Honey"}, 0},
+		{"karaoke title", "Radiohead - Creep", deemix.SearchResult{Artist: "Radiohead", Title: "Creep (Karaoke Version)"}, f.Penalty},
+		{"tribute artist", "Radiohead - Creep", deemix.SearchResult{Artist: "Radiohead Tribute Band", Title: "Creep"}, f.Penalty},
+		{"style album", "Radiohead - Creep", deemix.SearchResult{Artist: "Party Hits", Title: "Creep", Album: "Hits in the Style of Radiohead"}, f.Penalty},
+		{"asked for", "Radiohead - Creep (Instrumental)", deemix.SearchResult{Artist: "Radiohead", Title: "Creep (Instrumental)"}, 0},
+		{"asked for another", "Radiohead - Creep (Instrumental)", deemix.SearchResult{Artist: "Radiohead", Title: "Creep (Karaoke)"}, f.Penalty},
+	}
+	for _, tt := range tests {
+		if got := f.penalty(tt.title, tt.result); got != tt.want {
+			t.Errorf("%s: penalty = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRankCandidatesQuality(t *testing.T) {
+	results := []deemix.SearchResult{
+		{ID: 1, Artist: "Radiohead", Title: "Creep", Album: "Karaoke Hits Vol. 3"},
+		{ID: 2, Artist: "Radiohead", Title: "Creep", Album: "Pablo Honey"},
+	}
+
+	pipeline := NewPipeline(nil, nil, nil)
+	target := matchTarget{artist: "Radiohead", song: "Creep", title: "Radiohead - Creep"}
+	candidates := pipeline.rankCandidates(target, results)
+	if candidates[0].ID != 2 {
+		t.Errorf("best = %d, want the non-karaoke result 2", candidates[0].ID)
+	}
+	if candidates[1].Confidence >= pipeline.confidenceThreshold {
+		t.Errorf("karaoke confidence = %d, want below the threshold", candidates[1].Confidence)
+	}
+
+	pipeline.SetQualityFilter(QualityFilter{})
+	if candidates := pipeline.rankCandidates(target, results); candidates[0].ID != 1 {
+		t.Errorf("without a filter best = %d, want 1", candidates[0].ID)
+	}
+}
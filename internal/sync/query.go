@@ -0,0 +1,152 @@
+package sync
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/gndm/ytToDeemix/internal/deemix"
+)
+
+// Search strategies, tried in this order until one finds a match at or
+// above the confidence threshold. Track.MatchStrategy records which one
+// produced the match.
+const (
+	// StrategyISRC is an exact lookup of the ISRC an auto-generated
+	// upload's description gives. Its match always scores 100.
+	StrategyISRC = "isrc"
+	// StrategyStructured uses Deezer's advanced syntax:
+	// artist:"..." track:"...". Needs a parsed artist.
+	StrategyStructured = "structured"
+	// StrategyCombined is the artist and song as free text.
+	StrategyCombined = "combined"
+	// StrategySongOnly is the song alone, for a wrong or missing artist.
+	StrategySongOnly = "song_only"
+	// StrategyCleaned is the artist and song without bracketed parts,
+	// featured artists and punctuation.
+	StrategyCleaned = "cleaned"
+	// StrategyManual is a search typed in by the user.
+	StrategyManual = "manual"
+)
+
+// searchQuery is one query to send to Deezer.
+type searchQuery struct {
+	strategy string
+	query    string
+}
+
+// bracketed matches (...) and [...] segments.
+var bracketed = regexp.MustCompile(`\s*[\(\[][^\)\]]*[\)\]]`)
+
+// featTail matches a featured artist credit and everything after it.
+var featTail = regexp.MustCompile(`(?i)\s+\b(feat\.?|ft\.?|featuring)\s.*$`)
+
+// searchQueries returns the queries to try for a parsed track, in strategy
+// order. Strategies that would repeat an earlier query are left out. The
+// combined query names the version the video is, if any, so that Deezer
+// ranks a live or remixed recording among the first results.
+func searchQueries(target matchTarget) []searchQuery {
+	artist, song := target.artist, target.song
+	var queries []searchQuery
+	seen := make(map[string]bool)
+	add := func(strategy, query string) {
+		key := normalizeQuery(query)
+		if key == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		queries = append(queries, searchQuery{strategy: strategy, query: query})
+	}
+
+	if artist != "" && song != "" {
+		add(StrategyStructured, `artist:"`+stripQuotes(artist)+`" track:"`+stripQuotes(song)+`"`)
+	}
+	add(StrategyCombined, strings.TrimSpace(buildQuery(artist, song)+" "+target.version.String()))
+	add(StrategySongOnly, song)
+	add(StrategyCleaned, buildQuery(cleanTerm(artist), cleanTerm(song)))
+	return queries
+}
+
+// isrcQuery is the search cache key of an ISRC lookup, in the form Deezer's
+// API uses.
+func isrcQuery(isrc string) string {
+	return "isrc:" + isrc
+}
+
+// stripQuotes removes double quotes, which would end a structured field.
+func stripQuotes(s string) string {
+	return strings.ReplaceAll(s, `"`, "")
+}
+
+// cleanTerm drops bracketed parts, featured artists and punctuation.
+func cleanTerm(s string) string {
+	s = bracketed.ReplaceAllString(s, "")
+	s = featTail.ReplaceAllString(s, "")
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return ' '
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// searchStrategies runs the search strategies for a parsed track until one
+// finds a match at or above the confidence threshold. A track with an ISRC
+// is looked up by it first, and only searched for if Deezer doesn't know
+// the ISRC or the lookup fails. Results from every
+// strategy tried are merged and ranked together; each candidate records
+// the first strategy that returned it. query is the search that found the
+// best candidate, and cached reports whether every query was answered from
+// the search cache. A search that fails stops the strategies and returns
+// its error along with the candidates found so far, if any.
+func (p *Pipeline) searchStrategies(ctx context.Context, target matchTarget) (candidates []Candidate, query string, cached bool, err error) {
+	var results []Candidate
+	seen := make(map[int64]bool)
+	queries := make(map[string]string)
+	cached = true
+
+	if lookup, ok := p.deemixClient.(deemix.ISRCClient); ok && target.isrc != "" {
+		query := isrcQuery(target.isrc)
+		cached = p.cache.has(query)
+		found, err := lookup.SearchISRC(ctx, target.isrc)
+		if err != nil && ctx.Err() != nil {
+			return nil, query, false, err
+		}
+		if err != nil {
+			log.Printf("[sync] ISRC lookup failed for %s, searching instead: %v", target.isrc, err)
+		}
+		if found != nil {
+			return p.rankResults(target, []Candidate{{SearchResult: *found, Strategy: StrategyISRC}}), query, cached, nil
+		}
+	}
+
+	for _, q := range searchQueries(target) {
+		cached = cached && p.cache.has(q.query)
+		found, err := p.deemixClient.Search(ctx, q.query)
+		if err != nil {
+			if len(candidates) > 0 {
+				query = queries[candidates[0].Strategy]
+			}
+			return candidates, query, false, err
+		}
+		queries[q.strategy] = q.query
+		for _, r := range found {
+			if !seen[r.ID] {
+				seen[r.ID] = true
+				results = append(results, Candidate{SearchResult: r, Strategy: q.strategy})
+			}
+		}
+
+		candidates = p.rankResults(target, results)
+		if len(candidates) > 0 && candidates[0].Confidence >= p.confidenceThreshold {
+			break
+		}
+	}
+	if len(candidates) > 0 {
+		query = queries[candidates[0].Strategy]
+	}
+	return candidates, query, cached, nil
+}
@@ -0,0 +1,213 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gndm/ytToDeemix/internal/deemix"
+	"github.com/gndm/ytToDeemix/internal/parser"
+	"github.com/gndm/ytToDeemix/internal/ytdlp"
+)
+
+func TestSearchQueries(t *testing.T) {
+	tests := []struct {
+		target matchTarget
+		want   []searchQuery
+	}{
+		{matchTarget{artist: "Radiohead", song: "Creep"}, []searchQuery{
+			{StrategyStructured, `artist:"Radiohead" track:"Creep"`},
+			{StrategyCombined, "Radiohead Creep"},
+			{StrategySongOnly, "Creep"},
+		}},
+		{matchTarget{artist: "Daft Punk", song: "Get Lucky (Radio Edit) feat. Pharrell"}, []searchQuery{
+			{StrategyStructured, `artist:"Daft Punk" track:"Get Lucky (Radio Edit) feat. Pharrell"`},
+			{StrategyCombined, "Daft Punk Get Lucky (Radio Edit) feat. Pharrell"},
+			{StrategySongOnly, "Get Lucky (Radio Edit) feat. Pharrell"},
+			{StrategyCleaned, "Daft Punk Get Lucky"},
+		}},
+		{matchTarget{song: "Unknown Song!"}, []searchQuery{
+			{StrategyCombined, "Unknown Song!"},
+			{StrategyCleaned, "Unknown Song"},
+		}},
+		{matchTarget{artist: "AC/DC", song: `Back "In" Black`}, []searchQuery{
+			{StrategyStructured, `artist:"AC/DC" track:"Back In Black"`},
+			{StrategyCombined, `AC/DC Back "In" Black`},
+			{StrategySongOnly, `Back "In" Black`},
+			{StrategyCleaned, "AC DC Back In Black"},
+		}},
+		{matchTarget{artist: "Avicii", song: "Levels", version: parser.Version{Kind: parser.VersionRemix, Remixer: "Skrillex"}}, []searchQuery{
+			{StrategyStructured, `artist:"Avicii" track:"Levels"`},
+			{StrategyCombined, "Avicii Levels Skrillex remix"},
+			{StrategySongOnly, "Levels"},
+			{StrategyCleaned, "Avicii Levels"},
+		}},
+	}
+
+	for _, tt := range tests {
+		if got := searchQueries(tt.target); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("searchQueries(%+v) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestSearchStrategies(t *testing.T) {
+	yt := &mockYTClient{entries: []ytdlp.PlaylistEntry{
+		{Title: "Radiohead - Creep"},
+		{Title: "Radiohead Official - Karma Police"},
+		{Title: "Nobody - Nothing"},
+	}}
+	dx := &countingDeemixClient{mockDeemixClient: mockDeemixClient{
+		searchResults: map[string][]deemix.SearchResult{
+			`artist:"Radiohead" track:"Creep"`: {{ID: 1, Title: "Creep", Artist: "Radiohead"}},
+			"Radiohead Official Karma Police":  {{ID: 2, Title: "Karma Police (Karaoke)", Artist: "Party Hits"}},
+			"Karma Police":                     {{ID: 3, Title: "Karma Police", Artist: "Radiohead"}},
+		},
+	}}
+
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.searchDelay = 0
+	pipeline.SetWorkers(1)
+
+	id := pipeline.Analyze(context.Background(), "url", deemix.Bitrate320, false)
+	session, err := pipeline.WaitSettled(context.Background(), id)
+	if err != nil {
+		t.Fatalf("WaitSettled: %v", err)
+	}
+
+	// Creep stops at the structured query; Karma Police goes on to the
+	// song alone; the unknown song tries every distinct query.
+	if got := dx.searches.Load(); got != 1+3+3 {
+		t.Errorf("searches = %d, want 7", got)
+	}
+
+	creep := session.Tracks[0]
+	if creep.DeezerMatch == nil || creep.DeezerMatch.ID != 1 || creep.MatchStrategy != StrategyStructured {
+		t.Errorf("Creep: match %+v via %q, want ID 1 via structured", creep.DeezerMatch, creep.MatchStrategy)
+	}
+
+	karma := session.Tracks[1]
+	if karma.DeezerMatch == nil || karma.DeezerMatch.ID != 3 || karma.MatchStrategy != StrategySongOnly {
+		t.Errorf("Karma Police: match %+v via %q, want ID 3 via song_only", karma.DeezerMatch, karma.MatchStrategy)
+	}
+	// Results from earlier strategies stay as candidates.
+	if len(karma.Candidates) != 2 || karma.Candidates[1].Strategy != StrategyCombined {
+		t.Errorf("Karma Police candidates = %+v, want 2 with the karaoke one from combined", karma.Candidates)
+	}
+
+	if session.Tracks[2].Status != TrackNotFound || session.Tracks[2].MatchStrategy != "" {
+		t.Errorf("unknown track: status %q via %q, want not found", session.Tracks[2].Status, session.Tracks[2].MatchStrategy)
+	}
+}
+
+// failingDeemixClient fails the searches for failQuery.
+type failingDeemixClient struct {
+	mockDeemixClient
+	failQuery string
+}
+
+func (m *failingDeemixClient) Search(ctx context.Context, query string) ([]deemix.SearchResult, error) {
+	if query == m.failQuery {
+		return nil, fmt.Errorf("search failed (status 503)")
+	}
+	return m.mockDeemixClient.Search(ctx, query)
+}
+
+func TestSearchStrategiesKeepCandidatesOnError(t *testing.T) {
+	yt := &mockYTClient{entries: []ytdlp.PlaylistEntry{{Title: "Radiohead Official - Karma Police"}}}
+	dx := &failingDeemixClient{failQuery: "Karma Police", mockDeemixClient: mockDeemixClient{
+		searchResults: map[string][]deemix.SearchResult{
+			"Radiohead Official Karma Police": {{ID: 2, Title: "Karma Police (Karaoke)", Artist: "Party Hits"}},
+		},
+	}}
+
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.searchDelay = 0
+	id := pipeline.Analyze(context.Background(), "url", deemix.Bitrate320, false)
+	session, err := pipeline.WaitSettled(context.Background(), id)
+	if err != nil {
+		t.Fatalf("WaitSettled: %v", err)
+	}
+
+	// The song-only search failed, but the combined one had found a candidate.
+	track := session.Tracks[0]
+	if track.Status != TrackNeedsReview || track.DeezerMatch == nil || track.DeezerMatch.ID != 2 {
+		t.Errorf("status %q, match %+v, want the combined search's candidate to review", track.Status, track.DeezerMatch)
+	}
+}
+
+// isrcDeemixClient also looks tracks up by ISRC.
+type isrcDeemixClient struct {
+	countingDeemixClient
+	tracks  map[string]deemix.SearchResult
+	lookups atomic.Int32
+}
+
+func (m *isrcDeemixClient) SearchISRC(_ context.Context, isrc string) (*deemix.SearchResult, error) {
+	m.lookups.Add(1)
+	if r, ok := m.tracks[isrc]; ok {
+		return &r, nil
+	}
+	return nil, nil
+}
+
+func TestSearchStrategiesISRC(t *testing.T) {
+	sunflower := &ytdlp.TrackMetadata{
+		Title:   "Sunflower (Spider-Man: Into the Spider-Verse)",
+		Artists: []string{"Post Malone", "Swae Lee"},
+		ISRC:    "USUM71814888",
+	}
+	yt := &mockYTClient{entries: []ytdlp.PlaylistEntry{
+		{Title: "Sunflower (Spider-Man: Into the Spider-Verse)", VideoID: "a", Metadata: sunflower},
+		{Title: "Creep", VideoID: "b", Metadata: &ytdlp.TrackMetadata{Title: "Creep", Artists: []string{"Radiohead"}, ISRC: "GBAYE9200070"}},
+	}}
+	dx := &isrcDeemixClient{
+		countingDeemixClient: countingDeemixClient{mockDeemixClient: mockDeemixClient{
+			searchResults: map[string][]deemix.SearchResult{
+				`artist:"Radiohead" track:"Creep"`: {{ID: 2, Title: "Creep", Artist: "Radiohead"}},
+			},
+		}},
+		// The Deezer title differs, but the ISRC settles it.
+		tracks: map[string]deemix.SearchResult{
+			"USUM71814888": {ID: 1, Title: "Sunflower", Artist: "Post Malone"},
+		},
+	}
+
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.searchDelay = 0
+	pipeline.SetSearchCache(NewSearchCache("", time.Hour))
+
+	id := pipeline.Analyze(context.Background(), "url", deemix.Bitrate320, false)
+	session, err := pipeline.WaitSettled(context.Background(), id)
+	if err != nil {
+		t.Fatalf("WaitSettled: %v", err)
+	}
+
+	track := session.Tracks[0]
+	if track.ParsedArtist != "Post Malone" || !slices.Equal(track.Featured, []string{"Swae Lee"}) || track.ISRC != "USUM71814888" {
+		t.Errorf("parsed %q feat. %q, ISRC %q, want the description's credits and ISRC",
+			track.ParsedArtist, track.Featured, track.ISRC)
+	}
+	if track.DeezerMatch == nil || track.DeezerMatch.ID != 1 || track.MatchStrategy != StrategyISRC || track.Confidence != 100 {
+		t.Errorf("Sunflower: match %+v via %q at %d%%, want ID 1 via isrc at 100%%",
+			track.DeezerMatch, track.MatchStrategy, track.Confidence)
+	}
+
+	// An ISRC Deezer doesn't know falls back to searching.
+	creep := session.Tracks[1]
+	if creep.DeezerMatch == nil || creep.DeezerMatch.ID != 2 || creep.MatchStrategy != StrategyStructured {
+		t.Errorf("Creep: match %+v via %q, want ID 2 via structured", creep.DeezerMatch, creep.MatchStrategy)
+	}
+	if dx.lookups.Load() != 2 || dx.searches.Load() != 1 {
+		t.Errorf("lookups = %d, searches = %d, want 2 and 1", dx.lookups.Load(), dx.searches.Load())
+	}
+
+	// The found ISRC is cached.
+	if _, ok := pipeline.cache.Search(isrcQuery("USUM71814888")); !ok {
+		t.Error("ISRC lookup not cached")
+	}
+}
@@ -0,0 +1,125 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gndm/ytToDeemix/internal/deemix"
+)
+
+// defaultQueuePollInterval is how often the Deemix queue is read while a
+// session waits for its downloads.
+const defaultQueuePollInterval = 3 * time.Second
+
+// queueMissingLimit is how many polls in a row a queued track may be absent
+// from the Deemix queue before it is marked as failed. Covers items cleared
+// from the queue in the Deemix UI before they were seen finishing.
+const queueMissingLimit = 3
+
+// queueErrorLimit is how many failed queue reads in a row end the wait.
+const queueErrorLimit = 10
+
+// sentToDeemix reports whether a track with the given status was already
+// accepted by Deemix, so it must not be queued again.
+func sentToDeemix(status string) bool {
+	switch status {
+	case TrackQueued, TrackDownloading, TrackDownloaded:
+		return true
+	}
+	return false
+}
+
+// awaitDeemix polls the Deemix queue until every track of the session that
+// was sent to Deemix has finished downloading or failed. If the queue can't
+// be read for queueErrorLimit polls in a row, it gives up and returns an
+// error, as the outcome of the remaining tracks is unknown.
+func (p *Pipeline) awaitDeemix(ctx context.Context, session *Session, dx deemix.QueueClient) error {
+	missing := make(map[int]int)
+	var failures int
+	for {
+		if err := p.checkpoint(ctx, session, StatusDownloading); err != nil {
+			return err
+		}
+
+		items, err := dx.Queue(ctx)
+		switch {
+		case err != nil:
+			failures++
+			log.Printf("[sync] session %s: reading deemix queue: %v", session.ID, err)
+			if failures >= queueErrorLimit {
+				log.Printf("[sync] session %s: giving up on deemix queue after %d errors", session.ID, failures)
+				return fmt.Errorf("could not read the Deemix queue %d times in a row: %w", failures, err)
+			}
+		default:
+			failures = 0
+			if p.applyQueue(session, items, missing) == 0 {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(p.queuePollInterval):
+		}
+	}
+}
+
+// applyQueue updates the session's queued and downloading tracks from the
+// Deemix queue. Returns how many tracks are still pending in Deemix.
+func (p *Pipeline) applyQueue(session *Session, items map[string]deemix.QueueItem, missing map[int]int) int {
+	p.mu.Lock()
+	var pending int
+	var changed bool
+	for i := range session.Tracks {
+		track := &session.Tracks[i]
+		if track.Status != TrackQueued && track.Status != TrackDownloading {
+			continue
+		}
+
+		item, ok := items[deemix.QueueID(track.DeezerMatch.ID, session.Bitrate)]
+		if !ok {
+			missing[i]++
+			if missing[i] < queueMissingLimit {
+				pending++
+				continue
+			}
+			item = deemix.QueueItem{Status: deemix.QueueFailed, Error: "left the Deemix queue without a result"}
+		}
+		delete(missing, i)
+
+		status := track.Status
+		switch item.Status {
+		case deemix.QueueCompleted:
+			status = TrackDownloaded
+			session.Progress.Downloaded++
+		case deemix.QueueFailed, deemix.QueueWithErrors:
+			status = TrackError
+			track.Error = item.Error
+			if track.Error == "" {
+				track.Error = "download failed"
+			}
+			session.Progress.Failed++
+			log.Printf("[sync] session %s: track %d failed in deemix: %s", session.ID, i, track.Error)
+		case deemix.QueueDownloading:
+			status = TrackDownloading
+			pending++
+		default:
+			pending++
+		}
+
+		if status != track.Status {
+			track.Status = status
+			p.emitTrack(session, i)
+			changed = true
+		}
+	}
+	p.mu.Unlock()
+
+	if changed {
+		p.persist(session)
+	}
+	return pending
+}
@@ -0,0 +1,186 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gndm/ytToDeemix/internal/deemix"
+	"github.com/gndm/ytToDeemix/internal/ytdlp"
+)
+
+// mockQueueClient implements deemix.QueueClient for testing. Each call to
+// Queue returns the next snapshot; the last one repeats.
+type mockQueueClient struct {
+	mockDeemixClient
+	snapshots []map[string]deemix.QueueItem
+	polls     int
+	err       error
+}
+
+func (m *mockQueueClient) Queue(context.Context) (map[string]deemix.QueueItem, error) {
+	i := m.polls
+	if i >= len(m.snapshots) {
+		i = len(m.snapshots) - 1
+	}
+	m.polls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.snapshots[i], nil
+}
+
+func TestDownloadFollowsDeemixQueue(t *testing.T) {
+	yt := &mockYTClient{
+		entries: []ytdlp.PlaylistEntry{
+			{Title: "Arctic Monkeys - Do I Wanna Know?", VideoID: "abc"},
+			{Title: "Radiohead - Creep", VideoID: "def"},
+		},
+	}
+	dx := &mockQueueClient{
+		mockDeemixClient: mockDeemixClient{
+			searchResults: map[string][]deemix.SearchResult{
+				"Arctic Monkeys Do I Wanna Know?": {
+					{ID: 1, Title: "Do I Wanna Know?", Artist: "Arctic Monkeys", Link: "https://www.deezer.com/track/1"},
+				},
+				"Radiohead Creep": {
+					{ID: 2, Title: "Creep", Artist: "Radiohead", Link: "https://www.deezer.com/track/2"},
+				},
+			},
+		},
+		snapshots: []map[string]deemix.QueueItem{
+			{
+				"track_1_3": {Status: deemix.QueueDownloading},
+				"track_2_3": {Status: deemix.QueueInQueue},
+			},
+			{
+				"track_1_3": {Status: deemix.QueueCompleted},
+				"track_2_3": {Status: deemix.QueueFailed, Error: "Track not available in your country"},
+			},
+		},
+	}
+
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.searchDelay = 0
+	pipeline.queueDelay = 0
+	pipeline.queuePollInterval = 0
+
+	id := pipeline.Analyze(context.Background(), "https://youtube.com/playlist?list=test", deemix.Bitrate320, false)
+	if _, err := pipeline.WaitSettled(context.Background(), id); err != nil {
+		t.Fatalf("WaitSettled: %v", err)
+	}
+
+	events, unsubscribe, err := pipeline.Subscribe(id)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer unsubscribe()
+
+	if err := pipeline.Download(context.Background(), id); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+
+	session, _ := pipeline.GetSession(id)
+	if session.Status != StatusDone {
+		t.Errorf("session status = %q, want %q", session.Status, StatusDone)
+	}
+	if got := session.Tracks[0].Status; got != TrackDownloaded {
+		t.Errorf("track 0 status = %q, want %q", got, TrackDownloaded)
+	}
+	if got := session.Tracks[1].Status; got != TrackError {
+		t.Errorf("track 1 status = %q, want %q", got, TrackError)
+	}
+	if got := session.Tracks[1].Error; got != "Track not available in your country" {
+		t.Errorf("track 1 error = %q", got)
+	}
+	if session.Progress.Queued != 2 || session.Progress.Downloaded != 1 || session.Progress.Failed != 1 {
+		t.Errorf("progress = %+v, want 2 queued, 1 downloaded, 1 failed", session.Progress)
+	}
+
+	// Track 0 goes queued, downloading, downloaded.
+	var seen []string
+	for len(events) > 0 {
+		ev := <-events
+		if te, ok := ev.Data.(TrackEvent); ok && te.Index == 0 {
+			seen = append(seen, te.Track.Status)
+		}
+	}
+	want := []string{TrackQueued, TrackDownloading, TrackDownloaded}
+	if len(seen) != len(want) {
+		t.Fatalf("track 0 statuses = %v, want %v", seen, want)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Errorf("track 0 statuses = %v, want %v", seen, want)
+			break
+		}
+	}
+}
+
+func TestDownloadMissingFromDeemixQueue(t *testing.T) {
+	yt := &mockYTClient{
+		entries: []ytdlp.PlaylistEntry{{Title: "Radiohead - Creep", VideoID: "def"}},
+	}
+	dx := &mockQueueClient{
+		mockDeemixClient: mockDeemixClient{
+			searchResults: map[string][]deemix.SearchResult{
+				"Radiohead Creep": {{ID: 2, Title: "Creep", Artist: "Radiohead"}},
+			},
+		},
+		snapshots: []map[string]deemix.QueueItem{{}},
+	}
+
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.searchDelay = 0
+	pipeline.queueDelay = 0
+	pipeline.queuePollInterval = 0
+
+	id := pipeline.Analyze(context.Background(), "https://youtube.com/playlist?list=test", deemix.Bitrate320, false)
+	pipeline.WaitSettled(context.Background(), id)
+	if err := pipeline.Download(context.Background(), id); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+
+	session, _ := pipeline.GetSession(id)
+	if got := session.Tracks[0].Status; got != TrackError || session.Tracks[0].Error != "left the Deemix queue without a result" {
+		t.Errorf("status = %q, error %q, want an error", got, session.Tracks[0].Error)
+	}
+	if dx.polls != queueMissingLimit {
+		t.Errorf("polls = %d, want %d", dx.polls, queueMissingLimit)
+	}
+}
+
+func TestDownloadDeemixQueueUnreadable(t *testing.T) {
+	yt := &mockYTClient{
+		entries: []ytdlp.PlaylistEntry{{Title: "Radiohead - Creep", VideoID: "def"}},
+	}
+	dx := &mockQueueClient{
+		mockDeemixClient: mockDeemixClient{
+			searchResults: map[string][]deemix.SearchResult{
+				"Radiohead Creep": {{ID: 2, Title: "Creep", Artist: "Radiohead"}},
+			},
+		},
+		err: fmt.Errorf("connection refused"),
+	}
+
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.searchDelay = 0
+	pipeline.queueDelay = 0
+	pipeline.queuePollInterval = 0
+
+	id := pipeline.Analyze(context.Background(), "https://youtube.com/playlist?list=test", deemix.Bitrate320, false)
+	pipeline.WaitSettled(context.Background(), id)
+	if err := pipeline.Download(context.Background(), id); err == nil {
+		t.Fatal("Download() succeeded, want an error")
+	}
+
+	// The downloads' outcome is unknown, so the session is not done.
+	session, _ := pipeline.GetSession(id)
+	if session.Status != StatusError || !strings.Contains(session.Error, "Deemix queue") {
+		t.Errorf("status %q, error %q, want a queue error", session.Status, session.Error)
+	}
+	if session.Tracks[0].Status != TrackQueued || dx.polls != queueErrorLimit {
+		t.Errorf("track %q after %d polls, want queued after %d", session.Tracks[0].Status, dx.polls, queueErrorLimit)
+	}
+}
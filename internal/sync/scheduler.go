@@ -0,0 +1,184 @@
+package sync
+
+import (
+	"context"
+	"log"
+	"sort"
+)
+
+// DefaultMaxActive is how many sessions may analyze or download at once.
+const DefaultMaxActive = 3
+
+// waiter is a session waiting for a slot in the scheduler.
+type waiter struct {
+	session *Session
+	seq     int // arrival order, to keep equal priorities first come first served
+	ready   chan struct{}
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a context that gives the session Analyze or
+// AnalyzeEntries create from it the scheduling priority priority, so it
+// takes its place in the queue before its first acquire.
+func WithPriority(ctx context.Context, priority int) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// priorityFrom returns the priority set by WithPriority, or 0.
+func priorityFrom(ctx context.Context) int {
+	priority, _ := ctx.Value(priorityKey{}).(int)
+	return priority
+}
+
+// SetMaxActive sets how many sessions may analyze or download at the same
+// time across the pipeline. Others wait in StatusQueued, highest priority
+// first. 0 or less removes the limit.
+func (p *Pipeline) SetMaxActive(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.maxActive = n
+	p.dispatch()
+}
+
+// SetPriority changes a session's scheduling priority. Higher runs first;
+// the default is 0. A waiting session moves in the queue at once.
+func (p *Pipeline) SetPriority(sessionID string, priority int) error {
+	p.mu.Lock()
+	session, ok := p.sessions[sessionID]
+	if !ok {
+		p.mu.Unlock()
+		return ErrSessionNotFound
+	}
+	session.Priority = priority
+	p.sortWaiting()
+	p.mu.Unlock()
+	p.persist(session)
+	return nil
+}
+
+// acquire waits for a scheduler slot. If none is free the session shows
+// StatusQueued with its queue position until one is, then returns to
+// status. Returns an error if ctx is canceled first. Every successful
+// acquire must be paired with a release.
+func (p *Pipeline) acquire(ctx context.Context, session *Session, status string) error {
+	p.mu.Lock()
+	if len(p.waiting) == 0 && p.hasSlot() {
+		p.active++
+		p.holding[session.ID] = true
+		p.mu.Unlock()
+		return nil
+	}
+
+	w := &waiter{session: session, seq: p.waitSeq, ready: make(chan struct{})}
+	p.waitSeq++
+	p.waiting = append(p.waiting, w)
+	session.Status = StatusQueued
+	session.QueuedFor = status
+	p.sortWaiting()
+	log.Printf("[sync] session %s queued at position %d", session.ID, session.QueuePosition)
+	p.mu.Unlock()
+	p.persist(session)
+
+	select {
+	case <-w.ready:
+		p.mu.Lock()
+		session.Status = status
+		session.QueuedFor = ""
+		p.emitStatus(session)
+		p.mu.Unlock()
+		p.persist(session)
+		return nil
+
+	case <-ctx.Done():
+		p.mu.Lock()
+		select {
+		case <-w.ready:
+			// Granted while being canceled: hand the slot on.
+			delete(p.holding, session.ID)
+			p.active--
+			p.dispatch()
+		default:
+			p.removeWaiter(w)
+		}
+		session.QueuePosition = 0
+		session.QueuedFor = ""
+		p.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// release frees the slot session took with acquire and starts the next
+// waiting session. Does nothing if the session holds no slot, as when it
+// gave its slot up on pause and was canceled before getting one back.
+func (p *Pipeline) release(session *Session) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.holding[session.ID] {
+		return
+	}
+	delete(p.holding, session.ID)
+	p.active--
+	p.dispatch()
+}
+
+// hasSlot reports whether another session may start.
+// Must be called with p.mu held.
+func (p *Pipeline) hasSlot() bool {
+	return p.maxActive <= 0 || p.active < p.maxActive
+}
+
+// dispatch grants free slots to the waiting sessions in queue order.
+// Must be called with p.mu held.
+func (p *Pipeline) dispatch() {
+	granted := false
+	for len(p.waiting) > 0 && p.hasSlot() {
+		w := p.waiting[0]
+		p.waiting = p.waiting[1:]
+		w.session.QueuePosition = 0
+		p.active++
+		p.holding[w.session.ID] = true
+		close(w.ready)
+		granted = true
+	}
+	if granted {
+		p.updatePositions()
+	}
+}
+
+// removeWaiter drops a waiter that gave up.
+// Must be called with p.mu held.
+func (p *Pipeline) removeWaiter(w *waiter) {
+	for i, other := range p.waiting {
+		if other == w {
+			p.waiting = append(p.waiting[:i], p.waiting[i+1:]...)
+			p.updatePositions()
+			return
+		}
+	}
+}
+
+// sortWaiting orders the queue by priority, then arrival.
+// Must be called with p.mu held.
+func (p *Pipeline) sortWaiting() {
+	sort.SliceStable(p.waiting, func(i, j int) bool {
+		a, b := p.waiting[i], p.waiting[j]
+		if a.session.Priority != b.session.Priority {
+			return a.session.Priority > b.session.Priority
+		}
+		return a.seq < b.seq
+	})
+	p.updatePositions()
+}
+
+// updatePositions refreshes QueuePosition of every waiting session and
+// publishes the ones that moved.
+// Must be called with p.mu held.
+func (p *Pipeline) updatePositions() {
+	for i, w := range p.waiting {
+		if w.session.QueuePosition != i+1 {
+			w.session.QueuePosition = i + 1
+			p.emitStatus(w.session)
+		}
+	}
+}
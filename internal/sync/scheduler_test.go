@@ -0,0 +1,163 @@
+package sync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gndm/ytToDeemix/internal/deemix"
+)
+
+func schedulerTestPipeline(t *testing.T) *Pipeline {
+	t.Helper()
+	yt, results := workersTestClients(4)
+	dx := &concurrentDeemixClient{
+		mockDeemixClient: mockDeemixClient{searchResults: results},
+		delay:            50 * time.Millisecond,
+	}
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.searchDelay = 0
+	pipeline.SetWorkers(1)
+	pipeline.SetMaxActive(1)
+	return pipeline
+}
+
+func TestSchedulerQueuesSessions(t *testing.T) {
+	pipeline := schedulerTestPipeline(t)
+
+	first := pipeline.Analyze(context.Background(), "url1", deemix.Bitrate320, false)
+	time.Sleep(10 * time.Millisecond)
+	second := pipeline.Analyze(context.Background(), "url2", deemix.Bitrate320, false)
+	time.Sleep(10 * time.Millisecond)
+
+	session, _ := pipeline.GetSession(second)
+	if session.Status != StatusQueued || session.QueuePosition != 1 {
+		t.Fatalf("second session: status %q position %d, want queued at 1", session.Status, session.QueuePosition)
+	}
+
+	s, err := pipeline.WaitSettled(context.Background(), first)
+	if err != nil || s.Status != StatusReady {
+		t.Fatalf("first session: %v, status %v", err, s)
+	}
+	session, err = pipeline.WaitSettled(context.Background(), second)
+	if err != nil {
+		t.Fatalf("WaitSettled: %v", err)
+	}
+	if session.Status != StatusReady || session.QueuePosition != 0 || session.Progress.Searched != 4 {
+		t.Errorf("second session: status %q position %d searched %d, want ready, 0, 4",
+			session.Status, session.QueuePosition, session.Progress.Searched)
+	}
+}
+
+func TestSchedulerPriority(t *testing.T) {
+	pipeline := schedulerTestPipeline(t)
+
+	running := pipeline.Analyze(context.Background(), "url1", deemix.Bitrate320, false)
+	time.Sleep(10 * time.Millisecond)
+	low := pipeline.Analyze(context.Background(), "url2", deemix.Bitrate320, false)
+	high := pipeline.Analyze(context.Background(), "url3", deemix.Bitrate320, false)
+	time.Sleep(10 * time.Millisecond)
+
+	if err := pipeline.SetPriority(high, 5); err != nil {
+		t.Fatalf("SetPriority: %v", err)
+	}
+
+	lowSession, _ := pipeline.GetSession(low)
+	highSession, _ := pipeline.GetSession(high)
+	if highSession.QueuePosition != 1 || lowSession.QueuePosition != 2 {
+		t.Errorf("positions: high %d, low %d, want 1 and 2", highSession.QueuePosition, lowSession.QueuePosition)
+	}
+
+	// The high priority session starts as soon as the running one is done.
+	pipeline.WaitSettled(context.Background(), running)
+	time.Sleep(10 * time.Millisecond)
+	highSession, _ = pipeline.GetSession(high)
+	lowSession, _ = pipeline.GetSession(low)
+	if highSession.Status == StatusQueued {
+		t.Errorf("high priority session still queued")
+	}
+	if lowSession.Status != StatusQueued || lowSession.QueuePosition != 1 {
+		t.Errorf("low priority session: status %q position %d, want queued at 1", lowSession.Status, lowSession.QueuePosition)
+	}
+}
+
+func TestSchedulerCancelWaiting(t *testing.T) {
+	pipeline := schedulerTestPipeline(t)
+
+	running := pipeline.Analyze(context.Background(), "url1", deemix.Bitrate320, false)
+	time.Sleep(10 * time.Millisecond)
+	canceled := pipeline.Analyze(context.Background(), "url2", deemix.Bitrate320, false)
+	waiting := pipeline.Analyze(context.Background(), "url3", deemix.Bitrate320, false)
+	time.Sleep(10 * time.Millisecond)
+
+	if err := pipeline.CancelSession(canceled); err != nil {
+		t.Fatalf("CancelSession: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	session, _ := pipeline.GetSession(waiting)
+	if session.QueuePosition != 1 {
+		t.Errorf("waiting session position = %d, want 1", session.QueuePosition)
+	}
+
+	pipeline.WaitSettled(context.Background(), running)
+	session, err := pipeline.WaitSettled(context.Background(), waiting)
+	if err != nil || session.Status != StatusReady {
+		t.Errorf("waiting session: %v, status %q, want ready", err, session.Status)
+	}
+	session, _ = pipeline.GetSession(canceled)
+	if session.Status != StatusCanceled {
+		t.Errorf("canceled session status = %q", session.Status)
+	}
+}
+
+func TestSchedulerPriorityFromContext(t *testing.T) {
+	pipeline := schedulerTestPipeline(t)
+
+	running := pipeline.Analyze(context.Background(), "url1", deemix.Bitrate320, false)
+	time.Sleep(10 * time.Millisecond)
+	low := pipeline.Analyze(context.Background(), "url2", deemix.Bitrate320, false)
+	high := pipeline.Analyze(WithPriority(context.Background(), 5), "url3", deemix.Bitrate320, false)
+	time.Sleep(10 * time.Millisecond)
+
+	highSession, _ := pipeline.GetSession(high)
+	lowSession, _ := pipeline.GetSession(low)
+	if highSession.Priority != 5 {
+		t.Errorf("high priority = %d, want 5", highSession.Priority)
+	}
+	if highSession.QueuePosition != 1 || lowSession.QueuePosition != 2 {
+		t.Errorf("positions: high %d, low %d, want 1 and 2", highSession.QueuePosition, lowSession.QueuePosition)
+	}
+	pipeline.WaitSettled(context.Background(), running)
+}
+
+func TestSchedulerPauseFreesSlot(t *testing.T) {
+	pipeline := schedulerTestPipeline(t)
+
+	paused := pipeline.Analyze(context.Background(), "url1", deemix.Bitrate320, false)
+	time.Sleep(10 * time.Millisecond)
+	waiting := pipeline.Analyze(context.Background(), "url2", deemix.Bitrate320, false)
+	time.Sleep(10 * time.Millisecond)
+
+	if err := pipeline.PauseSession(paused); err != nil {
+		t.Fatalf("PauseSession: %v", err)
+	}
+
+	// The waiting session gets the paused one's slot and finishes.
+	session, err := pipeline.WaitSettled(context.Background(), waiting)
+	if err != nil || session.Status != StatusReady {
+		t.Fatalf("waiting session: %v, status %v, want ready", err, session)
+	}
+	session, _ = pipeline.GetSession(paused)
+	if session.Status != StatusPaused {
+		t.Fatalf("paused session status = %q", session.Status)
+	}
+
+	if err := pipeline.ResumeSession(paused); err != nil {
+		t.Fatalf("ResumeSession: %v", err)
+	}
+	session, err = pipeline.WaitSettled(context.Background(), paused)
+	if err != nil || session.Status != StatusReady || session.Progress.Searched != 4 {
+		t.Errorf("resumed session: %v, %v, want ready with 4 searched", err, session)
+	}
+}
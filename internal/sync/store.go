@@ -0,0 +1,168 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Store persists sessions so they survive a server restart.
+type Store interface {
+	Save(session *Session) error
+	Load() ([]*Session, error)
+	Delete(id string) error
+}
+
+// FileStore implements Store with one JSON file per session in a directory.
+type FileStore struct {
+	Dir string
+}
+
+// NewFileStore creates a FileStore rooted at dir, creating the directory if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating session directory: %w", err)
+	}
+	return &FileStore{Dir: dir}, nil
+}
+
+// Save writes the session atomically (temp file + rename).
+func (s *FileStore) Save(session *Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("encoding session: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.Dir, session.ID+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing session: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing session: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path(session.ID)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("renaming session file: %w", err)
+	}
+	return nil
+}
+
+// Load reads every stored session. Unreadable files are logged and skipped.
+func (s *FileStore) Load() ([]*Session, error) {
+	files, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading session directory: %w", err)
+	}
+
+	var sessions []*Session
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.Dir, f.Name()))
+		if err != nil {
+			log.Printf("[store] skipping %s: %v", f.Name(), err)
+			continue
+		}
+		var session Session
+		if err := json.Unmarshal(data, &session); err != nil || session.ID == "" {
+			log.Printf("[store] skipping malformed session file %s", f.Name())
+			continue
+		}
+		sessions = append(sessions, &session)
+	}
+	return sessions, nil
+}
+
+// Delete removes a stored session. Deleting a missing session is not an error.
+func (s *FileStore) Delete(id string) error {
+	if err := os.Remove(s.path(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("deleting session: %w", err)
+	}
+	return nil
+}
+
+// Migrate moves session files from dir, where sessions were stored before
+// they got their own directory, into the store. Other files in dir, such
+// as watches and the search cache, are left alone. Returns how many
+// sessions were moved.
+func (s *FileStore) Migrate(dir string) (int, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, fmt.Errorf("reading old session directory: %w", err)
+	}
+
+	moved := 0
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		old := filepath.Join(dir, f.Name())
+		data, err := os.ReadFile(old)
+		if err != nil {
+			continue
+		}
+		var session Session
+		if json.Unmarshal(data, &session) != nil || session.ID+".json" != f.Name() {
+			continue
+		}
+		if err := os.Rename(old, s.path(session.ID)); err != nil {
+			return moved, fmt.Errorf("moving session %s: %w", session.ID, err)
+		}
+		moved++
+	}
+	return moved, nil
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.Dir, id+".json")
+}
+
+// FileWatchStore implements WatchStore with a single JSON file.
+type FileWatchStore struct {
+	Path string
+}
+
+// SaveWatches writes all watches atomically (temp file + rename).
+func (s *FileWatchStore) SaveWatches(watches []Watch) error {
+	data, err := json.MarshalIndent(watches, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding watches: %w", err)
+	}
+
+	tmp := s.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("writing watches: %w", err)
+	}
+	if err := os.Rename(tmp, s.Path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("renaming watches file: %w", err)
+	}
+	return nil
+}
+
+// LoadWatches reads the stored watches. A missing file means no watches.
+func (s *FileWatchStore) LoadWatches() ([]Watch, error) {
+	data, err := os.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading watches: %w", err)
+	}
+
+	var watches []Watch
+	if err := json.Unmarshal(data, &watches); err != nil {
+		return nil, fmt.Errorf("decoding watches: %w", err)
+	}
+	return watches, nil
+}
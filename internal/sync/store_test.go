@@ -0,0 +1,105 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gndm/ytToDeemix/internal/deemix"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "sessions"))
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+
+	session := &Session{
+		ID:     "abc123",
+		URL:    "https://youtube.com/playlist?list=test",
+		Status: StatusReady,
+		Tracks: []Track{
+			{
+				YouTubeTitle: "Artist - Song",
+				ParsedArtist: "Artist",
+				ParsedSong:   "Song",
+				DeezerMatch:  &deemix.SearchResult{ID: 1, Title: "Song", Artist: "Artist"},
+				Status:       TrackFound,
+				Confidence:   100,
+				Selected:     true,
+			},
+		},
+		Progress: Progress{Total: 1, Searched: 1, Selected: 1},
+		Bitrate:  deemix.Bitrate320,
+	}
+	if err := store.Save(session); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(loaded) != 1 {
+		t.Fatalf("expected 1 session, got %d", len(loaded))
+	}
+	got := loaded[0]
+	if got.ID != session.ID || got.Status != StatusReady || got.Progress.Selected != 1 {
+		t.Errorf("loaded session = %+v", got)
+	}
+	if len(got.Tracks) != 1 || got.Tracks[0].DeezerMatch == nil || !got.Tracks[0].Selected {
+		t.Errorf("loaded tracks = %+v", got.Tracks)
+	}
+
+	if err := store.Delete(session.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	loaded, _ = store.Load()
+	if len(loaded) != 0 {
+		t.Errorf("expected 0 sessions after delete, got %d", len(loaded))
+	}
+	if err := store.Delete(session.ID); err != nil {
+		t.Errorf("Delete() of missing session error = %v", err)
+	}
+}
+
+func TestFileStoreSkipsMalformed(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := NewFileStore(dir)
+
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{not json"), 0o644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0o644)
+	store.Save(&Session{ID: "good", Status: StatusDone})
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(loaded) != 1 || loaded[0].ID != "good" {
+		t.Errorf("expected only the valid session, got %+v", loaded)
+	}
+}
+
+func TestFileStoreMigrate(t *testing.T) {
+	dataDir := t.TempDir()
+	old := &FileStore{Dir: dataDir}
+	old.Save(&Session{ID: "old1", Status: StatusReady})
+	old.Save(&Session{ID: "old2", Status: StatusDone})
+	os.WriteFile(filepath.Join(dataDir, "watches.json"), []byte(`[{"id":"w1"}]`), 0o644)
+	os.WriteFile(filepath.Join(dataDir, "search-cache.json"), []byte(`{"queries":{}}`), 0o644)
+
+	store, _ := NewFileStore(filepath.Join(dataDir, "sessions"))
+	n, err := store.Migrate(dataDir)
+	if err != nil || n != 2 {
+		t.Fatalf("Migrate() = %d, %v, want 2 sessions moved", n, err)
+	}
+	loaded, _ := store.Load()
+	if len(loaded) != 2 {
+		t.Errorf("loaded %d sessions after migrating, want 2", len(loaded))
+	}
+	for _, name := range []string{"watches.json", "search-cache.json"} {
+		if _, err := os.Stat(filepath.Join(dataDir, name)); err != nil {
+			t.Errorf("%s was moved or removed: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package sync
+
+import (
+	"context"
+	"log"
+
+	"github.com/gndm/ytToDeemix/internal/ytdlp"
+)
+
+// streamSearch runs phases 1 to 3 at once for a client that streams the
+// playlist: each entry becomes tracks as soon as yt-dlp prints it, and those
+// are searched while the rest of the playlist is still being fetched. The
+// session reports StatusFetching, with Progress.Fetched counting entries,
+// until the playlist is complete. A session interrupted mid-fetch keeps the
+// tracks it had and skips their entries when fetched again. Returns false
+// if fetching failed or the session was canceled.
+func (p *Pipeline) streamSearch(ctx context.Context, session *Session, stream ytdlp.StreamClient) bool {
+	p.mu.Lock()
+	session.Fetching = true
+	skip := session.Progress.Fetched
+	p.emitStatus(session)
+	p.mu.Unlock()
+	p.persist(session)
+
+	var fetchErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		received := 0
+		fetchErr = stream.StreamPlaylist(ctx, session.URL, func(entry ytdlp.PlaylistEntry) {
+			received++
+			if received > skip {
+				p.addEntry(session, entry)
+			}
+		})
+
+		p.mu.Lock()
+		session.Fetching = false
+		if session.Status == StatusFetching {
+			session.Status = StatusSearching
+		}
+		p.emitStatus(session)
+		log.Printf("[sync] session %s fetched %d entries", session.ID, session.Progress.Fetched)
+		p.mu.Unlock()
+		p.signalGrown(session)
+		p.persist(session)
+	}()
+
+	ok := p.searchTracks(ctx, session)
+	<-done
+	if !ok {
+		return false
+	}
+	if fetchErr != nil {
+		p.setError(session, "failed to fetch playlist: "+fetchErr.Error())
+		return false
+	}
+	return true
+}
+
+// addEntry appends the tracks of a streamed playlist entry and wakes the
+// search waiting for them.
+func (p *Pipeline) addEntry(session *Session, entry ytdlp.PlaylistEntry) {
+	p.mu.Lock()
+	first := len(session.Tracks)
+	p.appendEntry(session, entry)
+	for i := first; i < len(session.Tracks); i++ {
+		p.emitTrack(session, i)
+	}
+	p.mu.Unlock()
+	p.signalGrown(session)
+}
+
+// signalGrown wakes forEachTrack when tracks were added or fetching ended.
+func (p *Pipeline) signalGrown(session *Session) {
+	p.mu.RLock()
+	ctrl := p.controls[session.ID]
+	p.mu.RUnlock()
+	if ctrl == nil {
+		return
+	}
+	select {
+	case ctrl.grown <- struct{}{}:
+	default:
+		// Already signaled.
+	}
+}
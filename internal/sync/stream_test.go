@@ -0,0 +1,103 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	gosync "sync"
+	"testing"
+	"time"
+
+	"github.com/gndm/ytToDeemix/internal/deemix"
+	"github.com/gndm/ytToDeemix/internal/ytdlp"
+)
+
+// streamingYTClient implements ytdlp.StreamClient. It delivers the first
+// entry, then holds the rest back until searched is closed.
+type streamingYTClient struct {
+	mockYTClient
+	searched chan struct{}
+}
+
+func (m *streamingYTClient) StreamPlaylist(ctx context.Context, _ string, fn func(ytdlp.PlaylistEntry)) error {
+	for i, entry := range m.entries {
+		if i == 1 {
+			select {
+			case <-m.searched:
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+				return fmt.Errorf("first track was not searched while fetching")
+			}
+		}
+		fn(entry)
+	}
+	return m.err
+}
+
+// signalingDeemixClient closes searched on its first search.
+type signalingDeemixClient struct {
+	mockDeemixClient
+	searched chan struct{}
+	once     gosync.Once
+}
+
+func (m *signalingDeemixClient) Search(ctx context.Context, query string) ([]deemix.SearchResult, error) {
+	m.once.Do(func() { close(m.searched) })
+	return m.mockDeemixClient.Search(ctx, query)
+}
+
+func TestPipelineStreamsPlaylist(t *testing.T) {
+	searched := make(chan struct{})
+	yt := &streamingYTClient{searched: searched, mockYTClient: mockYTClient{entries: []ytdlp.PlaylistEntry{
+		{Title: "Radiohead - Creep", VideoID: "abc", PlaylistTitle: "Mix"},
+		{Title: "Muse - Uprising", VideoID: "def"},
+		{Title: "Unknown Song Title", VideoID: "ghi"},
+	}}}
+	dx := &signalingDeemixClient{searched: searched, mockDeemixClient: mockDeemixClient{
+		searchResults: map[string][]deemix.SearchResult{
+			"Radiohead Creep": {{ID: 1, Title: "Creep", Artist: "Radiohead"}},
+			"Muse Uprising":   {{ID: 2, Title: "Uprising", Artist: "Muse"}},
+		},
+	}}
+
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.searchDelay = 0
+	id := pipeline.Analyze(context.Background(), "url", deemix.Bitrate320, false)
+	session, err := pipeline.WaitSettled(context.Background(), id)
+	if err != nil {
+		t.Fatalf("WaitSettled: %v", err)
+	}
+
+	if session.Status != StatusReady || session.Fetching {
+		t.Fatalf("status %q, fetching %v, error %q; want ready", session.Status, session.Fetching, session.Error)
+	}
+	if session.Title != "Mix" {
+		t.Errorf("title = %q, want Mix", session.Title)
+	}
+	if session.Progress.Fetched != 3 || session.Progress.Total != 3 || session.Progress.Searched != 3 {
+		t.Errorf("progress = %+v, want 3 fetched, total and searched", session.Progress)
+	}
+	for i, want := range []int64{1, 2, 0} {
+		track := session.Tracks[i]
+		if want == 0 {
+			if track.Status != TrackNotFound {
+				t.Errorf("track %d status = %q, want not found", i, track.Status)
+			}
+		} else if track.DeezerMatch == nil || track.DeezerMatch.ID != want {
+			t.Errorf("track %d matched %+v, want ID %d", i, track.DeezerMatch, want)
+		}
+	}
+}
+
+func TestPipelineStreamError(t *testing.T) {
+	yt := &streamingYTClient{mockYTClient: mockYTClient{err: fmt.Errorf("network error")}}
+	pipeline := NewPipeline(yt, &mockDeemixClient{}, nil)
+	id := pipeline.Analyze(context.Background(), "bad-url", deemix.Bitrate320, false)
+	session, err := pipeline.WaitSettled(context.Background(), id)
+	if err != nil {
+		t.Fatalf("WaitSettled: %v", err)
+	}
+	if session.Status != StatusError || session.Error != "failed to fetch playlist: network error" {
+		t.Errorf("status %q, error %q; want fetch error", session.Status, session.Error)
+	}
+}
@@ -5,6 +5,10 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"log"
+	"math"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,11 +21,27 @@ import (
 // Default confidence threshold (0-100).
 const DefaultConfidenceThreshold = 70
 
+// waitPollInterval is how often WaitSettled checks the session status.
+const waitPollInterval = 250 * time.Millisecond
+
 // sessionControl holds cancellation and pause/resume channels for a session.
+// grown is signaled when tracks are added while the playlist is streamed in.
 type sessionControl struct {
 	cancel   context.CancelFunc
 	pauseCh  chan struct{}
 	resumeCh chan struct{}
+	grown    chan struct{}
+}
+
+// newSessionControl derives a cancellable context with fresh pause/resume channels.
+func newSessionControl(ctx context.Context) (context.Context, *sessionControl) {
+	ctx, cancel := context.WithCancel(ctx)
+	return ctx, &sessionControl{
+		cancel:   cancel,
+		pauseCh:  make(chan struct{}, 1),
+		resumeCh: make(chan struct{}, 1),
+		grown:    make(chan struct{}, 1),
+	}
 }
 
 // Pipeline manages sync sessions.
@@ -32,10 +52,26 @@ type Pipeline struct {
 	sessions            map[string]*Session
 	controls            map[string]*sessionControl
 	mu                  sync.RWMutex
+	store               Store
+	cache               *SearchCache
+	persistMu           sync.Mutex
+	subscribers         map[string]map[chan Event]struct{}
+	subMu               sync.Mutex
 	searchDelay         time.Duration
 	queueDelay          time.Duration
+	queuePollInterval   time.Duration
 	checkDelay          time.Duration
+	playlistDelay       time.Duration
+	scanPollInterval    time.Duration
+	createPlaylists     bool
+	workers             int
+	maxActive           int
+	active              int
+	holding             map[string]bool // sessions with a scheduler slot
+	waiting             []*waiter
+	waitSeq             int
 	confidenceThreshold int
+	quality             QualityFilter
 }
 
 // NewPipeline creates a new sync pipeline with the given clients.
@@ -47,10 +83,18 @@ func NewPipeline(yt ytdlp.Client, dx deemix.Client, nav navidrome.Client) *Pipel
 		navidromeClient:     nav,
 		sessions:            make(map[string]*Session),
 		controls:            make(map[string]*sessionControl),
+		holding:             make(map[string]bool),
+		subscribers:         make(map[string]map[chan Event]struct{}),
 		searchDelay:         200 * time.Millisecond,
 		queueDelay:          100 * time.Millisecond,
+		queuePollInterval:   defaultQueuePollInterval,
 		checkDelay:          100 * time.Millisecond,
+		playlistDelay:       defaultPlaylistDelay,
+		scanPollInterval:    scanPollInterval,
+		workers:             DefaultWorkers,
+		maxActive:           DefaultMaxActive,
 		confidenceThreshold: DefaultConfidenceThreshold,
+		quality:             DefaultQualityFilter,
 	}
 }
 
@@ -66,35 +110,150 @@ func (p *Pipeline) SetConfidenceThreshold(threshold int) {
 	p.confidenceThreshold = threshold
 }
 
+// SetStore enables session persistence. Call Restore afterwards to load
+// sessions saved by a previous run.
+func (p *Pipeline) SetStore(store Store) {
+	p.store = store
+}
+
+// Restore loads persisted sessions from the store. Sessions that were still
+// active when the server stopped are marked StatusInterrupted so they can be
+// resumed. Returns the number of sessions restored.
+func (p *Pipeline) Restore() (int, error) {
+	if p.store == nil {
+		return 0, nil
+	}
+	sessions, err := p.store.Load()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, s := range sessions {
+		if s.Status == StatusQueued {
+			s.Status = s.QueuedFor
+			s.QueuedFor = ""
+			s.QueuePosition = 0
+		}
+		switch s.Status {
+		case StatusFetching, StatusParsing, StatusSearching, StatusChecking, StatusDownloading:
+			s.Interrupted = s.Status
+			s.Status = StatusInterrupted
+			// A track caught mid-search has no result yet.
+			for i := range s.Tracks {
+				if s.Tracks[i].Status == TrackSearching {
+					s.Tracks[i].Status = TrackPending
+				}
+			}
+			if s.Interrupted != StatusDownloading {
+				retrySearches(s)
+			}
+		}
+
+		p.mu.Lock()
+		p.sessions[s.ID] = s
+		p.mu.Unlock()
+
+		if s.Status == StatusInterrupted {
+			log.Printf("[sync] session %s interrupted while %s, resumable", s.ID, s.Interrupted)
+			p.persist(s)
+		}
+	}
+
+	log.Printf("[sync] restored %d sessions", len(sessions))
+	return len(sessions), nil
+}
+
+// retrySearches puts the tracks whose search failed back to pending, so the
+// resumed analysis searches them again.
+func retrySearches(s *Session) {
+	for i := range s.Tracks {
+		track := &s.Tracks[i]
+		if track.Status != TrackError || track.DeezerMatch != nil {
+			continue
+		}
+		track.Status = TrackPending
+		track.Error = ""
+		s.Progress.SearchFailed--
+		s.Progress.Searched--
+	}
+}
+
 // Analyze begins a new analysis session for the given playlist URL and bitrate.
 // Returns the session ID immediately; processing runs in a goroutine.
 // Analysis fetches, parses, searches Deezer, and checks Navidrome, then stops at StatusReady.
 func (p *Pipeline) Analyze(ctx context.Context, playlistURL string, bitrate int, checkNavidrome bool) string {
-	id := generateID()
+	ctx, session := p.newSession(ctx, playlistURL, StatusFetching, bitrate, checkNavidrome)
+
+	log.Printf("[sync] session %s analyzing: %s", session.ID, playlistURL)
+	go p.run(ctx, session)
+	return session.ID
+}
+
+// AnalyzeEntries begins an analysis session for entries that were already
+// fetched from playlistURL, skipping the fetch phase.
+// Returns the session ID immediately; processing runs in a goroutine.
+func (p *Pipeline) AnalyzeEntries(ctx context.Context, playlistURL string, entries []ytdlp.PlaylistEntry, bitrate int, checkNavidrome bool) string {
+	ctx, session := p.newSession(ctx, playlistURL, StatusParsing, bitrate, checkNavidrome)
+
+	log.Printf("[sync] session %s analyzing %d entries: %s", session.ID, len(entries), playlistURL)
+	go func() {
+		if !p.start(ctx, session, StatusParsing) {
+			return
+		}
+		defer p.release(session)
+		p.parse(session, entries)
+		p.search(ctx, session)
+	}()
+	return session.ID
+}
+
+// newSession registers a new session with its control channels.
+func (p *Pipeline) newSession(ctx context.Context, playlistURL, status string, bitrate int, checkNavidrome bool) (context.Context, *Session) {
 	session := &Session{
-		ID:             id,
+		ID:             generateID(),
 		URL:            playlistURL,
-		Status:         StatusFetching,
+		Status:         status,
 		Bitrate:        bitrate,
 		CheckNavidrome: checkNavidrome,
+		Priority:       priorityFrom(ctx),
+		CreatedAt:      time.Now(),
+		RequestCookies: ytdlp.HasCookies(ctx),
 	}
 
 	// Create cancellable context and control channels.
-	ctx, cancel := context.WithCancel(ctx)
-	ctrl := &sessionControl{
-		cancel:   cancel,
-		pauseCh:  make(chan struct{}, 1),
-		resumeCh: make(chan struct{}, 1),
-	}
+	ctx, ctrl := newSessionControl(ctx)
 
 	p.mu.Lock()
-	p.sessions[id] = session
-	p.controls[id] = ctrl
+	p.sessions[session.ID] = session
+	p.controls[session.ID] = ctrl
 	p.mu.Unlock()
+	p.persist(session)
 
-	log.Printf("[sync] session %s analyzing: %s", id, playlistURL)
-	go p.run(ctx, session)
-	return id
+	return ctx, session
+}
+
+// WaitSettled blocks until the session leaves its active phases and returns
+// a copy of it. A paused session is still considered active.
+func (p *Pipeline) WaitSettled(ctx context.Context, sessionID string) (*Session, error) {
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+
+	for {
+		session, ok := p.GetSession(sessionID)
+		if !ok {
+			return nil, ErrSessionNotFound
+		}
+		switch session.Status {
+		case StatusReady, StatusDone, StatusError, StatusCanceled, StatusInterrupted:
+			return session, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
 }
 
 // GetSession returns a copy of the session state.
@@ -106,88 +265,382 @@ func (p *Pipeline) GetSession(id string) (*Session, bool) {
 		return nil, false
 	}
 	// Return a copy to avoid races.
+	return copySession(s), true
+}
+
+// ListSessions returns a copy of every session without its tracks, oldest first.
+func (p *Pipeline) ListSessions() []Session {
+	p.mu.RLock()
+	list := make([]Session, 0, len(p.sessions))
+	for _, s := range p.sessions {
+		cp := *s
+		cp.Tracks = nil
+		list = append(list, cp)
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+// DeleteSession removes a session that is no longer running, including its stored copy.
+func (p *Pipeline) DeleteSession(sessionID string) error {
+	p.mu.Lock()
+	session, ok := p.sessions[sessionID]
+	if !ok {
+		p.mu.Unlock()
+		return ErrSessionNotFound
+	}
+	switch session.Status {
+	case StatusReady, StatusDone, StatusError, StatusCanceled, StatusInterrupted:
+	default:
+		p.mu.Unlock()
+		return ErrSessionActive
+	}
+	delete(p.sessions, sessionID)
+	delete(p.controls, sessionID)
+	p.mu.Unlock()
+	p.closeSubscribers(sessionID)
+
+	if p.store != nil {
+		p.persistMu.Lock()
+		defer p.persistMu.Unlock()
+		if err := p.store.Delete(sessionID); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("[sync] session %s deleted", sessionID)
+	return nil
+}
+
+// copySession returns a copy of the session with its own tracks slice.
+// Must be called with p.mu held.
+func copySession(s *Session) *Session {
 	cp := *s
 	cp.Tracks = make([]Track, len(s.Tracks))
 	copy(cp.Tracks, s.Tracks)
-	return &cp, true
+	return &cp
+}
+
+// persist writes a snapshot of the session to the store, if one is configured.
+// Must be called without p.mu held.
+func (p *Pipeline) persist(session *Session) {
+	if p.store == nil {
+		return
+	}
+	p.persistMu.Lock()
+	defer p.persistMu.Unlock()
+
+	p.mu.RLock()
+	cp := copySession(session)
+	p.mu.RUnlock()
+
+	if err := p.store.Save(cp); err != nil {
+		log.Printf("[sync] session %s: failed to persist: %v", session.ID, err)
+	}
 }
 
 func (p *Pipeline) run(ctx context.Context, session *Session) {
+	// A resumed session already has its tracks, unless it was interrupted
+	// while they were streamed in; only fetch new ones.
+	p.mu.RLock()
+	fetched := len(session.Tracks) > 0 && !session.Fetching
+	status := session.Status
+	p.mu.RUnlock()
+
+	if !p.start(ctx, session, status) {
+		return
+	}
+	defer p.release(session)
+
+	if stream, ok := p.ytClient.(ytdlp.StreamClient); ok && !fetched {
+		if p.streamSearch(ctx, session, stream) {
+			p.finishSearch(ctx, session)
+		}
+		return
+	}
+
+	if !fetched && !p.fetch(ctx, session) {
+		return
+	}
+
+	p.search(ctx, session)
+}
+
+// start waits for a scheduler slot before a phase. Returns false if the
+// session was canceled while waiting; otherwise the caller must release.
+func (p *Pipeline) start(ctx context.Context, session *Session, status string) bool {
+	if err := p.acquire(ctx, session, status); err != nil {
+		p.setErrorUnlessCanceled(session, "canceled")
+		return false
+	}
+	return true
+}
+
+// fetch runs phases 1 and 2: fetch the playlist and parse titles into tracks.
+// Returns false if the session failed.
+func (p *Pipeline) fetch(ctx context.Context, session *Session) bool {
 	// Phase 1: Fetch playlist.
 	entries, err := p.ytClient.GetPlaylist(ctx, session.URL)
 	if err != nil {
 		p.setError(session, "failed to fetch playlist: "+err.Error())
-		return
+		return false
 	}
 
 	// Phase 2: Parse titles.
+	p.parse(session, entries)
+	return true
+}
+
+// parse turns playlist entries into pending tracks. A mix or compilation
+// video with chapters becomes one track per chapter.
+func (p *Pipeline) parse(session *Session, entries []ytdlp.PlaylistEntry) {
 	p.mu.Lock()
 	session.Status = StatusParsing
-	session.Tracks = make([]Track, len(entries))
-	session.Progress.Total = len(entries)
-	for i, entry := range entries {
-		var artist, song string
-
-		// Priority 1: yt-dlp artist/track fields (YouTube Music metadata).
-		if entry.Artist != "" {
-			artist = entry.Artist
-			if entry.Track != "" {
-				song = entry.Track
-			} else {
-				song = entry.Title
-			}
-		} else {
-			// Priority 2: Parse from title (handles "Artist - Song" format).
-			artist, song = parser.Parse(entry.Title)
-			// Note: We don't use channel as fallback because it's often
-			// unreliable (could be uploader, label, band member, etc.).
-			// Better to search with just title than wrong artist.
+	p.emitStatus(session)
+	session.Tracks = make([]Track, 0, len(entries))
+	for _, entry := range entries {
+		p.appendEntry(session, entry)
+	}
+	session.Status = StatusSearching
+	p.emitStatus(session)
+	p.emitProgress(session)
+	p.mu.Unlock()
+	p.persist(session)
+}
+
+// appendEntry adds the tracks of a playlist entry to the session and counts
+// it as fetched. Must be called with p.mu held.
+func (p *Pipeline) appendEntry(session *Session, entry ytdlp.PlaylistEntry) {
+	if session.Title == "" && entry.PlaylistTitle != "" {
+		session.Title = entry.PlaylistTitle
+		p.emitStatus(session)
+	}
+	for _, track := range tracksOf(entry) {
+		if track.Status == TrackUnavailable {
+			session.Progress.Unavailable++
 		}
+		session.Tracks = append(session.Tracks, track)
+	}
+	session.Progress.Fetched++
+	session.Progress.Total = len(session.Tracks)
+}
 
-		session.Tracks[i] = Track{
+// tracksOf turns a playlist entry into pending tracks: one per chapter for
+// a mix, otherwise one. An unavailable video becomes a single track
+// without a title to search for.
+func tracksOf(entry ytdlp.PlaylistEntry) []Track {
+	if entry.Unavailable != "" {
+		return []Track{{
+			VideoID:      entry.VideoID,
 			YouTubeTitle: entry.Title,
-			ParsedArtist: artist,
-			ParsedSong:   song,
+			Status:       TrackUnavailable,
+			Error:        entry.Unavailable,
+		}}
+	}
+	if isMix(entry) {
+		return segmentTracks(entry)
+	}
+	return []Track{entryTrack(entry)}
+}
+
+// entryTrack turns a playlist entry into a pending track.
+func entryTrack(entry ytdlp.PlaylistEntry) Track {
+	var parsed parser.Result
+
+	// Priority 1: the "Provided to YouTube by" description block of
+	// auto-generated uploads, which credits the exact track.
+	if entry.Metadata != nil {
+		parsed = parseMetadata(entry.Metadata)
+	} else if entry.Artist != "" {
+		// Priority 2: yt-dlp artist/track fields (YouTube Music metadata).
+		song := entry.Track
+		if song == "" {
+			song = entry.Title
+		}
+		parsed = parser.ParseTrack(entry.Artist, song)
+	} else {
+		// Priority 3: Parse from title (handles "Artist - Song" format).
+		parsed = parser.Parse(entry.Title)
+		// Note: We don't use channel as fallback because it's often
+		// unreliable (could be uploader, label, band member, etc.).
+		// Better to search with just title than wrong artist.
+	}
+
+	return Track{
+		VideoID:      entry.VideoID,
+		YouTubeTitle: entry.Title,
+		ParsedArtist: parsed.Artist,
+		Artists:      parsed.Artists,
+		Featured:     parsed.Featured,
+		ParsedSong:   parsed.Song,
+		Version:      parsed.Version,
+		ISRC:         isrcOf(entry),
+		Status:       TrackPending,
+		Duration:     int(math.Round(entry.Duration)),
+	}
+}
+
+// isMix reports whether an entry is a mix or compilation to split into
+// its chapters: a long video with at least two of them.
+func isMix(entry ytdlp.PlaylistEntry) bool {
+	return len(entry.Chapters) >= 2 && entry.Duration >= ytdlp.MixMinDuration
+}
+
+// segmentTracks turns each chapter of a mix into a pending track. Chapter
+// titles are parsed like video titles; one without an artist, as in a
+// full album upload, takes the video's YouTube Music artist if it has one.
+func segmentTracks(entry ytdlp.PlaylistEntry) []Track {
+	tracks := make([]Track, len(entry.Chapters))
+	for i, ch := range entry.Chapters {
+		parsed := parser.Parse(ch.Title)
+		if parsed.Artist == "" && entry.Artist != "" {
+			credit := parser.ParseTrack(entry.Artist, parsed.Song)
+			parsed.Artist, parsed.Artists = credit.Artist, credit.Artists
+			parsed.Featured = append(credit.Featured, parsed.Featured...)
+		}
+
+		segment := &Segment{
+			VideoTitle: entry.Title,
+			Index:      i,
+			Start:      int(math.Round(ch.StartTime)),
+			End:        int(math.Round(ch.EndTime)),
+		}
+		var duration int
+		if segment.End > segment.Start {
+			duration = segment.End - segment.Start
+		}
+		tracks[i] = Track{
+			VideoID:      entry.VideoID,
+			YouTubeTitle: ch.Title,
+			ParsedArtist: parsed.Artist,
+			Artists:      parsed.Artists,
+			Featured:     parsed.Featured,
+			ParsedSong:   parsed.Song,
+			Version:      parsed.Version,
 			Status:       TrackPending,
+			Duration:     duration,
+			Segment:      segment,
 		}
 	}
-	session.Status = StatusSearching
-	p.mu.Unlock()
+	return tracks
+}
 
-	// Phase 3: Search Deemix for each track.
-	for i := range session.Tracks {
-		if err := p.checkpoint(ctx, session, StatusSearching); err != nil {
-			if session.Status != StatusCanceled {
-				p.setError(session, "canceled")
-			}
-			return
+// parseMetadata builds a parse result from an auto-generated upload's
+// description. The block lists the main artist first; the others are
+// treated as featured, which is how Deezer credits them. An album such as
+// "Live at Wembley" gives the version when the title doesn't.
+func parseMetadata(m *ytdlp.TrackMetadata) parser.Result {
+	parsed := parser.ParseTrack(m.Artists[0], m.Title)
+	for _, artist := range m.Artists[1:] {
+		if !slices.ContainsFunc(parsed.Featured, func(f string) bool { return strings.EqualFold(f, artist) }) {
+			parsed.Featured = append(parsed.Featured, artist)
 		}
+	}
+	if parsed.Version.IsZero() {
+		parsed.Version = parser.VersionOf(m.Album)
+	}
+	return parsed
+}
 
+// isrcOf returns the ISRC an entry's description gives, if any.
+func isrcOf(entry ytdlp.PlaylistEntry) string {
+	if entry.Metadata == nil {
+		return ""
+	}
+	return entry.Metadata.ISRC
+}
+
+// search runs phases 3 and 3.5: search Deezer for each pending track, then
+// check Navidrome. Tracks already resolved before an interruption are kept.
+// Both phases run on up to p.workers tracks at a time.
+func (p *Pipeline) search(ctx context.Context, session *Session) {
+	if p.searchTracks(ctx, session) {
+		p.finishSearch(ctx, session)
+	}
+}
+
+// searchTracks runs phase 3, searching Deezer for each pending track, and
+// returns false if the session was canceled. While the playlist is still
+// streamed in, it also searches the tracks added meanwhile. Each result is
+// persisted as soon as it is known, so a search interrupted by a restart
+// resumes without asking Deemix again.
+func (p *Pipeline) searchTracks(ctx context.Context, session *Session) bool {
+	// Phase 3: Search Deemix for each track.
+	err := p.forEachTrack(ctx, session, StatusSearching, func(i int) {
 		p.mu.Lock()
+		if session.Tracks[i].Status != TrackPending {
+			p.mu.Unlock()
+			return
+		}
 		session.Tracks[i].Status = TrackSearching
+		p.emitTrack(session, i)
+		key := session.Tracks[i].cacheKey()
+		target := targetOf(&session.Tracks[i])
 		p.mu.Unlock()
 
-		query := buildQuery(session.Tracks[i].ParsedArtist, session.Tracks[i].ParsedSong)
-		results, err := p.deemixClient.Search(ctx, query)
+		// A video resolved in an earlier session reuses those results,
+		// including a manual search or pick, without asking Deemix.
+		var candidates []Candidate
+		var err error
+		resolved, cached := p.cache.video(key)
+		if cached {
+			candidates = p.rankResults(target, resolved.candidates())
+		} else {
+			var query string
+			candidates, query, cached, err = p.searchStrategies(ctx, target)
+			switch {
+			case err == nil:
+				p.cache.putVideo(key, query, candidates)
+			case len(candidates) > 0 && ctx.Err() == nil:
+				// Keep what the strategies that ran found, without caching
+				// it as the video's full result.
+				log.Printf("[sync] session %s: search failed for track %d, keeping %d candidates: %v", session.ID, i, len(candidates), err)
+				err = nil
+			}
+		}
 
 		p.mu.Lock()
-		if err != nil || len(results) == 0 {
+		if err != nil && ctx.Err() != nil {
+			// Canceled mid-search: leave the track for a resumed run.
+			session.Tracks[i].Status = TrackPending
+			p.emitTrack(session, i)
+			p.mu.Unlock()
+			return
+		}
+		if err != nil {
+			// The client already retried; keep the track apart from real
+			// misses so it can be searched again.
+			session.Tracks[i].Status = TrackError
+			session.Tracks[i].Error = err.Error()
+			session.Progress.SearchFailed++
+			log.Printf("[sync] session %s: search failed for track %d: %v", session.ID, i, err)
+		} else if len(candidates) == 0 {
 			session.Tracks[i].Status = TrackNotFound
 			session.Progress.NotFound++
 		} else {
-			match := results[0]
-			session.Tracks[i].DeezerMatch = &match
-
-			// Calculate confidence score.
-			confidence := calculateConfidence(
-				session.Tracks[i].ParsedArtist,
-				session.Tracks[i].ParsedSong,
-				match.Artist,
-				match.Title,
-			)
+			// Keep the best candidate as the match, or the user's earlier pick.
+			best, picked := candidates[0], false
+			if resolved.PickedID != 0 {
+				for _, c := range candidates {
+					if c.ID == resolved.PickedID {
+						best, picked = c, true
+						break
+					}
+				}
+			}
+			session.Tracks[i].DeezerMatch = &best.SearchResult
+			session.Tracks[i].DurationDelta = best.DurationDelta
+			session.Tracks[i].Candidates = candidates
+			session.Tracks[i].MatchStrategy = best.Strategy
+
+			confidence := best.Confidence
 			session.Tracks[i].Confidence = confidence
 
-			if confidence >= p.confidenceThreshold {
+			if picked || confidence >= p.confidenceThreshold {
 				session.Tracks[i].Status = TrackFound
 				session.Tracks[i].Selected = true
 				session.Progress.Selected++
@@ -197,36 +650,43 @@ func (p *Pipeline) run(ctx context.Context, session *Session) {
 			}
 		}
 		session.Progress.Searched++
+		p.emitTrack(session, i)
+		last := i == len(session.Tracks)-1 && !session.Fetching
 		p.mu.Unlock()
+		p.persist(session)
 
-		if i < len(session.Tracks)-1 {
-			time.Sleep(p.searchDelay)
+		if !cached && !last {
+			sleep(ctx, p.searchDelay)
 		}
+	})
+	if err != nil {
+		p.setErrorUnlessCanceled(session, "canceled")
+		return false
 	}
+	return true
+}
 
+// finishSearch runs phase 3.5, checking Navidrome for the matched tracks,
+// and marks the session ready. Tracks found in Navidrome are persisted as
+// they are skipped.
+func (p *Pipeline) finishSearch(ctx context.Context, session *Session) {
 	// Phase 3.5: Check Navidrome for existing tracks.
 	if p.navidromeClient != nil && session.CheckNavidrome {
 		p.mu.Lock()
 		session.Status = StatusChecking
+		p.emitStatus(session)
 		p.mu.Unlock()
 
-		for i := range session.Tracks {
-			if err := p.checkpoint(ctx, session, StatusChecking); err != nil {
-				if session.Status != StatusCanceled {
-					p.setError(session, "canceled")
-				}
-				return
-			}
-
+		err := p.forEachTrack(ctx, session, StatusChecking, func(i int) {
 			p.mu.RLock()
 			track := session.Tracks[i]
 			p.mu.RUnlock()
 
-			if track.DeezerMatch == nil {
-				continue
+			if track.DeezerMatch == nil || track.Status == TrackSkipped {
+				return
 			}
 
-			results, err := p.navidromeClient.Search(ctx, track.ParsedArtist, track.ParsedSong)
+			results, err := p.navidromeClient.Search(ctx, artistCredit(track), track.ParsedSong)
 			if err == nil && len(results) > 0 {
 				p.mu.Lock()
 				// Deselect if it was selected before marking as skipped.
@@ -236,29 +696,70 @@ func (p *Pipeline) run(ctx context.Context, session *Session) {
 				}
 				session.Tracks[i].Status = TrackSkipped
 				session.Progress.Skipped++
+				p.emitTrack(session, i)
 				p.mu.Unlock()
+				p.persist(session)
 			}
 
 			if i < len(session.Tracks)-1 {
-				time.Sleep(p.checkDelay)
+				sleep(ctx, p.checkDelay)
 			}
+		})
+		if err != nil {
+			p.setErrorUnlessCanceled(session, "canceled")
+			return
 		}
 	}
 
 	// Analysis complete - wait for user to trigger download.
 	p.mu.Lock()
 	session.Status = StatusReady
+	p.emitStatus(session)
 	log.Printf("[sync] session %s ready: %d selected, %d skipped, %d needs review, %d not found",
 		session.ID, session.Progress.Selected, session.Progress.Skipped, session.Progress.NeedsReview, session.Progress.NotFound)
 	p.mu.Unlock()
+	p.persist(session)
 }
 
 func (p *Pipeline) setError(session *Session, msg string) {
 	p.mu.Lock()
 	session.Status = StatusError
 	session.Error = msg
+	p.emitStatus(session)
+	log.Printf("[sync] session %s error: %s", session.ID, msg)
+	p.mu.Unlock()
+	p.persist(session)
+}
+
+// setErrorUnlessCanceled is setError for a phase that stopped on its
+// context, which CancelSession has usually marked canceled already. The
+// check and the update share the lock, so that is never overwritten.
+func (p *Pipeline) setErrorUnlessCanceled(session *Session, msg string) {
+	p.mu.Lock()
+	if session.Status == StatusCanceled {
+		p.mu.Unlock()
+		return
+	}
+	session.Status = StatusError
+	session.Error = msg
+	p.emitStatus(session)
 	log.Printf("[sync] session %s error: %s", session.ID, msg)
 	p.mu.Unlock()
+	p.persist(session)
+}
+
+// sleep waits for d or until ctx is done. Callers reach a checkpoint next,
+// which reports the cancellation.
+func sleep(ctx context.Context, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
 }
 
 // checkpoint checks for cancellation or pause signals.
@@ -281,32 +782,59 @@ func (p *Pipeline) checkpoint(ctx context.Context, session *Session, previousSta
 	case <-ctx.Done():
 		return ctx.Err()
 	case <-ctrl.pauseCh:
-		p.mu.Lock()
-		session.Status = StatusPaused
-		log.Printf("[sync] session %s paused", session.ID)
-		p.mu.Unlock()
-
-		// Wait for resume or cancel.
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-ctrl.resumeCh:
-			p.mu.Lock()
-			session.Status = previousStatus
-			log.Printf("[sync] session %s resumed", session.ID)
-			p.mu.Unlock()
-		}
+		return p.waitResume(ctx, session, ctrl, previousStatus)
 	default:
 		// Not paused, continue.
 	}
 	return nil
 }
 
+// waitResume marks the session paused and blocks until it is resumed, then
+// restores previousStatus. The session gives up its scheduler slot while
+// paused and waits for one again on resume. Returns an error if ctx is
+// canceled meanwhile.
+func (p *Pipeline) waitResume(ctx context.Context, session *Session, ctrl *sessionControl, previousStatus string) error {
+	p.mu.Lock()
+	session.Status = StatusPaused
+	p.emitStatus(session)
+	log.Printf("[sync] session %s paused", session.ID)
+	p.mu.Unlock()
+	p.release(session)
+
+	// Wait for resume or cancel.
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-ctrl.resumeCh:
+		p.mu.RLock()
+		status := previousStatus
+		if session.Fetching {
+			// Searching while the playlist streams in reports as fetching.
+			status = StatusFetching
+		}
+		p.mu.RUnlock()
+
+		if err := p.acquire(ctx, session, status); err != nil {
+			return err
+		}
+		p.mu.Lock()
+		session.Status = status
+		p.emitStatus(session)
+		log.Printf("[sync] session %s resumed", session.ID)
+		p.mu.Unlock()
+	}
+	return nil
+}
+
 // PauseSession pauses the session, allowing it to be resumed later.
 func (p *Pipeline) PauseSession(sessionID string) error {
 	p.mu.RLock()
 	session, ok := p.sessions[sessionID]
 	ctrl, ctrlOk := p.controls[sessionID]
+	var status string
+	if ok {
+		status = session.Status
+	}
 	p.mu.RUnlock()
 
 	if !ok || !ctrlOk {
@@ -314,7 +842,7 @@ func (p *Pipeline) PauseSession(sessionID string) error {
 	}
 
 	// Only allow pausing active operations.
-	switch session.Status {
+	switch status {
 	case StatusFetching, StatusParsing, StatusSearching, StatusChecking, StatusDownloading:
 		// Valid states for pausing.
 	case StatusPaused:
@@ -334,18 +862,26 @@ func (p *Pipeline) PauseSession(sessionID string) error {
 	return nil
 }
 
-// ResumeSession resumes a paused session.
+// ResumeSession resumes a paused session, or one interrupted by a restart.
 func (p *Pipeline) ResumeSession(sessionID string) error {
 	p.mu.RLock()
 	session, ok := p.sessions[sessionID]
 	ctrl, ctrlOk := p.controls[sessionID]
+	var status string
+	if ok {
+		status = session.Status
+	}
 	p.mu.RUnlock()
 
+	if ok && status == StatusInterrupted {
+		return p.resumeInterrupted(session)
+	}
+
 	if !ok || !ctrlOk {
 		return ErrSessionNotFound
 	}
 
-	if session.Status != StatusPaused {
+	if status != StatusPaused {
 		return ErrSessionNotPaused
 	}
 
@@ -360,6 +896,54 @@ func (p *Pipeline) ResumeSession(sessionID string) error {
 	return nil
 }
 
+// resumeInterrupted restarts a session restored in StatusInterrupted.
+// Analysis continues with the tracks that were not searched yet; a download
+// continues with the selected tracks that were not queued yet. Returns
+// ErrSessionNotPaused if the session is no longer interrupted, so concurrent
+// resumes start it only once, and ErrCookiesGone if the playlist still has
+// to be fetched with cookies that were sent with the request.
+func (p *Pipeline) resumeInterrupted(session *Session) error {
+	p.mu.Lock()
+	if session.Status != StatusInterrupted {
+		p.mu.Unlock()
+		return ErrSessionNotPaused
+	}
+	phase := session.Interrupted
+	fetched := len(session.Tracks) > 0 && !session.Fetching
+	if phase != StatusDownloading && !fetched && session.RequestCookies {
+		p.mu.Unlock()
+		return ErrCookiesGone
+	}
+	session.Interrupted = ""
+
+	ctx, ctrl := newSessionControl(context.Background())
+	p.controls[session.ID] = ctrl
+
+	if phase == StatusDownloading {
+		session.Status = StatusDownloading
+		p.emitStatus(session)
+		p.mu.Unlock()
+		p.persist(session)
+
+		log.Printf("[sync] session %s resuming download", session.ID)
+		go p.download(ctx, session)
+		return nil
+	}
+
+	if fetched {
+		session.Status = StatusSearching
+	} else {
+		session.Status = StatusFetching
+	}
+	p.emitStatus(session)
+	p.mu.Unlock()
+	p.persist(session)
+
+	log.Printf("[sync] session %s resuming analysis", session.ID)
+	go p.run(ctx, session)
+	return nil
+}
+
 // CancelSession cancels a session, stopping it permanently.
 func (p *Pipeline) CancelSession(sessionID string) error {
 	p.mu.Lock()
@@ -389,7 +973,9 @@ func (p *Pipeline) CancelSession(sessionID string) error {
 
 	p.mu.Lock()
 	session.Status = StatusCanceled
+	p.emitStatus(session)
 	p.mu.Unlock()
+	p.persist(session)
 
 	log.Printf("[sync] session %s canceled", sessionID)
 	return nil
@@ -423,24 +1009,34 @@ func (p *Pipeline) Download(ctx context.Context, sessionID string) error {
 		return ErrSessionNotReady
 	}
 	session.Status = StatusDownloading
+	p.emitStatus(session)
 
 	// Create cancellable context and control channels for download.
-	ctx, cancel := context.WithCancel(ctx)
-	ctrl := &sessionControl{
-		cancel:   cancel,
-		pauseCh:  make(chan struct{}, 1),
-		resumeCh: make(chan struct{}, 1),
-	}
+	ctx, ctrl := newSessionControl(ctx)
 	p.controls[sessionID] = ctrl
 	p.mu.Unlock()
+	p.persist(session)
 
 	log.Printf("[sync] session %s: starting download of %d selected tracks", sessionID, session.Progress.Selected)
+	return p.download(ctx, session)
+}
+
+// download queues every selected track that has not been queued yet.
+// Each track's result is persisted as soon as it is known, so a download
+// interrupted by a restart resumes without re-queuing finished tracks.
+// When the Deemix client can report its queue, the session stays
+// downloading until Deemix has finished or failed every queued track.
+func (p *Pipeline) download(ctx context.Context, session *Session) error {
+	if !p.start(ctx, session, StatusDownloading) {
+		return ctx.Err()
+	}
+	defer p.release(session)
+
+	tracker, tracking := p.deemixClient.(deemix.QueueClient)
 
 	for i := range session.Tracks {
 		if err := p.checkpoint(ctx, session, StatusDownloading); err != nil {
-			if session.Status != StatusCanceled {
-				p.setError(session, "canceled")
-			}
+			p.setErrorUnlessCanceled(session, "canceled")
 			return err
 		}
 
@@ -448,51 +1044,89 @@ func (p *Pipeline) Download(ctx context.Context, sessionID string) error {
 		track := session.Tracks[i]
 		p.mu.RUnlock()
 
-		if !track.Selected || track.DeezerMatch == nil {
+		if !track.Selected || track.DeezerMatch == nil || sentToDeemix(track.Status) {
 			continue
 		}
 
 		err := p.deemixClient.AddToQueue(ctx, track.DeezerMatch.Link, session.Bitrate)
 
 		p.mu.Lock()
-		if err != nil {
+		if track.Status == TrackError {
+			// Failed in an earlier run; this attempt replaces that result.
+			session.Progress.Failed--
+			session.Tracks[i].Error = ""
+		}
+		switch {
+		case err != nil:
 			session.Tracks[i].Status = TrackError
-		} else {
+			session.Tracks[i].Error = err.Error()
+			session.Progress.Failed++
+		case tracking:
+			session.Tracks[i].Status = TrackQueued
+			session.Progress.Queued++
+		default:
 			session.Tracks[i].Status = TrackDownloaded
 			session.Progress.Queued++
 		}
+		p.emitTrack(session, i)
 		p.mu.Unlock()
+		p.persist(session)
+
+		sleep(ctx, p.queueDelay)
+	}
 
-		time.Sleep(p.queueDelay)
+	if tracking {
+		if err := p.awaitDeemix(ctx, session, tracker); err != nil {
+			msg := "canceled"
+			if ctx.Err() == nil {
+				// The queue could not be read: the downloads' outcome is unknown.
+				msg = err.Error()
+			}
+			p.setErrorUnlessCanceled(session, msg)
+			return err
+		}
 	}
 
 	p.mu.Lock()
 	session.Status = StatusDone
-	log.Printf("[sync] session %s done: %d queued", sessionID, session.Progress.Queued)
+	p.emitStatus(session)
+	log.Printf("[sync] session %s done: %d queued, %d downloaded, %d failed", session.ID, session.Progress.Queued, session.Progress.Downloaded, session.Progress.Failed)
 	p.mu.Unlock()
+	p.persist(session)
+
+	if p.createPlaylists {
+		go p.syncPlaylistLater(session.ID)
+	}
 
 	return nil
 }
 
 // SetTrackSelected toggles the selection state of a track.
-// Only works when session is in StatusReady state.
+// Only works when session is in StatusReady state, and not for unavailable
+// tracks.
 func (p *Pipeline) SetTrackSelected(sessionID string, trackIndex int, selected bool) error {
 	p.mu.Lock()
-	defer p.mu.Unlock()
-
 	session, ok := p.sessions[sessionID]
 	if !ok {
+		p.mu.Unlock()
 		return ErrSessionNotFound
 	}
 	if session.Status != StatusReady {
+		p.mu.Unlock()
 		return ErrSessionNotReady
 	}
 	if trackIndex < 0 || trackIndex >= len(session.Tracks) {
+		p.mu.Unlock()
 		return ErrTrackNotFound
 	}
 
 	track := &session.Tracks[trackIndex]
+	if track.Status == TrackUnavailable {
+		p.mu.Unlock()
+		return ErrTrackUnavailable
+	}
 	if track.Selected == selected {
+		p.mu.Unlock()
 		return nil // No change needed
 	}
 
@@ -502,13 +1136,18 @@ func (p *Pipeline) SetTrackSelected(sessionID string, trackIndex int, selected b
 	} else {
 		session.Progress.Selected--
 	}
+	p.emitTrack(session, trackIndex)
+
+	p.mu.Unlock()
+	p.persist(session)
 
 	log.Printf("[sync] session %s: track %d selected=%v", sessionID, trackIndex, selected)
 	return nil
 }
 
 // SearchTrack performs a manual Deezer search for a track and updates its match.
-// Only works when session is in StatusReady state.
+// Only works when session is in StatusReady state, and not for unavailable
+// tracks.
 func (p *Pipeline) SearchTrack(ctx context.Context, sessionID string, trackIndex int, query string) error {
 	p.mu.Lock()
 	session, ok := p.sessions[sessionID]
@@ -524,21 +1163,30 @@ func (p *Pipeline) SearchTrack(ctx context.Context, sessionID string, trackIndex
 		p.mu.Unlock()
 		return ErrTrackNotFound
 	}
+	if session.Tracks[trackIndex].Status == TrackUnavailable {
+		p.mu.Unlock()
+		return ErrTrackUnavailable
+	}
 	checkNavidrome := session.CheckNavidrome
-	parsedArtist := session.Tracks[trackIndex].ParsedArtist
+	target := targetOf(&session.Tracks[trackIndex])
+	key := session.Tracks[trackIndex].cacheKey()
 	p.mu.Unlock()
 
 	// Combine parsed artist with user query for better Deezer results.
-	searchQuery := buildQuery(parsedArtist, query)
+	searchQuery := buildQuery(target.artist, query)
 	results, err := p.deemixClient.Search(ctx, searchQuery)
 	if err != nil {
 		return err
 	}
-
+	candidates := p.rankCandidates(target, results)
+	for i := range candidates {
+		candidates[i].Strategy = StrategyManual
+	}
+	p.cache.putVideo(key, searchQuery, candidates)
 	// Check Navidrome for the new match (outside lock).
 	var existsInNavidrome bool
-	if len(results) > 0 && p.navidromeClient != nil && checkNavidrome {
-		match := results[0]
+	if len(candidates) > 0 && p.navidromeClient != nil && checkNavidrome {
+		match := candidates[0]
 		navResults, err := p.navidromeClient.Search(ctx, match.Artist, match.Title)
 		if err == nil && len(navResults) > 0 {
 			existsInNavidrome = true
@@ -546,45 +1194,202 @@ func (p *Pipeline) SearchTrack(ctx context.Context, sessionID string, trackIndex
 	}
 
 	p.mu.Lock()
-	defer p.mu.Unlock()
-
 	track := &session.Tracks[trackIndex]
-	prevStatus := track.Status
 
-	if len(results) == 0 {
+	if len(candidates) == 0 {
 		track.DeezerMatch = nil
+		track.DurationDelta = nil
+		track.Candidates = nil
 		track.Confidence = 0
-		if prevStatus != TrackNotFound {
-			track.Status = TrackNotFound
-			p.updateProgressForStatusChange(session, prevStatus, TrackNotFound, track.Selected)
-			track.Selected = false
-		}
+		track.MatchStrategy = ""
+		p.setTrackStatus(session, track, TrackNotFound, false)
+		p.emitTrack(session, trackIndex)
+		p.mu.Unlock()
+		p.persist(session)
 		return nil
 	}
 
-	match := results[0]
-	track.DeezerMatch = &match
-	track.Confidence = calculateConfidence(track.ParsedArtist, track.ParsedSong, match.Artist, match.Title)
+	match := candidates[0]
+	track.DeezerMatch = &match.SearchResult
+	track.DurationDelta = match.DurationDelta
+	track.Candidates = candidates
+	track.Confidence = match.Confidence
+	track.MatchStrategy = match.Strategy
 
 	var newStatus string
 	if existsInNavidrome {
 		newStatus = TrackSkipped
-		track.Selected = false
 	} else if track.Confidence >= p.confidenceThreshold {
 		newStatus = TrackFound
-		track.Selected = true
 	} else {
 		newStatus = TrackNeedsReview
-		track.Selected = false
 	}
 
-	track.Status = newStatus
-	p.updateProgressForStatusChange(session, prevStatus, newStatus, false)
+	p.setTrackStatus(session, track, newStatus, newStatus == TrackFound)
+	p.emitTrack(session, trackIndex)
+	p.mu.Unlock()
+	p.persist(session)
 
 	log.Printf("[sync] session %s: track %d manual search found: %s - %s (status: %s)", sessionID, trackIndex, match.Artist, match.Title, newStatus)
 	return nil
 }
 
+// TrackCandidates searches Deezer for a track and returns every result ranked
+// by confidence, best first. An empty query uses the parsed artist and song.
+// The results are added to the track's stored candidates, so SetTrackMatch
+// can pick them; its match and an earlier pick are unchanged.
+// Only works when session is in StatusReady state, and not for unavailable
+// tracks.
+func (p *Pipeline) TrackCandidates(ctx context.Context, sessionID string, trackIndex int, query string) ([]Candidate, error) {
+	p.mu.RLock()
+	session, ok := p.sessions[sessionID]
+	if !ok {
+		p.mu.RUnlock()
+		return nil, ErrSessionNotFound
+	}
+	if session.Status != StatusReady {
+		p.mu.RUnlock()
+		return nil, ErrSessionNotReady
+	}
+	if trackIndex < 0 || trackIndex >= len(session.Tracks) {
+		p.mu.RUnlock()
+		return nil, ErrTrackNotFound
+	}
+	if session.Tracks[trackIndex].Status == TrackUnavailable {
+		p.mu.RUnlock()
+		return nil, ErrTrackUnavailable
+	}
+	target := targetOf(&session.Tracks[trackIndex])
+	p.mu.RUnlock()
+
+	strategy := StrategyManual
+	if query == "" {
+		query = target.song
+		strategy = StrategyCombined
+	}
+	searchQuery := buildQuery(target.artist, query)
+	results, err := p.deemixClient.Search(ctx, searchQuery)
+	if err != nil {
+		return nil, err
+	}
+	candidates := p.rankCandidates(target, results)
+	for i := range candidates {
+		candidates[i].Strategy = strategy
+	}
+
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	p.mu.Lock()
+	if session.Status != StatusReady {
+		p.mu.Unlock()
+		return nil, ErrSessionNotReady
+	}
+	track := &session.Tracks[trackIndex]
+	merged := slices.Clone(track.Candidates)
+	for _, c := range candidates {
+		if !slices.ContainsFunc(merged, func(m Candidate) bool { return m.ID == c.ID }) {
+			merged = append(merged, c)
+		}
+	}
+	slices.SortStableFunc(merged, func(a, b Candidate) int { return b.Confidence - a.Confidence })
+	track.Candidates = merged
+	p.emitTrack(session, trackIndex)
+	key := track.cacheKey()
+	p.mu.Unlock()
+	p.persist(session)
+	p.cache.addVideo(key, searchQuery, candidates)
+
+	return candidates, nil
+}
+
+// SetTrackMatch binds one of the track's candidates as its Deezer match.
+// A user's pick is trusted regardless of confidence, so the track becomes
+// found and selected, unless it already exists in Navidrome.
+// Only works when session is in StatusReady state.
+func (p *Pipeline) SetTrackMatch(ctx context.Context, sessionID string, trackIndex int, deezerID int64) error {
+	p.mu.RLock()
+	session, ok := p.sessions[sessionID]
+	if !ok {
+		p.mu.RUnlock()
+		return ErrSessionNotFound
+	}
+	if session.Status != StatusReady {
+		p.mu.RUnlock()
+		return ErrSessionNotReady
+	}
+	if trackIndex < 0 || trackIndex >= len(session.Tracks) {
+		p.mu.RUnlock()
+		return ErrTrackNotFound
+	}
+	var match *Candidate
+	for _, c := range session.Tracks[trackIndex].Candidates {
+		if c.ID == deezerID {
+			match = &c
+			break
+		}
+	}
+	checkNavidrome := session.CheckNavidrome
+	p.mu.RUnlock()
+
+	if match == nil {
+		return ErrCandidateNotFound
+	}
+
+	// Check Navidrome for the chosen match (outside lock).
+	var existsInNavidrome bool
+	if p.navidromeClient != nil && checkNavidrome {
+		navResults, err := p.navidromeClient.Search(ctx, match.Artist, match.Title)
+		if err == nil && len(navResults) > 0 {
+			existsInNavidrome = true
+		}
+	}
+
+	p.mu.Lock()
+	if session.Status != StatusReady {
+		p.mu.Unlock()
+		return ErrSessionNotReady
+	}
+	track := &session.Tracks[trackIndex]
+	track.DeezerMatch = &match.SearchResult
+	track.DurationDelta = match.DurationDelta
+	track.Confidence = match.Confidence
+	track.MatchStrategy = match.Strategy
+	newStatus := TrackFound
+	if existsInNavidrome {
+		newStatus = TrackSkipped
+	}
+	p.setTrackStatus(session, track, newStatus, newStatus == TrackFound)
+	p.emitTrack(session, trackIndex)
+	key := track.cacheKey()
+	p.mu.Unlock()
+	p.cache.pickVideo(key, deezerID)
+	p.persist(session)
+
+	log.Printf("[sync] session %s: track %d matched to %s - %s (status: %s)", sessionID, trackIndex, match.Artist, match.Title, newStatus)
+	return nil
+}
+
+// setTrackStatus moves a track to a new status and selection state, keeping
+// the session's progress counters consistent.
+// Must be called with p.mu held.
+func (p *Pipeline) setTrackStatus(session *Session, track *Track, status string, selected bool) {
+	p.updateProgressForStatusChange(session, track.Status, status, false)
+	if track.Selected != selected {
+		if selected {
+			session.Progress.Selected++
+		} else {
+			session.Progress.Selected--
+		}
+	}
+	track.Status = status
+	track.Selected = selected
+	if status != TrackError {
+		track.Error = ""
+	}
+}
+
 // updateProgressForStatusChange adjusts session progress counters when a track status changes.
 // Must be called with p.mu held.
 func (p *Pipeline) updateProgressForStatusChange(session *Session, oldStatus, newStatus string, wasSelected bool) {
@@ -592,8 +1397,11 @@ func (p *Pipeline) updateProgressForStatusChange(session *Session, oldStatus, ne
 		return
 	}
 
-	// Decrement old status counter.
+	// Decrement old status counter. Before the download, a track is only
+	// in error because its search failed.
 	switch oldStatus {
+	case TrackError:
+		session.Progress.SearchFailed--
 	case TrackNotFound:
 		session.Progress.NotFound--
 	case TrackNeedsReview:
@@ -3,6 +3,8 @@ package sync
 import (
 	"context"
 	"fmt"
+	gosync "sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -24,6 +26,7 @@ func (m *mockYTClient) GetPlaylist(_ context.Context, _ string) ([]ytdlp.Playlis
 // mockDeemixClient implements deemix.Client for testing.
 type mockDeemixClient struct {
 	searchResults map[string][]deemix.SearchResult
+	searchErr     error
 	queuedURLs    []string
 	queueErr      error
 }
@@ -31,6 +34,9 @@ type mockDeemixClient struct {
 func (m *mockDeemixClient) Login(_ context.Context) error { return nil }
 
 func (m *mockDeemixClient) Search(_ context.Context, query string) ([]deemix.SearchResult, error) {
+	if m.searchErr != nil {
+		return nil, m.searchErr
+	}
 	if results, ok := m.searchResults[query]; ok {
 		return results, nil
 	}
@@ -209,6 +215,33 @@ func TestPipelineContextCanceled(t *testing.T) {
 	time.Sleep(100 * time.Millisecond)
 }
 
+func TestPipelineSearchError(t *testing.T) {
+	yt := &mockYTClient{
+		entries: []ytdlp.PlaylistEntry{{Title: "Radiohead - Creep", VideoID: "abc"}},
+	}
+	dx := &mockDeemixClient{searchErr: fmt.Errorf("search failed (status 503)")}
+
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.searchDelay = 0
+
+	id := pipeline.Analyze(context.Background(), "https://youtube.com/playlist?list=test", deemix.Bitrate320, false)
+	session, err := pipeline.WaitSettled(context.Background(), id)
+	if err != nil {
+		t.Fatalf("WaitSettled: %v", err)
+	}
+
+	track := session.Tracks[0]
+	if track.Status != TrackError {
+		t.Errorf("status = %q, want %q", track.Status, TrackError)
+	}
+	if track.Error != "search failed (status 503)" {
+		t.Errorf("error = %q", track.Error)
+	}
+	if session.Progress.NotFound != 0 || session.Progress.SearchFailed != 1 {
+		t.Errorf("progress = %+v, want 1 search failed and none not found", session.Progress)
+	}
+}
+
 func TestSetTrackSelected(t *testing.T) {
 	yt := &mockYTClient{
 		entries: []ytdlp.PlaylistEntry{
@@ -692,3 +725,478 @@ func TestPauseErrors(t *testing.T) {
 		t.Errorf("expected ErrSessionNotPaused for ready session, got %v", err)
 	}
 }
+
+// memoryStore implements Store in memory for testing.
+type memoryStore struct {
+	mu       gosync.Mutex
+	sessions map[string]Session
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{sessions: make(map[string]Session)}
+}
+
+func (m *memoryStore) Save(session *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[session.ID] = *session
+	return nil
+}
+
+func (m *memoryStore) Load() ([]*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []*Session
+	for _, s := range m.sessions {
+		cp := s
+		list = append(list, &cp)
+	}
+	return list, nil
+}
+
+func (m *memoryStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	return nil
+}
+
+func TestPipelinePersistsSessions(t *testing.T) {
+	yt := &mockYTClient{entries: []ytdlp.PlaylistEntry{{Title: "Artist - Song", VideoID: "abc"}}}
+	dx := &mockDeemixClient{
+		searchResults: map[string][]deemix.SearchResult{
+			"Artist Song": {{ID: 1, Title: "Song", Artist: "Artist", Link: "https://www.deezer.com/track/1"}},
+		},
+	}
+	store := newMemoryStore()
+
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.SetStore(store)
+	pipeline.searchDelay = 0
+	id := pipeline.Analyze(context.Background(), "url", deemix.Bitrate320, false)
+
+	for i := 0; i < 50; i++ {
+		time.Sleep(10 * time.Millisecond)
+		s, _ := pipeline.GetSession(id)
+		if s.Status == StatusReady {
+			break
+		}
+	}
+	pipeline.SetTrackSelected(id, 0, false)
+
+	// A fresh pipeline sees the saved state.
+	restored := NewPipeline(yt, dx, nil)
+	restored.SetStore(store)
+	n, err := restored.Restore()
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("restored %d sessions, want 1", n)
+	}
+
+	session, ok := restored.GetSession(id)
+	if !ok {
+		t.Fatal("restored session not found")
+	}
+	if session.Status != StatusReady {
+		t.Errorf("status = %q, want 'ready'", session.Status)
+	}
+	if session.Tracks[0].Selected || session.Progress.Selected != 0 {
+		t.Error("selection change was not persisted")
+	}
+
+	if err := restored.DeleteSession(id); err != nil {
+		t.Fatalf("DeleteSession() error = %v", err)
+	}
+	if len(store.sessions) != 0 {
+		t.Error("session still in store after delete")
+	}
+}
+
+// storeCheckingDeemixClient records, on each search, how many tracks the
+// store already holds a result for.
+type storeCheckingDeemixClient struct {
+	mockDeemixClient
+	store    *memoryStore
+	searched []int
+}
+
+func (m *storeCheckingDeemixClient) Search(ctx context.Context, query string) ([]deemix.SearchResult, error) {
+	m.store.mu.Lock()
+	n := 0
+	for _, s := range m.store.sessions {
+		for _, track := range s.Tracks {
+			if track.Status != TrackPending && track.Status != TrackSearching {
+				n++
+			}
+		}
+	}
+	m.store.mu.Unlock()
+	m.searched = append(m.searched, n)
+	return m.mockDeemixClient.Search(ctx, query)
+}
+
+func TestPipelinePersistsSearchResults(t *testing.T) {
+	yt := &mockYTClient{entries: []ytdlp.PlaylistEntry{
+		{Title: "Artist - One", VideoID: "abc"},
+		{Title: "Artist - Two", VideoID: "def"},
+	}}
+	store := newMemoryStore()
+	dx := &storeCheckingDeemixClient{store: store}
+
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.SetStore(store)
+	pipeline.SetWorkers(1)
+	pipeline.searchDelay = 0
+	id := pipeline.Analyze(context.Background(), "url", deemix.Bitrate320, false)
+	if _, err := pipeline.WaitSettled(context.Background(), id); err != nil {
+		t.Fatalf("WaitSettled: %v", err)
+	}
+
+	// The first track's result was saved before the second was searched.
+	if len(dx.searched) < 2 || dx.searched[len(dx.searched)-1] != 1 {
+		t.Errorf("tracks saved at each search = %v, want the first one saved before the last search", dx.searched)
+	}
+}
+
+func TestRestoreInterruptedAnalysis(t *testing.T) {
+	dx := &mockDeemixClient{
+		searchResults: map[string][]deemix.SearchResult{
+			"Artist Song 1": {{ID: 1, Title: "Song 1", Artist: "Artist", Link: "https://www.deezer.com/track/1"}},
+			"Artist Song 2": {{ID: 2, Title: "Song 2", Artist: "Artist", Link: "https://www.deezer.com/track/2"}},
+			"Artist Song 3": {{ID: 3, Title: "Song 3", Artist: "Artist", Link: "https://www.deezer.com/track/3"}},
+		},
+	}
+	store := newMemoryStore()
+	store.Save(&Session{
+		ID:     "interrupted",
+		Status: StatusSearching,
+		Tracks: []Track{
+			{ParsedArtist: "Artist", ParsedSong: "Song 1", Status: TrackFound, Confidence: 100, Selected: true,
+				DeezerMatch: &deemix.SearchResult{ID: 1, Link: "https://www.deezer.com/track/1"}},
+			{ParsedArtist: "Artist", ParsedSong: "Song 2", Status: TrackSearching},
+			{ParsedArtist: "Artist", ParsedSong: "Song 3", Status: TrackError, Error: "search failed (status 503)"},
+		},
+		Progress: Progress{Total: 3, Searched: 2, Selected: 1, SearchFailed: 1},
+	})
+
+	// The playlist must not be fetched again on resume.
+	yt := &mockYTClient{err: fmt.Errorf("should not fetch")}
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.SetStore(store)
+	pipeline.searchDelay = 0
+	if _, err := pipeline.Restore(); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	session, _ := pipeline.GetSession("interrupted")
+	if session.Status != StatusInterrupted {
+		t.Fatalf("status = %q, want 'interrupted'", session.Status)
+	}
+	if session.Interrupted != StatusSearching {
+		t.Errorf("interrupted = %q, want 'searching'", session.Interrupted)
+	}
+
+	if err := pipeline.ResumeSession("interrupted"); err != nil {
+		t.Fatalf("ResumeSession() error = %v", err)
+	}
+	for i := 0; i < 50; i++ {
+		time.Sleep(10 * time.Millisecond)
+		session, _ = pipeline.GetSession("interrupted")
+		if session.Status == StatusReady || session.Status == StatusError {
+			break
+		}
+	}
+
+	if session.Status != StatusReady {
+		t.Fatalf("status = %q, want 'ready' (error: %s)", session.Status, session.Error)
+	}
+	if session.Progress.Searched != 3 || session.Progress.Selected != 3 || session.Progress.SearchFailed != 0 {
+		t.Errorf("progress = %+v, want 3 searched and 3 selected", session.Progress)
+	}
+	// The failed search was retried.
+	for i := 1; i < 3; i++ {
+		if session.Tracks[i].Status != TrackFound {
+			t.Errorf("track[%d] status = %q, want 'found'", i, session.Tracks[i].Status)
+		}
+	}
+}
+
+func TestResumeWithoutRequestCookies(t *testing.T) {
+	store := newMemoryStore()
+	store.Save(&Session{ID: "signed-in", Status: StatusFetching, RequestCookies: true})
+
+	pipeline := NewPipeline(&mockYTClient{}, &mockDeemixClient{}, nil)
+	pipeline.SetStore(store)
+	pipeline.Restore()
+
+	if err := pipeline.ResumeSession("signed-in"); err != ErrCookiesGone {
+		t.Fatalf("ResumeSession() error = %v, want ErrCookiesGone", err)
+	}
+	session, _ := pipeline.GetSession("signed-in")
+	if session.Status != StatusInterrupted {
+		t.Errorf("status = %q, want still interrupted", session.Status)
+	}
+}
+
+func TestResumeInterruptedDownload(t *testing.T) {
+	store := newMemoryStore()
+	store.Save(&Session{
+		ID:      "downloading",
+		Status:  StatusDownloading,
+		Bitrate: deemix.Bitrate320,
+		Tracks: []Track{
+			{Status: TrackDownloaded, Selected: true, DeezerMatch: &deemix.SearchResult{ID: 1, Link: "https://www.deezer.com/track/1"}},
+			{Status: TrackFound, Selected: true, DeezerMatch: &deemix.SearchResult{ID: 2, Link: "https://www.deezer.com/track/2"}},
+			{Status: TrackFound, Selected: false, DeezerMatch: &deemix.SearchResult{ID: 3, Link: "https://www.deezer.com/track/3"}},
+			{Status: TrackError, Error: "queue full", Selected: true, DeezerMatch: &deemix.SearchResult{ID: 4, Link: "https://www.deezer.com/track/4"}},
+		},
+		Progress: Progress{Total: 4, Searched: 4, Selected: 3, Queued: 1, Failed: 1},
+	})
+
+	dx := &mockDeemixClient{}
+	pipeline := NewPipeline(&mockYTClient{}, dx, nil)
+	pipeline.SetStore(store)
+	pipeline.queueDelay = 0
+	pipeline.Restore()
+
+	session, _ := pipeline.GetSession("downloading")
+	if session.Status != StatusInterrupted || session.Interrupted != StatusDownloading {
+		t.Fatalf("status = %q (interrupted %q), want interrupted download", session.Status, session.Interrupted)
+	}
+
+	// Concurrent resumes start the download once.
+	var wg gosync.WaitGroup
+	var resumed atomic.Int32
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if pipeline.ResumeSession("downloading") == nil {
+				resumed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if resumed.Load() != 1 {
+		t.Fatalf("%d resumes succeeded, want 1", resumed.Load())
+	}
+	for i := 0; i < 50; i++ {
+		time.Sleep(10 * time.Millisecond)
+		session, _ = pipeline.GetSession("downloading")
+		if session.Status == StatusDone || session.Status == StatusError {
+			break
+		}
+	}
+
+	if session.Status != StatusDone {
+		t.Fatalf("status = %q, want 'done'", session.Status)
+	}
+	want := []string{"https://www.deezer.com/track/2", "https://www.deezer.com/track/4"}
+	if fmt.Sprint(dx.queuedURLs) != fmt.Sprint(want) {
+		t.Errorf("queued = %v, want %v", dx.queuedURLs, want)
+	}
+	if session.Progress.Queued != 3 {
+		t.Errorf("queued count = %d, want 3", session.Progress.Queued)
+	}
+	// The failed track was queued again, so it no longer counts as failed.
+	if session.Progress.Failed != 0 || session.Tracks[3].Error != "" {
+		t.Errorf("failed = %d, track[3] error = %q, want the failure cleared", session.Progress.Failed, session.Tracks[3].Error)
+	}
+
+	// The per-track result was checkpointed to the store.
+	stored, _ := store.Load()
+	if stored[0].Tracks[3].Status != TrackDownloaded {
+		t.Errorf("stored track[3] status = %q, want 'downloaded'", stored[0].Tracks[3].Status)
+	}
+}
+
+func TestPipelinePicksBestCandidate(t *testing.T) {
+	yt := &mockYTClient{entries: []ytdlp.PlaylistEntry{{Title: "Radiohead - Creep", VideoID: "abc"}}}
+	dx := &mockDeemixClient{
+		searchResults: map[string][]deemix.SearchResult{
+			"Radiohead Creep": {
+				{ID: 1, Title: "Creep (Karaoke Version)", Artist: "Pop Hits Band", Link: "https://www.deezer.com/track/1"},
+				{ID: 2, Title: "Creep", Artist: "Radiohead", Link: "https://www.deezer.com/track/2"},
+			},
+		},
+	}
+
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.searchDelay = 0
+	id := pipeline.Analyze(context.Background(), "url", deemix.Bitrate320, false)
+	session, _ := pipeline.WaitSettled(context.Background(), id)
+
+	track := session.Tracks[0]
+	if track.DeezerMatch == nil || track.DeezerMatch.ID != 2 {
+		t.Fatalf("match = %+v, want track 2", track.DeezerMatch)
+	}
+	if track.Status != TrackFound || track.Confidence != 100 {
+		t.Errorf("status = %q, confidence = %d, want found at 100", track.Status, track.Confidence)
+	}
+	if len(track.Candidates) != 2 || track.Candidates[1].ID != 1 {
+		t.Errorf("candidates = %+v, want both results ranked", track.Candidates)
+	}
+}
+
+func TestSetTrackMatch(t *testing.T) {
+	yt := &mockYTClient{entries: []ytdlp.PlaylistEntry{{Title: "Radiohead - Creep", VideoID: "abc"}}}
+	dx := &mockDeemixClient{
+		searchResults: map[string][]deemix.SearchResult{
+			"Radiohead Creep": {
+				{ID: 1, Title: "Creep (Karaoke Version)", Artist: "Pop Hits Band", Link: "https://www.deezer.com/track/1"},
+			},
+			"Radiohead Creep Acoustic": {
+				{ID: 1, Title: "Creep (Karaoke Version)", Artist: "Pop Hits Band", Link: "https://www.deezer.com/track/1"},
+				{ID: 2, Title: "Creep (Acoustic)", Artist: "Radiohead", Album: "Creep EP", Duration: 259, Link: "https://www.deezer.com/track/2"},
+			},
+		},
+	}
+
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.searchDelay = 0
+	pipeline.SetSearchCache(NewSearchCache("", time.Hour))
+	id := pipeline.Analyze(context.Background(), "url", deemix.Bitrate320, false)
+	session, _ := pipeline.WaitSettled(context.Background(), id)
+	if session.Tracks[0].Status != TrackNeedsReview || session.Progress.NeedsReview != 1 {
+		t.Fatalf("status = %q, needs review = %d, want one track to review", session.Tracks[0].Status, session.Progress.NeedsReview)
+	}
+
+	candidates, err := pipeline.TrackCandidates(context.Background(), id, 0, "Creep Acoustic")
+	if err != nil {
+		t.Fatalf("TrackCandidates() error = %v", err)
+	}
+	if len(candidates) != 2 || candidates[0].ID != 2 || candidates[0].Album != "Creep EP" {
+		t.Fatalf("candidates = %+v, want the Radiohead result first", candidates)
+	}
+
+	if err := pipeline.SetTrackMatch(context.Background(), id, 0, 99); err != ErrCandidateNotFound {
+		t.Errorf("SetTrackMatch() with unknown ID error = %v, want ErrCandidateNotFound", err)
+	}
+
+	// Picking a candidate selects it even below the threshold.
+	if err := pipeline.SetTrackMatch(context.Background(), id, 0, 1); err != nil {
+		t.Fatalf("SetTrackMatch() error = %v", err)
+	}
+	session, _ = pipeline.GetSession(id)
+	track := session.Tracks[0]
+	if track.DeezerMatch.ID != 1 || track.Status != TrackFound || !track.Selected {
+		t.Errorf("track = %+v, want found and selected with match 1", track)
+	}
+	if session.Progress.NeedsReview != 0 || session.Progress.Selected != 1 {
+		t.Errorf("progress = %+v, want needs review 0, selected 1", session.Progress)
+	}
+
+	// Switching between candidates keeps the counters steady.
+	if err := pipeline.SetTrackMatch(context.Background(), id, 0, 2); err != nil {
+		t.Fatalf("SetTrackMatch() error = %v", err)
+	}
+	session, _ = pipeline.GetSession(id)
+	if session.Tracks[0].DeezerMatch.ID != 2 || session.Progress.Selected != 1 {
+		t.Errorf("match = %d, selected = %d, want match 2 and selected 1", session.Tracks[0].DeezerMatch.ID, session.Progress.Selected)
+	}
+
+	// Searching again keeps the pick and the earlier candidates.
+	if _, err := pipeline.TrackCandidates(context.Background(), id, 0, ""); err != nil {
+		t.Fatalf("TrackCandidates() error = %v", err)
+	}
+	session, _ = pipeline.GetSession(id)
+	track = session.Tracks[0]
+	if track.DeezerMatch.ID != 2 || len(track.Candidates) != 2 {
+		t.Errorf("match = %d, candidates = %+v, want match 2 among both candidates", track.DeezerMatch.ID, track.Candidates)
+	}
+	if resolved, ok := pipeline.cache.video(track.cacheKey()); !ok || resolved.PickedID != 2 || len(resolved.Results) != 2 {
+		t.Errorf("cached video = %+v, want both results and the pick", resolved)
+	}
+}
+
+func TestPipelineSplitsMix(t *testing.T) {
+	yt := &mockYTClient{entries: []ytdlp.PlaylistEntry{
+		{Title: "Radiohead - Creep", VideoID: "abc", Duration: 236},
+		{Title: "90s Alternative Mix", VideoID: "mix", Duration: 1200, Chapters: []ytdlp.Chapter{
+			{Title: "Radiohead - Karma Police", StartTime: 0, EndTime: 264},
+			{Title: "Blur - Song 2", StartTime: 264, EndTime: 386},
+			{Title: "Intro", StartTime: 386, EndTime: 1200},
+		}},
+		// Short videos keep their chapters to themselves.
+		{Title: "Muse - Uprising", VideoID: "short", Duration: 305, Chapters: []ytdlp.Chapter{
+			{Title: "Intro", StartTime: 0, EndTime: 30},
+			{Title: "Song", StartTime: 30, EndTime: 305},
+		}},
+	}}
+	dx := &mockDeemixClient{searchResults: map[string][]deemix.SearchResult{
+		`artist:"Radiohead" track:"Karma Police"`: {{ID: 1, Title: "Karma Police", Artist: "Radiohead", Duration: 264}},
+		`artist:"Blur" track:"Song 2"`:            {{ID: 2, Title: "Song 2", Artist: "Blur", Duration: 122}},
+	}}
+
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.searchDelay = 0
+	pipeline.SetSearchCache(NewSearchCache("", time.Hour))
+
+	id := pipeline.Analyze(context.Background(), "url", deemix.Bitrate320, false)
+	session, err := pipeline.WaitSettled(context.Background(), id)
+	if err != nil {
+		t.Fatalf("WaitSettled: %v", err)
+	}
+
+	if len(session.Tracks) != 5 || session.Progress.Total != 5 {
+		t.Fatalf("%d tracks, total %d, want the mix split into 3 of 5", len(session.Tracks), session.Progress.Total)
+	}
+	blur := session.Tracks[2]
+	if blur.VideoID != "mix" || blur.Segment == nil || blur.Segment.Index != 1 || blur.Segment.Start != 264 ||
+		blur.Segment.VideoTitle != "90s Alternative Mix" {
+		t.Errorf("segment = %q %+v, want index 1 of the mix at 264s", blur.VideoID, blur.Segment)
+	}
+	if blur.ParsedArtist != "Blur" || blur.Duration != 122 || blur.DeezerMatch == nil || blur.DeezerMatch.ID != 2 {
+		t.Errorf("Blur: parsed %q, %ds, match %+v, want Blur, 122s and ID 2", blur.ParsedArtist, blur.Duration, blur.DeezerMatch)
+	}
+	if session.Tracks[1].DeezerMatch == nil || session.Tracks[1].DeezerMatch.ID != 1 {
+		t.Errorf("Karma Police matched %+v, want ID 1", session.Tracks[1].DeezerMatch)
+	}
+	if session.Tracks[4].Segment != nil || session.Tracks[4].YouTubeTitle != "Muse - Uprising" {
+		t.Errorf("short video = %+v, want one unsplit track", session.Tracks[4])
+	}
+
+	// Segments of one video are cached apart.
+	if info := pipeline.cache.Info(); len(info.Videos) != 2 || info.Videos[0].Key != "mix@0" || info.Videos[1].Key != "mix@264" {
+		t.Errorf("cached videos = %+v, want mix@0 and mix@264", info.Videos)
+	}
+}
+
+func TestPipelineUnavailableTracks(t *testing.T) {
+	yt := &mockYTClient{entries: []ytdlp.PlaylistEntry{
+		{Title: "Radiohead - Creep", VideoID: "abc"},
+		{Title: "[Private video]", VideoID: "priv", Unavailable: "Private video. Sign in if you've been granted access to this video"},
+	}}
+	dx := &countingDeemixClient{mockDeemixClient: mockDeemixClient{searchResults: map[string][]deemix.SearchResult{
+		"Radiohead Creep": {{ID: 1, Title: "Creep", Artist: "Radiohead"}},
+	}}}
+
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.searchDelay = 0
+	id := pipeline.Analyze(context.Background(), "url", deemix.Bitrate320, false)
+	session, err := pipeline.WaitSettled(context.Background(), id)
+	if err != nil {
+		t.Fatalf("WaitSettled: %v", err)
+	}
+
+	track := session.Tracks[1]
+	if track.Status != TrackUnavailable || track.Error != yt.entries[1].Unavailable || track.VideoID != "priv" {
+		t.Errorf("private video = %+v, want unavailable with yt-dlp's reason", track)
+	}
+	if p := session.Progress; p.Total != 2 || p.Unavailable != 1 || p.Searched != 1 {
+		t.Errorf("progress = %+v, want 2 tracks, 1 unavailable, 1 searched", p)
+	}
+	// Only Creep was searched: the structured query, then the combined one.
+	if dx.searches.Load() != 2 {
+		t.Errorf("Deemix searches = %d, want 2", dx.searches.Load())
+	}
+
+	if err := pipeline.SetTrackSelected(id, 1, true); err != ErrTrackUnavailable {
+		t.Errorf("SetTrackSelected = %v, want ErrTrackUnavailable", err)
+	}
+	if err := pipeline.SearchTrack(context.Background(), id, 1, "Creep"); err != ErrTrackUnavailable {
+		t.Errorf("SearchTrack = %v, want ErrTrackUnavailable", err)
+	}
+}
@@ -2,58 +2,168 @@ package sync
 
 import (
 	"errors"
+	"strconv"
+	"time"
 
 	"github.com/gndm/ytToDeemix/internal/deemix"
+	"github.com/gndm/ytToDeemix/internal/parser"
 )
 
 // Error constants for session operations.
 var (
-	ErrSessionNotFound  = errors.New("session not found")
-	ErrSessionNotReady  = errors.New("session is not in ready status")
-	ErrTrackNotFound    = errors.New("track not found")
-	ErrNoMatch          = errors.New("track has no deezer match")
-	ErrDownloadActive   = errors.New("download already in progress")
-	ErrSessionPaused    = errors.New("session is paused")
-	ErrSessionNotPaused = errors.New("session is not paused")
-	ErrSessionCanceled  = errors.New("session is canceled")
+	ErrSessionNotFound   = errors.New("session not found")
+	ErrSessionNotReady   = errors.New("session is not in ready status")
+	ErrTrackNotFound     = errors.New("track not found")
+	ErrNoMatch           = errors.New("track has no deezer match")
+	ErrDownloadActive    = errors.New("download already in progress")
+	ErrSessionPaused     = errors.New("session is paused")
+	ErrSessionNotPaused  = errors.New("session is not paused")
+	ErrSessionCanceled   = errors.New("session is canceled")
+	ErrSessionActive     = errors.New("session is still active")
+	ErrWatchNotFound     = errors.New("watch not found")
+	ErrInvalidInterval   = errors.New("invalid watch interval")
+	ErrCandidateNotFound = errors.New("candidate not found")
+	ErrTrackUnavailable  = errors.New("track's video is unavailable")
+	ErrNoPlaylistSupport = errors.New("navidrome playlists are not configured")
+	ErrNoPlaylistTitle   = errors.New("session has no playlist title")
+	ErrCookiesGone       = errors.New("session's cookies are gone")
 )
 
 // Session represents a single sync operation from a YouTube playlist.
 type Session struct {
-	ID             string   `json:"id"`
-	URL            string   `json:"url"`
-	Status         string   `json:"status"`
-	Error          string   `json:"error,omitempty"`
-	Tracks         []Track  `json:"tracks"`
-	Progress       Progress `json:"progress"`
-	Bitrate        int      `json:"bitrate"`
-	CheckNavidrome bool     `json:"check_navidrome,omitempty"`
+	ID             string    `json:"id"`
+	URL            string    `json:"url"`
+	Status         string    `json:"status"`
+	Error          string    `json:"error,omitempty"`
+	Tracks         []Track   `json:"tracks"`
+	Progress       Progress  `json:"progress"`
+	Bitrate        int       `json:"bitrate"`
+	CheckNavidrome bool      `json:"check_navidrome,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	// Title is the YouTube playlist title, when yt-dlp reports one.
+	Title string `json:"title,omitempty"`
+	// NavidromePlaylist is the ID of the Navidrome playlist synced from this
+	// session, once SyncPlaylist has run.
+	NavidromePlaylist string `json:"navidrome_playlist,omitempty"`
+	// Interrupted holds the phase that was running when the server stopped.
+	// Set only while Status is StatusInterrupted.
+	Interrupted string `json:"interrupted,omitempty"`
+	// Priority orders sessions waiting for a scheduler slot; higher first.
+	Priority int `json:"priority,omitempty"`
+	// QueuePosition is the 1-based place in the scheduler queue while Status
+	// is StatusQueued, and QueuedFor the status the session starts in once
+	// it gets a slot.
+	QueuePosition int    `json:"queue_position,omitempty"`
+	QueuedFor     string `json:"queued_for,omitempty"`
+	// Fetching is set while playlist entries are still arriving from
+	// yt-dlp. Tracks are added, and searched, in the meantime.
+	Fetching bool `json:"fetching,omitempty"`
+	// RequestCookies is set when the playlist is fetched with cookies sent
+	// with the analyze request. They are never stored, so a session
+	// interrupted before its playlist was fetched can't be resumed.
+	RequestCookies bool `json:"request_cookies,omitempty"`
 }
 
 // Track represents a single video being processed through the pipeline.
 type Track struct {
-	YouTubeTitle string               `json:"youtube_title"`
-	ParsedArtist string               `json:"parsed_artist"`
-	ParsedSong   string               `json:"parsed_song"`
-	DeezerMatch  *deemix.SearchResult `json:"deezer_match,omitempty"`
-	Status       string               `json:"status"`
-	Confidence   int                  `json:"confidence"`
-	Selected     bool                 `json:"selected"`
+	VideoID      string `json:"video_id,omitempty"`
+	YouTubeTitle string `json:"youtube_title"`
+	// ParsedArtist is the primary artist credit, without featured
+	// artists. Artists splits it into single names.
+	ParsedArtist string   `json:"parsed_artist"`
+	Artists      []string `json:"artists,omitempty"`
+	Featured     []string `json:"featured,omitempty"`
+	ParsedSong   string   `json:"parsed_song"`
+	// Version is the recording the YouTube title names, such as a live
+	// version or a remix. Zero for the original.
+	Version parser.Version `json:"version,omitzero"`
+	// ISRC identifies the recording, when the video's description gives it.
+	ISRC        string               `json:"isrc,omitempty"`
+	DeezerMatch *deemix.SearchResult `json:"deezer_match,omitempty"`
+	// Candidates holds every Deezer result scored against the parsed track,
+	// best first. DeezerMatch is the first candidate unless the user picked
+	// another one with SetTrackMatch.
+	Candidates []Candidate `json:"candidates,omitempty"`
+	Status     string      `json:"status"`
+	Confidence int         `json:"confidence"`
+	Selected   bool        `json:"selected"`
+	// Duration is the YouTube video length in seconds, 0 if unknown.
+	Duration int `json:"duration,omitempty"`
+	// DurationDelta is the match's Deezer duration minus Duration, in
+	// seconds. Nil when either duration is unknown.
+	DurationDelta *int `json:"duration_delta,omitempty"`
+	// Error is why searching, queuing or downloading the track failed, as
+	// reported by Deemix, while Status is TrackError, or why yt-dlp could not fetch the
+	// video while Status is TrackUnavailable.
+	Error string `json:"error,omitempty"`
+	// MatchStrategy is the search strategy that found DeezerMatch, one of
+	// the Strategy constants.
+	MatchStrategy string `json:"match_strategy,omitempty"`
+	// Segment is set when the track is one part of a longer video, such as
+	// a DJ mix or a full album upload. VideoID is then the whole video's.
+	Segment *Segment `json:"segment,omitempty"`
+}
+
+// Segment locates a track within the video it was cut from, using the
+// video's chapters or the timestamped tracklist in its description.
+type Segment struct {
+	// VideoTitle is the title of the whole video; Track.YouTubeTitle is the
+	// chapter's.
+	VideoTitle string `json:"video_title"`
+	// Index is the segment's 0-based position in the video.
+	Index int `json:"index"`
+	// Start and End are in seconds. End is 0 for the last segment when the
+	// video's length is unknown.
+	Start int `json:"start"`
+	End   int `json:"end,omitempty"`
+}
+
+// cacheKey identifies what the track resolved to in the search cache: the
+// video ID, plus the start time for a segment of a video.
+func (t *Track) cacheKey() string {
+	if t.Segment == nil || t.VideoID == "" {
+		return t.VideoID
+	}
+	return t.VideoID + "@" + strconv.Itoa(t.Segment.Start)
+}
+
+// Candidate is a Deezer search result with its confidence score.
+type Candidate struct {
+	deemix.SearchResult
+	Confidence    int  `json:"confidence"`
+	DurationDelta *int `json:"duration_delta,omitempty"`
+	// Strategy is the search strategy that returned this result.
+	Strategy string `json:"strategy,omitempty"`
+	// Version is the recording the result's title or album names.
+	Version parser.Version `json:"version,omitzero"`
 }
 
 // Progress holds aggregate counts for the session.
 type Progress struct {
+	// Fetched counts the playlist entries received from yt-dlp. It can be
+	// lower than Total, as a mix becomes several tracks.
+	Fetched     int `json:"fetched"`
 	Total       int `json:"total"`
 	Searched    int `json:"searched"`
 	Queued      int `json:"queued"`
+	Downloaded  int `json:"downloaded"`
+	Failed      int `json:"failed"`
 	NotFound    int `json:"not_found"`
 	Skipped     int `json:"skipped"`
 	NeedsReview int `json:"needs_review"`
 	Selected    int `json:"selected"`
+	// Unavailable counts the tracks whose video could not be fetched.
+	Unavailable int `json:"unavailable"`
+	// SearchFailed counts the tracks whose Deezer search failed. A resumed
+	// analysis searches them again.
+	SearchFailed int `json:"search_failed"`
 }
 
 // Status constants for sessions.
 const (
+	// StatusQueued marks a session waiting for a scheduler slot before
+	// analysis or download.
+	StatusQueued      = "queued"
 	StatusFetching    = "fetching"
 	StatusParsing     = "parsing"
 	StatusSearching   = "searching"
@@ -64,6 +174,9 @@ const (
 	StatusError       = "error"
 	StatusPaused      = "paused"
 	StatusCanceled    = "canceled"
+	// StatusInterrupted marks a session restored from the store after a restart
+	// while it was still active. It can be resumed with ResumeSession.
+	StatusInterrupted = "interrupted"
 )
 
 // Track status constants.
@@ -74,6 +187,12 @@ const (
 	TrackNotFound    = "not_found"
 	TrackSkipped     = "skipped"
 	TrackNeedsReview = "needs_review"
+	TrackQueued      = "queued"
+	TrackDownloading = "downloading"
 	TrackDownloaded  = "downloaded"
 	TrackError       = "error"
+	// TrackUnavailable marks a private, deleted or otherwise unavailable
+	// video the playlist lists. It has no title to search for; Error holds
+	// yt-dlp's reason.
+	TrackUnavailable = "unavailable"
 )
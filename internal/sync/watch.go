@@ -0,0 +1,400 @@
+package sync
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gndm/ytToDeemix/internal/ytdlp"
+)
+
+// MinWatchInterval is the shortest allowed interval between playlist checks.
+const MinWatchInterval = 5 * time.Minute
+
+// defaultWatchTick is how often the Watcher looks for due watches.
+const defaultWatchTick = time.Minute
+
+// Watch is a playlist that is checked periodically for new videos.
+type Watch struct {
+	ID             string    `json:"id"`
+	URL            string    `json:"url"`
+	Interval       string    `json:"interval"`
+	Bitrate        int       `json:"bitrate"`
+	CheckNavidrome bool      `json:"check_navidrome,omitempty"`
+	AutoDownload   bool      `json:"auto_download,omitempty"`
+	SeenIDs        []string  `json:"seen_ids"`
+	LastRun        time.Time `json:"last_run"`
+	NextRun        time.Time `json:"next_run"`
+	LastSessionID  string    `json:"last_session_id,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+	// PendingIDs are the new videos LastSessionID is still analysing. They
+	// become seen once it is ready.
+	PendingIDs []string `json:"pending_ids,omitempty"`
+}
+
+// WatchStore persists registered watches.
+type WatchStore interface {
+	SaveWatches(watches []Watch) error
+	LoadWatches() ([]Watch, error)
+}
+
+// ParseInterval parses a watch interval. Accepts Go durations ("6h", "30m")
+// and the shortcuts "@hourly", "@daily" and "@weekly".
+func ParseInterval(s string) (time.Duration, error) {
+	var d time.Duration
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "@hourly":
+		d = time.Hour
+	case "@daily":
+		d = 24 * time.Hour
+	case "@weekly":
+		d = 7 * 24 * time.Hour
+	default:
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, ErrInvalidInterval
+		}
+		d = parsed
+	}
+	if d < MinWatchInterval {
+		return 0, ErrInvalidInterval
+	}
+	return d, nil
+}
+
+// Watcher checks watched playlists on their interval and analyzes only the
+// videos it has not seen before.
+type Watcher struct {
+	pipeline *Pipeline
+	store    WatchStore
+	watches  map[string]*Watch
+	running  map[string]bool
+	mu       sync.Mutex
+	saveMu   sync.Mutex
+	tick     time.Duration
+}
+
+// NewWatcher creates a Watcher that starts sessions on the given pipeline.
+// store can be nil to keep watches in memory only.
+func NewWatcher(pipeline *Pipeline, store WatchStore) *Watcher {
+	return &Watcher{
+		pipeline: pipeline,
+		store:    store,
+		watches:  make(map[string]*Watch),
+		running:  make(map[string]bool),
+		tick:     defaultWatchTick,
+	}
+}
+
+// Load restores watches from the store.
+func (w *Watcher) Load() error {
+	if w.store == nil {
+		return nil
+	}
+	watches, err := w.store.LoadWatches()
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	for i := range watches {
+		watch := watches[i]
+		w.watches[watch.ID] = &watch
+	}
+	w.mu.Unlock()
+
+	log.Printf("[watch] restored %d watches", len(watches))
+	return nil
+}
+
+// Add registers a playlist to watch. The first check runs on the next tick.
+func (w *Watcher) Add(playlistURL, interval string, bitrate int, checkNavidrome, autoDownload bool) (*Watch, error) {
+	if _, err := ParseInterval(interval); err != nil {
+		return nil, err
+	}
+
+	watch := &Watch{
+		ID:             generateID(),
+		URL:            playlistURL,
+		Interval:       interval,
+		Bitrate:        bitrate,
+		CheckNavidrome: checkNavidrome,
+		AutoDownload:   autoDownload,
+		SeenIDs:        []string{},
+		NextRun:        time.Now(),
+	}
+
+	w.mu.Lock()
+	w.watches[watch.ID] = watch
+	cp := *watch
+	w.mu.Unlock()
+	w.save()
+
+	log.Printf("[watch] watching %s every %s", playlistURL, interval)
+	return &cp, nil
+}
+
+// Remove stops watching a playlist.
+func (w *Watcher) Remove(id string) error {
+	w.mu.Lock()
+	if _, ok := w.watches[id]; !ok {
+		w.mu.Unlock()
+		return ErrWatchNotFound
+	}
+	delete(w.watches, id)
+	w.mu.Unlock()
+	w.save()
+
+	log.Printf("[watch] watch %s removed", id)
+	return nil
+}
+
+// List returns a copy of every watch, ordered by URL.
+func (w *Watcher) List() []Watch {
+	w.mu.Lock()
+	list := make([]Watch, 0, len(w.watches))
+	for _, watch := range w.watches {
+		cp := *watch
+		cp.SeenIDs = append([]string(nil), watch.SeenIDs...)
+		cp.PendingIDs = append([]string(nil), watch.PendingIDs...)
+		list = append(list, cp)
+	}
+	w.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].URL < list[j].URL
+	})
+	return list
+}
+
+// Trigger checks a watch immediately, in the background.
+func (w *Watcher) Trigger(ctx context.Context, id string) error {
+	w.mu.Lock()
+	_, ok := w.watches[id]
+	w.mu.Unlock()
+	if !ok {
+		return ErrWatchNotFound
+	}
+
+	go w.Check(ctx, id)
+	return nil
+}
+
+// Run checks due watches every tick until ctx is canceled.
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.tick)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		w.mu.Lock()
+		var due []string
+		for id, watch := range w.watches {
+			if !now.Before(watch.NextRun) {
+				due = append(due, id)
+			}
+		}
+		w.mu.Unlock()
+
+		for _, id := range due {
+			go w.Check(ctx, id)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check fetches the watched playlist and analyses the videos not seen
+// before in a new session, waiting until it is ready; they are recorded
+// as seen only then. With AutoDownload, the selected tracks (those at or
+// above the confidence threshold) are queued once analysis is ready.
+// If the previous check stopped waiting before its session was ready, as
+// on shutdown, Check resumes and waits for that session instead.
+// Returns the session ID, or "" when there was nothing new.
+func (w *Watcher) Check(ctx context.Context, id string) (string, error) {
+	w.mu.Lock()
+	watch, ok := w.watches[id]
+	if !ok {
+		w.mu.Unlock()
+		return "", ErrWatchNotFound
+	}
+	if w.running[id] {
+		w.mu.Unlock()
+		return "", nil
+	}
+	w.running[id] = true
+	url := watch.URL
+	bitrate, checkNavidrome, autoDownload := watch.Bitrate, watch.CheckNavidrome, watch.AutoDownload
+	lastSessionID := watch.LastSessionID
+	pending := append([]string(nil), watch.PendingIDs...)
+	seen := make(map[string]bool, len(watch.SeenIDs))
+	for _, vid := range watch.SeenIDs {
+		seen[vid] = true
+	}
+	w.mu.Unlock()
+
+	defer func() {
+		w.mu.Lock()
+		delete(w.running, id)
+		w.mu.Unlock()
+	}()
+
+	if len(pending) > 0 && w.resume(lastSessionID) {
+		log.Printf("[watch] waiting for session %s of %s", lastSessionID, url)
+		return w.await(ctx, id, lastSessionID, pending, autoDownload)
+	}
+
+	log.Printf("[watch] checking %s", url)
+	entries, err := w.pipeline.ytClient.GetPlaylist(ctx, url)
+	if err != nil {
+		log.Printf("[watch] failed to fetch %s: %v", url, err)
+		w.finish(id, "", nil, nil, "failed to fetch playlist: "+err.Error())
+		return "", err
+	}
+
+	var newEntries []ytdlp.PlaylistEntry
+	var newIDs []string
+	for _, e := range entries {
+		// Left unseen, so the video is analysed once it is available again.
+		if e.Unavailable != "" {
+			continue
+		}
+		key := e.VideoID
+		if key == "" {
+			key = e.URL
+		}
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		newEntries = append(newEntries, e)
+		newIDs = append(newIDs, key)
+	}
+
+	if len(newEntries) == 0 {
+		log.Printf("[watch] no new videos in %s", url)
+		w.finish(id, "", nil, nil, "")
+		return "", nil
+	}
+
+	log.Printf("[watch] %d new videos in %s", len(newEntries), url)
+	sessionID := w.pipeline.AnalyzeEntries(context.Background(), url, newEntries, bitrate, checkNavidrome)
+	w.begin(id, sessionID, newIDs)
+	return w.await(ctx, id, sessionID, newIDs, autoDownload)
+}
+
+// resume prepares the session of an unfinished check to be waited for
+// again, resuming it if the server stopped while it ran. Returns false if
+// the session is gone or ended without analysing the videos, which are
+// then analysed again.
+func (w *Watcher) resume(sessionID string) bool {
+	session, ok := w.pipeline.GetSession(sessionID)
+	if !ok {
+		return false
+	}
+	switch session.Status {
+	case StatusError, StatusCanceled:
+		return false
+	case StatusInterrupted:
+		if err := w.pipeline.ResumeSession(sessionID); err != nil {
+			log.Printf("[watch] failed to resume session %s: %v", sessionID, err)
+			return false
+		}
+	}
+	return true
+}
+
+// await waits for the session analysing newIDs. The new videos only count
+// as seen once analysed, so a session that fails, is canceled or deleted
+// leaves them for the next check. If ctx ends first, they stay pending on
+// the session for the next check to wait for.
+func (w *Watcher) await(ctx context.Context, id, sessionID string, newIDs []string, autoDownload bool) (string, error) {
+	session, err := w.pipeline.WaitSettled(ctx, sessionID)
+	if err != nil {
+		w.finish(id, sessionID, nil, newIDs, "analysis did not finish: "+err.Error())
+		return sessionID, err
+	}
+	switch session.Status {
+	case StatusReady:
+	case StatusDone:
+		// Downloaded by hand while the check was not waiting.
+		w.finish(id, sessionID, newIDs, nil, "")
+		return sessionID, nil
+	default:
+		msg := "analysis ended " + session.Status
+		if session.Error != "" {
+			msg += ": " + session.Error
+		}
+		log.Printf("[watch] session %s for %s %s", sessionID, session.URL, msg)
+		w.finish(id, sessionID, nil, nil, msg)
+		return sessionID, nil
+	}
+	w.finish(id, sessionID, newIDs, nil, "")
+
+	if autoDownload {
+		if err := w.pipeline.Download(context.Background(), sessionID); err != nil {
+			log.Printf("[watch] auto-download of session %s failed: %v", sessionID, err)
+			return sessionID, err
+		}
+	}
+
+	return sessionID, nil
+}
+
+// begin records the session a check started before waiting for it, so a
+// check cut short can find it again.
+func (w *Watcher) begin(id, sessionID string, newIDs []string) {
+	w.mu.Lock()
+	watch, ok := w.watches[id]
+	if !ok {
+		w.mu.Unlock()
+		return
+	}
+	watch.LastSessionID = sessionID
+	watch.PendingIDs = newIDs
+	w.mu.Unlock()
+	w.save()
+}
+
+// finish records the outcome of a check and schedules the next one. seen
+// are added to SeenIDs; pending are left waiting for sessionID.
+func (w *Watcher) finish(id, sessionID string, seen, pending []string, errMsg string) {
+	w.mu.Lock()
+	watch, ok := w.watches[id]
+	if !ok {
+		w.mu.Unlock()
+		return
+	}
+	interval, _ := ParseInterval(watch.Interval)
+	watch.LastRun = time.Now()
+	watch.NextRun = watch.LastRun.Add(interval)
+	watch.LastError = errMsg
+	watch.SeenIDs = append(watch.SeenIDs, seen...)
+	watch.PendingIDs = pending
+	if sessionID != "" {
+		watch.LastSessionID = sessionID
+	}
+	w.mu.Unlock()
+	w.save()
+}
+
+// save writes all watches to the store, if one is configured.
+func (w *Watcher) save() {
+	if w.store == nil {
+		return
+	}
+	w.saveMu.Lock()
+	defer w.saveMu.Unlock()
+
+	list := w.List()
+	if err := w.store.SaveWatches(list); err != nil {
+		log.Printf("[watch] failed to persist watches: %v", err)
+	}
+}
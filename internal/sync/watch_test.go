@@ -0,0 +1,268 @@
+package sync
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gndm/ytToDeemix/internal/deemix"
+	"github.com/gndm/ytToDeemix/internal/ytdlp"
+)
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"@hourly", time.Hour, false},
+		{"@daily", 24 * time.Hour, false},
+		{"@WEEKLY", 7 * 24 * time.Hour, false},
+		{"6h", 6 * time.Hour, false},
+		{"30m", 30 * time.Minute, false},
+		{"1m", 0, true},
+		{"tomorrow", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseInterval(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseInterval(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseInterval(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWatcherIncrementalCheck(t *testing.T) {
+	yt := &mockYTClient{
+		entries: []ytdlp.PlaylistEntry{
+			{Title: "Artist - Song 1", VideoID: "a"},
+			{Title: "Artist - Song 2", VideoID: "b"},
+		},
+	}
+	dx := &mockDeemixClient{
+		searchResults: map[string][]deemix.SearchResult{
+			"Artist Song 1": {{ID: 1, Title: "Song 1", Artist: "Artist", Link: "https://www.deezer.com/track/1"}},
+			"Artist Song 2": {{ID: 2, Title: "Song 2", Artist: "Artist", Link: "https://www.deezer.com/track/2"}},
+			"Artist Song 3": {{ID: 3, Title: "Song 3", Artist: "Artist", Link: "https://www.deezer.com/track/3"}},
+		},
+	}
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.searchDelay = 0
+
+	watcher := NewWatcher(pipeline, nil)
+	watch, err := watcher.Add("https://youtube.com/playlist?list=test", "@daily", deemix.Bitrate320, false, false)
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	// First check analyzes the whole playlist.
+	id, err := watcher.Check(context.Background(), watch.ID)
+	if err != nil || id == "" {
+		t.Fatalf("first Check() = %q, %v", id, err)
+	}
+	session, _ := pipeline.WaitSettled(context.Background(), id)
+	if len(session.Tracks) != 2 {
+		t.Errorf("first session has %d tracks, want 2", len(session.Tracks))
+	}
+
+	// Second check only picks up the new video.
+	yt.entries = append(yt.entries, ytdlp.PlaylistEntry{Title: "Artist - Song 3", VideoID: "c"})
+	id, err = watcher.Check(context.Background(), watch.ID)
+	if err != nil || id == "" {
+		t.Fatalf("second Check() = %q, %v", id, err)
+	}
+	session, _ = pipeline.WaitSettled(context.Background(), id)
+	if len(session.Tracks) != 1 || session.Tracks[0].VideoID != "c" {
+		t.Errorf("second session tracks = %+v, want only video c", session.Tracks)
+	}
+
+	// Nothing new: no session.
+	id, err = watcher.Check(context.Background(), watch.ID)
+	if err != nil || id != "" {
+		t.Errorf("third Check() = %q, %v, want no session", id, err)
+	}
+
+	list := watcher.List()
+	if len(list) != 1 || len(list[0].SeenIDs) != 3 {
+		t.Fatalf("watch list = %+v, want 3 seen IDs", list)
+	}
+	if !list[0].NextRun.After(time.Now().Add(23 * time.Hour)) {
+		t.Errorf("next run = %v, want about a day from now", list[0].NextRun)
+	}
+}
+
+// blockingDeemixClient holds every search until release is closed.
+type blockingDeemixClient struct {
+	mockDeemixClient
+	release chan struct{}
+}
+
+func (m *blockingDeemixClient) Search(ctx context.Context, query string) ([]deemix.SearchResult, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-m.release:
+	}
+	return m.mockDeemixClient.Search(ctx, query)
+}
+
+func TestWatcherKeepsVideosOfCanceledSession(t *testing.T) {
+	yt := &mockYTClient{entries: []ytdlp.PlaylistEntry{{Title: "Artist - Song 1", VideoID: "a"}}}
+	dx := &blockingDeemixClient{release: make(chan struct{}), mockDeemixClient: mockDeemixClient{
+		searchResults: map[string][]deemix.SearchResult{
+			"Artist Song 1": {{ID: 1, Title: "Song 1", Artist: "Artist"}},
+		},
+	}}
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.searchDelay = 0
+
+	watcher := NewWatcher(pipeline, nil)
+	watch, _ := watcher.Add("https://youtube.com/playlist?list=test", "@daily", deemix.Bitrate320, false, false)
+
+	// Cancel the first session while it is searching.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		watcher.Check(context.Background(), watch.ID)
+	}()
+	for deadline := time.Now().Add(2 * time.Second); ; {
+		if sessions := pipeline.ListSessions(); len(sessions) == 1 {
+			if err := pipeline.CancelSession(sessions[0].ID); err == nil {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("watch session did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	<-done
+
+	list := watcher.List()
+	if len(list[0].SeenIDs) != 0 || list[0].LastError == "" {
+		t.Fatalf("after a canceled session: seen %v, error %q; want none seen and an error", list[0].SeenIDs, list[0].LastError)
+	}
+
+	// The next check analyses the video again.
+	close(dx.release)
+	id, err := watcher.Check(context.Background(), watch.ID)
+	if err != nil || id == "" {
+		t.Fatalf("second Check() = %q, %v", id, err)
+	}
+	if list := watcher.List(); len(list[0].SeenIDs) != 1 || list[0].LastError != "" {
+		t.Errorf("after a ready session: seen %v, error %q; want video a", list[0].SeenIDs, list[0].LastError)
+	}
+}
+
+func TestWatcherWaitsForUnfinishedSession(t *testing.T) {
+	yt := &mockYTClient{entries: []ytdlp.PlaylistEntry{{Title: "Artist - Song 1", VideoID: "a"}}}
+	dx := &blockingDeemixClient{release: make(chan struct{}), mockDeemixClient: mockDeemixClient{
+		searchResults: map[string][]deemix.SearchResult{
+			"Artist Song 1": {{ID: 1, Title: "Song 1", Artist: "Artist"}},
+		},
+	}}
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.searchDelay = 0
+
+	watcher := NewWatcher(pipeline, nil)
+	watch, _ := watcher.Add("https://youtube.com/playlist?list=test", "@daily", deemix.Bitrate320, false, false)
+
+	// The check stops waiting, as on shutdown, while its session searches.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	first, err := watcher.Check(ctx, watch.ID)
+	if err == nil || first == "" {
+		t.Fatalf("first Check() = %q, %v, want the session and an error", first, err)
+	}
+	list := watcher.List()
+	if list[0].LastSessionID != first || len(list[0].PendingIDs) != 1 || len(list[0].SeenIDs) != 0 {
+		t.Fatalf("after an unfinished check: %+v, want session %s pending with video a", list[0], first)
+	}
+
+	// The next check waits for that session instead of starting another.
+	close(dx.release)
+	id, err := watcher.Check(context.Background(), watch.ID)
+	if err != nil || id != first {
+		t.Fatalf("second Check() = %q, %v, want %q", id, err, first)
+	}
+	if n := len(pipeline.ListSessions()); n != 1 {
+		t.Errorf("%d sessions, want 1", n)
+	}
+	list = watcher.List()
+	if len(list[0].SeenIDs) != 1 || len(list[0].PendingIDs) != 0 || list[0].LastError != "" {
+		t.Errorf("after the session is ready: %+v, want video a seen", list[0])
+	}
+}
+
+func TestWatcherAutoDownload(t *testing.T) {
+	yt := &mockYTClient{
+		entries: []ytdlp.PlaylistEntry{
+			{Title: "Artist - Song 1", VideoID: "a"},
+			{Title: "Someone - Different", VideoID: "b"},
+		},
+	}
+	dx := &mockDeemixClient{
+		searchResults: map[string][]deemix.SearchResult{
+			"Artist Song 1":     {{ID: 1, Title: "Song 1", Artist: "Artist", Link: "https://www.deezer.com/track/1"}},
+			"Someone Different": {{ID: 2, Title: "Unrelated", Artist: "Nobody", Link: "https://www.deezer.com/track/2"}},
+		},
+	}
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.searchDelay = 0
+	pipeline.queueDelay = 0
+
+	watcher := NewWatcher(pipeline, nil)
+	watch, _ := watcher.Add("https://youtube.com/playlist?list=test", "@hourly", deemix.Bitrate320, false, true)
+
+	id, err := watcher.Check(context.Background(), watch.ID)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	session, _ := pipeline.GetSession(id)
+	if session.Status != StatusDone {
+		t.Fatalf("status = %q, want 'done'", session.Status)
+	}
+	// Only the high-confidence match is queued.
+	if len(dx.queuedURLs) != 1 || dx.queuedURLs[0] != "https://www.deezer.com/track/1" {
+		t.Errorf("queued = %v, want only track 1", dx.queuedURLs)
+	}
+}
+
+func TestWatcherRemoveAndPersist(t *testing.T) {
+	store := &FileWatchStore{Path: filepath.Join(t.TempDir(), "watches.json")}
+	pipeline := NewPipeline(&mockYTClient{}, &mockDeemixClient{}, nil)
+
+	watcher := NewWatcher(pipeline, store)
+	if _, err := watcher.Add("https://youtube.com/playlist?list=a", "1m", deemix.Bitrate128, false, false); err != ErrInvalidInterval {
+		t.Errorf("Add() with short interval error = %v, want ErrInvalidInterval", err)
+	}
+	watch, _ := watcher.Add("https://youtube.com/playlist?list=a", "12h", deemix.Bitrate128, true, false)
+	watcher.Add("https://youtube.com/playlist?list=b", "@weekly", deemix.Bitrate128, false, false)
+
+	restored := NewWatcher(pipeline, store)
+	if err := restored.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := restored.List(); len(got) != 2 || !got[0].CheckNavidrome {
+		t.Fatalf("restored watches = %+v", got)
+	}
+
+	if err := restored.Remove(watch.ID); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := restored.Remove(watch.ID); err != ErrWatchNotFound {
+		t.Errorf("second Remove() error = %v, want ErrWatchNotFound", err)
+	}
+
+	again := NewWatcher(pipeline, store)
+	again.Load()
+	if got := again.List(); len(got) != 1 {
+		t.Errorf("watches after remove = %d, want 1", len(got))
+	}
+}
@@ -0,0 +1,98 @@
+package sync
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultWorkers is how many tracks a session searches or checks at once.
+const DefaultWorkers = 4
+
+// SetWorkers sets how many tracks a session searches on Deezer or checks in
+// Navidrome at once. Values below 1 are treated as 1.
+func (p *Pipeline) SetWorkers(n int) {
+	p.workers = max(1, n)
+}
+
+// forEachTrack calls fn for every track index on up to p.workers goroutines.
+// fn must lock p.mu itself; each call owns its track.
+//
+// Indices are handed out from the calling goroutine, which also watches the
+// session's pause and cancel signals in place of checkpoint: a pause stops
+// handing out tracks, takes effect once the ones in flight finish, and
+// holds the phase open until resumed. While the session is still fetching,
+// tracks appended meanwhile are handed out too, and the call returns once
+// fetching ends and every track was handed out. Returns a non-nil error if
+// ctx was canceled.
+func (p *Pipeline) forEachTrack(ctx context.Context, session *Session, status string, fn func(i int)) error {
+	p.mu.RLock()
+	ctrl := p.controls[session.ID]
+	p.mu.RUnlock()
+
+	var pauseCh, grown <-chan struct{}
+	if ctrl != nil {
+		pauseCh, grown = ctrl.pauseCh, ctrl.grown
+	}
+
+	jobs := make(chan int)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for range max(1, p.workers) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	var err error
+	for i := 0; err == nil; {
+		p.mu.RLock()
+		n, fetching := len(session.Tracks), session.Fetching
+		p.mu.RUnlock()
+		if i >= n {
+			if !fetching {
+				break
+			}
+			// Wait for the next track to arrive.
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+			case <-pauseCh:
+				err = p.waitResume(ctx, session, ctrl, status)
+			case <-grown:
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-pauseCh:
+			err = p.waitResume(ctx, session, ctrl, status)
+		case jobs <- i:
+			i++
+		}
+	}
+	close(jobs)
+
+	// Let the tracks in flight finish. A pause now still holds the phase.
+	for {
+		select {
+		case <-done:
+			if err == nil {
+				err = ctx.Err()
+			}
+			return err
+		case <-pauseCh:
+			if err == nil {
+				err = p.waitResume(ctx, session, ctrl, status)
+			}
+		}
+	}
+}
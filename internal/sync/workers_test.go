@@ -0,0 +1,160 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	gosync "sync"
+	"testing"
+	"time"
+
+	"github.com/gndm/ytToDeemix/internal/deemix"
+	"github.com/gndm/ytToDeemix/internal/ytdlp"
+)
+
+// concurrentDeemixClient records how many searches run at once.
+type concurrentDeemixClient struct {
+	mockDeemixClient
+	delay time.Duration
+
+	mu      gosync.Mutex
+	active  int
+	maxSeen int
+}
+
+func (m *concurrentDeemixClient) Search(ctx context.Context, query string) ([]deemix.SearchResult, error) {
+	m.mu.Lock()
+	m.active++
+	m.maxSeen = max(m.maxSeen, m.active)
+	m.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(m.delay):
+	}
+
+	m.mu.Lock()
+	m.active--
+	m.mu.Unlock()
+	return m.mockDeemixClient.Search(ctx, query)
+}
+
+func workersTestClients(n int) (*mockYTClient, map[string][]deemix.SearchResult) {
+	yt := &mockYTClient{}
+	results := make(map[string][]deemix.SearchResult)
+	for i := range n {
+		song := fmt.Sprintf("Song %d", i)
+		yt.entries = append(yt.entries, ytdlp.PlaylistEntry{Title: "Artist - " + song})
+		results["Artist "+song] = []deemix.SearchResult{{ID: int64(i), Title: song, Artist: "Artist"}}
+	}
+	return yt, results
+}
+
+func TestSearchWorkers(t *testing.T) {
+	yt, results := workersTestClients(12)
+	dx := &concurrentDeemixClient{
+		mockDeemixClient: mockDeemixClient{searchResults: results},
+		delay:            20 * time.Millisecond,
+	}
+
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.searchDelay = 0
+	pipeline.SetWorkers(3)
+
+	id := pipeline.Analyze(context.Background(), "url", deemix.Bitrate320, false)
+	session, err := pipeline.WaitSettled(context.Background(), id)
+	if err != nil {
+		t.Fatalf("WaitSettled: %v", err)
+	}
+
+	if session.Status != StatusReady {
+		t.Fatalf("status = %q, want %q", session.Status, StatusReady)
+	}
+	if dx.maxSeen != 3 {
+		t.Errorf("max concurrent searches = %d, want 3", dx.maxSeen)
+	}
+	if session.Progress.Searched != 12 {
+		t.Errorf("searched = %d, want 12", session.Progress.Searched)
+	}
+	// Each track keeps its own match, in playlist order.
+	for i, track := range session.Tracks {
+		if track.DeezerMatch == nil || track.DeezerMatch.ID != int64(i) {
+			t.Errorf("track %d matched %+v, want ID %d", i, track.DeezerMatch, i)
+		}
+	}
+}
+
+func TestPauseWithWorkers(t *testing.T) {
+	yt, results := workersTestClients(12)
+	dx := &concurrentDeemixClient{
+		mockDeemixClient: mockDeemixClient{searchResults: results},
+		delay:            30 * time.Millisecond,
+	}
+
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.searchDelay = 0
+	pipeline.SetWorkers(3)
+
+	id := pipeline.Analyze(context.Background(), "url", deemix.Bitrate320, false)
+	time.Sleep(40 * time.Millisecond)
+
+	if err := pipeline.PauseSession(id); err != nil {
+		t.Fatalf("PauseSession: %v", err)
+	}
+
+	// Tracks in flight finish, then nothing moves.
+	time.Sleep(100 * time.Millisecond)
+	session, _ := pipeline.GetSession(id)
+	if session.Status != StatusPaused {
+		t.Fatalf("status = %q, want %q", session.Status, StatusPaused)
+	}
+	searched := session.Progress.Searched
+	time.Sleep(100 * time.Millisecond)
+	session, _ = pipeline.GetSession(id)
+	if session.Progress.Searched != searched {
+		t.Errorf("searched went from %d to %d while paused", searched, session.Progress.Searched)
+	}
+	if searched >= 12 {
+		t.Errorf("searched = %d while paused, want fewer than 12", searched)
+	}
+
+	if err := pipeline.ResumeSession(id); err != nil {
+		t.Fatalf("ResumeSession: %v", err)
+	}
+	session, err := pipeline.WaitSettled(context.Background(), id)
+	if err != nil {
+		t.Fatalf("WaitSettled: %v", err)
+	}
+	if session.Status != StatusReady || session.Progress.Searched != 12 {
+		t.Errorf("after resume: status %q, searched %d, want ready and 12", session.Status, session.Progress.Searched)
+	}
+}
+
+func TestCancelWithWorkers(t *testing.T) {
+	yt, results := workersTestClients(12)
+	dx := &concurrentDeemixClient{
+		mockDeemixClient: mockDeemixClient{searchResults: results},
+		delay:            30 * time.Millisecond,
+	}
+
+	pipeline := NewPipeline(yt, dx, nil)
+	pipeline.searchDelay = 0
+	pipeline.SetWorkers(3)
+
+	id := pipeline.Analyze(context.Background(), "url", deemix.Bitrate320, false)
+	time.Sleep(40 * time.Millisecond)
+
+	if err := pipeline.CancelSession(id); err != nil {
+		t.Fatalf("CancelSession: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	session, _ := pipeline.GetSession(id)
+	if session.Status != StatusCanceled {
+		t.Errorf("status = %q, want %q", session.Status, StatusCanceled)
+	}
+	for i, track := range session.Tracks {
+		if track.Status == TrackSearching || track.Status == TrackError {
+			t.Errorf("track %d left %q after cancel", i, track.Status)
+		}
+	}
+}
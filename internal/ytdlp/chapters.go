@@ -0,0 +1,91 @@
+package ytdlp
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MixMinDuration is the length in seconds from which a video with chapters
+// is treated as a mix or compilation to split into tracks. Shorter videos
+// may have chapters too, but they mark parts of one song. In flat
+// playlists, videos this long are fetched in full to get their chapters.
+const MixMinDuration = 10 * 60
+
+// Chapter is a part of a video: one of its chapters, or a line of the
+// timestamped tracklist in its description. Times are in seconds; EndTime
+// is 0 when the video's length is unknown.
+type Chapter struct {
+	Title     string  `json:"title"`
+	StartTime float64 `json:"start_time"`
+	EndTime   float64 `json:"end_time"`
+}
+
+// timestamp matches [h:]mm:ss.
+const timestamp = `((?:\d{1,2}:)?\d{1,2}:\d{2})`
+
+// tracklistLeading matches a tracklist line starting with its timestamp,
+// optionally numbered or bracketed: "03:45 Artist - Song", "1. [03:45]
+// Song".
+var tracklistLeading = regexp.MustCompile(`^(?:\d{1,3}[.)]\s*)?[\[(]?` + timestamp + `[\])]?\s*(?:[-–—|:.]\s*)?(.+)$`)
+
+// tracklistTrailing matches a tracklist line ending with its timestamp:
+// "Artist - Song 03:45", "1. Song - 1:03:45".
+var tracklistTrailing = regexp.MustCompile(`^(?:\d{1,3}[.)]\s*)?(.+?)\s*[-–—|]?\s*[\[(]?` + timestamp + `[\])]?$`)
+
+// maxFirstStart is how far into the video, in seconds, the first entry of a
+// tracklist may start. Lists of track lengths, which also look like
+// timestamps, usually don't begin this early.
+const maxFirstStart = 60
+
+// ParseTracklist reads a timestamped tracklist from a video description.
+// It needs at least two timestamped lines in increasing order, the first
+// within the first minute; otherwise it returns nil. Each entry ends where
+// the next starts, and the last at duration.
+func ParseTracklist(description string, duration float64) []Chapter {
+	var chapters []Chapter
+	for _, line := range strings.Split(description, "\n") {
+		line = strings.TrimSpace(line)
+		m := tracklistLeading.FindStringSubmatch(line)
+		if m == nil {
+			if m = tracklistTrailing.FindStringSubmatch(line); m == nil {
+				continue
+			}
+			m[1], m[2] = m[2], m[1]
+		}
+		start, ok := parseTimestamp(m[1])
+		title := strings.Trim(m[2], " -–—|:")
+		if !ok || title == "" {
+			continue
+		}
+		if len(chapters) > 0 && start <= chapters[len(chapters)-1].StartTime {
+			return nil
+		}
+		chapters = append(chapters, Chapter{Title: title, StartTime: start})
+	}
+	if len(chapters) < 2 || chapters[0].StartTime > maxFirstStart {
+		return nil
+	}
+
+	for i := range chapters {
+		if i+1 < len(chapters) {
+			chapters[i].EndTime = chapters[i+1].StartTime
+		} else if duration > chapters[i].StartTime {
+			chapters[i].EndTime = duration
+		}
+	}
+	return chapters
+}
+
+// parseTimestamp converts [h:]mm:ss to seconds.
+func parseTimestamp(s string) (float64, bool) {
+	seconds := 0
+	for _, part := range strings.Split(s, ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, false
+		}
+		seconds = seconds*60 + n
+	}
+	return float64(seconds), true
+}
@@ -0,0 +1,71 @@
+package ytdlp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTracklist(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		duration    float64
+		want        []Chapter
+	}{
+		{
+			name: "leading timestamps",
+			description: `Summer mix 2024, enjoy!
+
+Tracklist:
+00:00 Daft Punk - One More Time
+5:20 Stardust - Music Sounds Better With You
+1:02:03 Modjo - Lady (Hear Me Tonight)
+
+Follow me on Instagram`,
+			duration: 4000,
+			want: []Chapter{
+				{Title: "Daft Punk - One More Time", StartTime: 0, EndTime: 320},
+				{Title: "Stardust - Music Sounds Better With You", StartTime: 320, EndTime: 3723},
+				{Title: "Modjo - Lady (Hear Me Tonight)", StartTime: 3723, EndTime: 4000},
+			},
+		},
+		{
+			name:        "numbered and bracketed",
+			description: "1. [00:00] Intro\n2. [03:15] - Creep",
+			want: []Chapter{
+				{Title: "Intro", StartTime: 0, EndTime: 195},
+				{Title: "Creep", StartTime: 195},
+			},
+		},
+		{
+			name:        "trailing timestamps",
+			description: "Airbag 0:00\nParanoid Android - 4:44",
+			duration:    700,
+			want: []Chapter{
+				{Title: "Airbag", StartTime: 0, EndTime: 284},
+				{Title: "Paranoid Android", StartTime: 284, EndTime: 700},
+			},
+		},
+		{
+			name:        "track lengths, not start times",
+			description: "Airbag 4:44\nParanoid Android 6:23",
+		},
+		{
+			name:        "out of order",
+			description: "00:00 One\n05:00 Two\n03:00 Three",
+		},
+		{
+			name:        "single timestamp",
+			description: "The drop at 2:45 is insane",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseTracklist(tt.description, tt.duration)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTracklist() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,14 +1,16 @@
 package ytdlp
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
-	"os/exec"
+	"regexp"
 	"strings"
+	"sync"
 )
 
 // Client defines the interface for fetching YouTube playlist data.
@@ -16,10 +18,28 @@ type Client interface {
 	GetPlaylist(ctx context.Context, playlistURL string) ([]PlaylistEntry, error)
 }
 
+// StreamClient is a Client that can also pass playlist entries on while
+// the playlist is still being fetched, so callers can start on the first
+// entries of a long playlist.
+type StreamClient interface {
+	Client
+	// StreamPlaylist calls fn with each entry, in playlist order, from the
+	// calling goroutine. It returns once the playlist is fetched.
+	StreamPlaylist(ctx context.Context, playlistURL string, fn func(PlaylistEntry)) error
+}
+
 // CommandClient implements Client by calling the yt-dlp binary.
 type CommandClient struct {
 	// BinaryPath is the path to the yt-dlp executable. Defaults to "yt-dlp".
 	BinaryPath string
+	// CookiesFile is a cookies.txt in Netscape format, exported from a
+	// browser signed in to YouTube, for private playlists and the
+	// account's own lists. Each run signs in with its own copy, so
+	// concurrent runs don't clobber the file, at the cost of the cookies
+	// yt-dlp refreshes never being written back: re-export them when
+	// YouTube stops accepting them. Empty for anonymous access.
+	// WithCookies overrides it per call.
+	CookiesFile string
 }
 
 // NewClient creates a new yt-dlp CommandClient.
@@ -29,101 +49,338 @@ func NewClient() *CommandClient {
 
 // GetPlaylist fetches all video entries from a YouTube playlist URL.
 func (c *CommandClient) GetPlaylist(ctx context.Context, playlistURL string) ([]PlaylistEntry, error) {
+	var entries []PlaylistEntry
+	err := c.StreamPlaylist(ctx, playlistURL, func(e PlaylistEntry) {
+		entries = append(entries, e)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// StreamPlaylist fetches the video entries of a YouTube playlist URL and
+// passes each one to fn, in playlist order, as soon as yt-dlp has printed
+// everything needed for it.
+func (c *CommandClient) StreamPlaylist(ctx context.Context, playlistURL string, fn func(PlaylistEntry)) error {
 	log.Printf("[ytdlp] fetching playlist: %s", playlistURL)
 	bin := c.BinaryPath
 	if bin == "" {
 		bin = "yt-dlp"
 	}
+	if name, ok := PersonalList(playlistURL); ok && c.cookies(ctx) == "" {
+		return fmt.Errorf("%w (%s is only visible to its account)", ErrCookiesRequired, name)
+	}
 
-	// For YouTube Music, use hybrid approach: flat first, then full metadata.
-	if isPlaylistURL(playlistURL) && isYouTubeMusicURL(playlistURL) {
-		return c.getYouTubeMusicPlaylist(ctx, bin, playlistURL)
+	n := 0
+	count := func(e PlaylistEntry) {
+		n++
+		fn(e)
 	}
 
-	args := []string{"--dump-json", "--no-warnings", "--ignore-errors"}
-	if isPlaylistURL(playlistURL) {
-		args = append(args, "--flat-playlist")
-	} else {
-		args = append(args, "--no-playlist")
+	var err error
+	switch {
+	case isPlaylistURL(playlistURL) && isYouTubeMusicURL(playlistURL):
+		// For YouTube Music, use hybrid approach: flat first, then full metadata.
+		err = c.streamYouTubeMusicPlaylist(ctx, bin, playlistURL, count)
+	case isPlaylistURL(playlistURL):
+		err = c.streamFlatPlaylist(ctx, bin, playlistURL, count)
+	default:
+		args := []string{"--dump-json", "--no-warnings", "--ignore-errors", "--no-playlist", playlistURL}
+		_, err = c.streamYtdlp(ctx, bin, args, playlistURL, count, nil)
 	}
-	args = append(args, playlistURL)
+	if err != nil {
+		return err
+	}
+	log.Printf("[ytdlp] fetched %d entries from playlist", n)
+	return nil
+}
 
-	entries, err := c.runYtdlp(ctx, bin, args, playlistURL)
+// streamFlatPlaylist lists a playlist flat, then fetches full metadata for
+// the entries whose description or chapters matter: auto-generated
+// uploads, recognised by their "Artist - Topic" channel, and videos long
+// enough to be mixes. Unavailable entries are fetched too, for yt-dlp's
+// reason. Entries that fail keep their flat data. Entries are passed on as
+// soon as they and every entry before them are complete.
+func (c *CommandClient) streamFlatPlaylist(ctx context.Context, bin, playlistURL string, fn func(PlaylistEntry)) error {
+	order := newOrderedEntries(fn)
+	detailArgs := []string{"--dump-json", "--no-warnings", "--ignore-errors", "--no-playlist"}
+	flatArgs := []string{"--dump-json", "--no-warnings", "--ignore-errors", "--flat-playlist", playlistURL}
+	_, err := c.streamYtdlp(ctx, bin, flatArgs, playlistURL, func(e PlaylistEntry) {
+		details := e.VideoID != "" && e.Description == "" &&
+			(strings.HasSuffix(e.Channel, " - Topic") || e.Duration >= MixMinDuration || e.Unavailable != "")
+		if details {
+			detailArgs = append(detailArgs, "https://www.youtube.com/watch?v="+e.VideoID)
+		}
+		order.add(e, details)
+	}, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	log.Printf("[ytdlp] fetched %d entries from playlist", len(entries))
-	return entries, nil
+
+	if waiting := order.waiting(); waiting > 0 {
+		n, _ := c.streamYtdlp(ctx, bin, detailArgs, "video details", order.complete, order.fail)
+		log.Printf("[ytdlp] fetched details of %d/%d auto-generated uploads, long and unavailable videos", n, waiting)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	order.finish()
+	return nil
 }
 
-// getYouTubeMusicPlaylist fetches YouTube Music playlists with full metadata.
-// First fetches flat playlist for complete list, then fetches full metadata.
+// streamYouTubeMusicPlaylist fetches YouTube Music playlists with full metadata.
+// First fetches flat playlist for complete list, then fetches full metadata,
+// passing entries on as it arrives.
 // Falls back to flat data for videos that fail full fetch.
-func (c *CommandClient) getYouTubeMusicPlaylist(ctx context.Context, bin, playlistURL string) ([]PlaylistEntry, error) {
+func (c *CommandClient) streamYouTubeMusicPlaylist(ctx context.Context, bin, playlistURL string, fn func(PlaylistEntry)) error {
 	// Step 1: Get flat playlist (fast, complete list).
 	flatArgs := []string{"--dump-json", "--no-warnings", "--flat-playlist", playlistURL}
 	flatEntries, err := c.runYtdlp(ctx, bin, flatArgs, playlistURL)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// Step 2: Get full metadata (slower, may fail for some videos).
+	order := newOrderedEntries(fn)
+	for _, e := range flatEntries {
+		order.add(e, e.VideoID != "")
+	}
 	fullArgs := []string{"--dump-json", "--no-warnings", "--ignore-errors", playlistURL}
-	fullEntries, _ := c.runYtdlp(ctx, bin, fullArgs, playlistURL)
-
-	// Build map of full entries by video ID.
-	fullMap := make(map[string]PlaylistEntry)
-	for _, e := range fullEntries {
-		if e.VideoID != "" {
-			fullMap[e.VideoID] = e
-		}
+	full, _ := c.streamYtdlp(ctx, bin, fullArgs, playlistURL, order.complete, order.fail)
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
 	// Merge: prefer full metadata, fallback to flat.
-	result := make([]PlaylistEntry, 0, len(flatEntries))
-	for _, flat := range flatEntries {
-		if full, ok := fullMap[flat.VideoID]; ok {
-			result = append(result, full)
-		} else {
-			result = append(result, flat)
+	order.finish()
+	log.Printf("[ytdlp] YouTube Music: %d entries (%d with full metadata)", len(flatEntries), full)
+	return nil
+}
+
+// orderedEntries holds the entries of a flat listing until their full
+// metadata arrives, and passes them on in playlist order.
+type orderedEntries struct {
+	entries []PlaylistEntry
+	pending []bool           // full metadata still expected
+	byID    map[string][]int // pending indexes by video ID
+	next    int              // first entry not passed on yet
+	fn      func(PlaylistEntry)
+}
+
+func newOrderedEntries(fn func(PlaylistEntry)) *orderedEntries {
+	return &orderedEntries{byID: make(map[string][]int), fn: fn}
+}
+
+// add appends a flat entry. If pending, it is held until complete or
+// finish.
+func (o *orderedEntries) add(e PlaylistEntry, pending bool) {
+	if pending {
+		o.byID[e.VideoID] = append(o.byID[e.VideoID], len(o.entries))
+	}
+	o.entries = append(o.entries, e)
+	o.pending = append(o.pending, pending)
+	o.flush()
+}
+
+// waiting returns how many entries are still pending.
+func (o *orderedEntries) waiting() int {
+	n := 0
+	for _, p := range o.pending[o.next:] {
+		if p {
+			n++
 		}
 	}
+	return n
+}
+
+// complete replaces a pending entry with its full metadata, keeping the
+// playlist title.
+func (o *orderedEntries) complete(full PlaylistEntry) {
+	i, ok := o.take(full.VideoID)
+	if !ok {
+		return
+	}
+	if full.PlaylistTitle == "" {
+		full.PlaylistTitle = o.entries[i].PlaylistTitle
+	}
+	o.entries[i] = full
+	o.pending[i] = false
+	o.flush()
+}
+
+// fail marks a pending entry yt-dlp reported an error for as done, with its
+// flat data. An entry listed as unavailable takes the error as its reason.
+// Errors arrive on stderr, apart from the entries on stdout, so they can
+// come after the metadata of later entries: those are held back until the
+// entries before them completed or failed, or until finish.
+func (o *orderedEntries) fail(videoID, reason string) {
+	i, ok := o.take(videoID)
+	if !ok {
+		return
+	}
+	if o.entries[i].Unavailable != "" {
+		o.entries[i].Unavailable = reason
+	} else {
+		log.Printf("[ytdlp] could not fetch details of %s, using the listing: %s", videoID, reason)
+	}
+	o.pending[i] = false
+	o.flush()
+}
+
+// take returns the index of the first pending entry for a video ID, if one
+// is still held.
+func (o *orderedEntries) take(videoID string) (int, bool) {
+	indexes := o.byID[videoID]
+	if len(indexes) == 0 {
+		return 0, false
+	}
+	o.byID[videoID] = indexes[1:]
+	return indexes[0], indexes[0] >= o.next
+}
+
+// finish passes on the remaining entries, with flat data for those whose
+// full metadata never came.
+func (o *orderedEntries) finish() {
+	for j := o.next; j < len(o.pending); j++ {
+		o.pending[j] = false
+	}
+	o.flush()
+}
 
-	log.Printf("[ytdlp] YouTube Music: %d entries (%d with full metadata)", len(result), len(fullEntries))
-	return result, nil
+// flush passes on the entries that are complete, up to the first pending
+// one.
+func (o *orderedEntries) flush() {
+	for o.next < len(o.entries) && !o.pending[o.next] {
+		o.fn(o.entries[o.next])
+		o.entries[o.next] = PlaylistEntry{}
+		o.next++
+	}
 }
 
 // runYtdlp executes yt-dlp and parses the JSON output.
 func (c *CommandClient) runYtdlp(ctx context.Context, bin string, args []string, url string) ([]PlaylistEntry, error) {
-	cmd := exec.CommandContext(ctx, bin, args...)
+	var entries []PlaylistEntry
+	_, err := c.streamYtdlp(ctx, bin, args, url, func(e PlaylistEntry) {
+		entries = append(entries, e)
+	}, nil)
+	return entries, err
+}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+// unavailableTitle matches the placeholder titles playlists list private,
+// deleted and otherwise unavailable videos under.
+var unavailableTitle = regexp.MustCompile(`^\[(Private|Deleted|Unavailable) video\]$`)
 
-	runErr := cmd.Run()
+// videoError matches the error yt-dlp prints for a video it could not
+// fetch: "ERROR: [youtube] dQw4w9WgXcQ: Private video. Sign in if ...".
+var videoError = regexp.MustCompile(`^ERROR: \[[^\]]+\] ([\w-]+): (.+)$`)
+
+// ytdlpLine is a line of yt-dlp's output, from stdout or stderr.
+type ytdlpLine struct {
+	text   []byte
+	stderr bool
+}
+
+// streamYtdlp executes yt-dlp and calls fn with each entry as soon as
+// yt-dlp prints it, one JSON object per line. With --ignore-errors, yt-dlp
+// reports the videos it could not fetch on stderr instead; failed, if not
+// nil, is called with each one's ID and error message. Both callbacks run
+// on the calling goroutine. Returns how many entries it passed on.
+func (c *CommandClient) streamYtdlp(ctx context.Context, bin string, args []string, url string, fn func(PlaylistEntry), failed func(videoID, reason string)) (int, error) {
+	cmd, done, err := c.command(ctx, bin, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer done()
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, fmt.Errorf("yt-dlp failed: %w", err)
+	}
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		return 0, fmt.Errorf("yt-dlp failed: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		log.Printf("[ytdlp] command failed for %s: %v", url, err)
+		return 0, fmt.Errorf("yt-dlp failed: %w", err)
+	}
+
+	lines := make(chan ytdlpLine)
+	var stderr bytes.Buffer
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		readLines(stdout, func(line []byte) { lines <- ytdlpLine{text: line} })
+	}()
+	go func() {
+		defer wg.Done()
+		readLines(stderrPipe, func(line []byte) {
+			stderr.Write(line)
+			lines <- ytdlpLine{text: line, stderr: true}
+		})
+	}()
+	go func() {
+		wg.Wait()
+		close(lines)
+	}()
 
 	// Parse output even if command failed (some videos may have succeeded).
-	var entries []PlaylistEntry
-	dec := json.NewDecoder(&stdout)
-	for {
+	n, skipped := 0, 0
+	for line := range lines {
+		text := bytes.TrimSpace(line.text)
+		if line.stderr {
+			if m := videoError.FindSubmatch(text); m != nil && failed != nil {
+				failed(string(m[1]), string(m[2]))
+			}
+			continue
+		}
+		if len(text) == 0 {
+			continue
+		}
 		var entry PlaylistEntry
-		if err := dec.Decode(&entry); err == io.EOF {
-			break
-		} else if err != nil {
-			// Skip malformed entries, continue parsing.
+		if err := json.Unmarshal(text, &entry); err != nil {
+			skipped++
 			continue
 		}
-		entries = append(entries, entry)
+		entry.Metadata = ParseDescription(entry.Description)
+		if len(entry.Chapters) == 0 {
+			entry.Chapters = ParseTracklist(entry.Description, entry.Duration)
+		}
+		if unavailableTitle.MatchString(entry.Title) {
+			entry.Unavailable = strings.Trim(entry.Title, "[]")
+		}
+		n++
+		fn(entry)
+	}
+	runErr := cmd.Wait()
+	if skipped > 0 {
+		log.Printf("[ytdlp] skipped %d lines of output that are not JSON for %s", skipped, url)
 	}
 
 	// Only fail if command failed AND we got no entries.
-	if runErr != nil && len(entries) == 0 {
+	if runErr != nil && n == 0 {
 		log.Printf("[ytdlp] command failed for %s: %v", url, runErr)
-		return nil, fmt.Errorf("yt-dlp failed: %w: %s", runErr, stderr.String())
+		return 0, fetchError(url, c.cookies(ctx), runErr, stderr.String())
 	}
 
-	return entries, nil
+	return n, nil
+}
+
+// readLines calls fn with each line read from r, including its newline.
+// Lines of full metadata can be megabytes long, too long for a Scanner.
+func readLines(r io.Reader, fn func([]byte)) {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			fn(line)
+		}
+		if err != nil {
+			return
+		}
+	}
 }
 
 func isPlaylistURL(url string) bool {
@@ -146,7 +403,11 @@ func (c *CommandClient) GetChannelPlaylists(ctx context.Context, channelURL stri
 	url := normalizeChannelURL(channelURL)
 
 	args := []string{"--flat-playlist", "--dump-json", "--no-warnings", url}
-	cmd := exec.CommandContext(ctx, bin, args...)
+	cmd, done, err := c.command(ctx, bin, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -154,7 +415,7 @@ func (c *CommandClient) GetChannelPlaylists(ctx context.Context, channelURL stri
 
 	if err := cmd.Run(); err != nil {
 		log.Printf("[ytdlp] command failed for channel %s: %v", channelURL, err)
-		return nil, fmt.Errorf("yt-dlp failed: %w: %s", err, stderr.String())
+		return nil, fetchError(channelURL, c.cookies(ctx), err, stderr.String())
 	}
 
 	var playlists []ChannelPlaylist
@@ -194,7 +455,11 @@ func (c *CommandClient) GetURLInfo(ctx context.Context, url string) (string, err
 	}
 
 	args := []string{"--dump-single-json", "--flat-playlist", "--no-warnings", url}
-	cmd := exec.CommandContext(ctx, bin, args...)
+	cmd, done, err := c.command(ctx, bin, args...)
+	if err != nil {
+		return "", err
+	}
+	defer done()
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -202,7 +467,7 @@ func (c *CommandClient) GetURLInfo(ctx context.Context, url string) (string, err
 
 	if err := cmd.Run(); err != nil {
 		log.Printf("[ytdlp] command failed for URL info %s: %v", url, err)
-		return "", fmt.Errorf("yt-dlp failed: %w: %s", err, stderr.String())
+		return "", fetchError(url, c.cookies(ctx), err, stderr.String())
 	}
 
 	var info struct {
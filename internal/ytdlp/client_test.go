@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -13,9 +14,9 @@ func TestGetPlaylist(t *testing.T) {
 	fakeBin := filepath.Join(tmpDir, "yt-dlp")
 
 	script := `#!/bin/sh
-echo '{"title":"Arctic Monkeys - Do I Wanna Know?","id":"bpOSxM0rNPM","url":"https://www.youtube.com/watch?v=bpOSxM0rNPM"}'
+echo '{"title":"Arctic Monkeys - Do I Wanna Know?","id":"bpOSxM0rNPM","url":"https://www.youtube.com/watch?v=bpOSxM0rNPM","duration":272.0}'
 echo '{"title":"Tame Impala - The Less I Know The Better","id":"sBzrzS1Ag_g","url":"https://www.youtube.com/watch?v=sBzrzS1Ag_g"}'
-echo '{"title":"Radiohead - Creep","id":"XFkzRNyygfk","url":"https://www.youtube.com/watch?v=XFkzRNyygfk"}'
+echo '{"title":"Radiohead - Creep","id":"XFkzRNyygfk","url":"https://www.youtube.com/watch?v=XFkzRNyygfk","duration":null}'
 `
 	if err := os.WriteFile(fakeBin, []byte(script), 0755); err != nil {
 		t.Fatal(err)
@@ -32,13 +33,13 @@ echo '{"title":"Radiohead - Creep","id":"XFkzRNyygfk","url":"https://www.youtube
 	}
 
 	want := []PlaylistEntry{
-		{Title: "Arctic Monkeys - Do I Wanna Know?", VideoID: "bpOSxM0rNPM", URL: "https://www.youtube.com/watch?v=bpOSxM0rNPM"},
+		{Title: "Arctic Monkeys - Do I Wanna Know?", VideoID: "bpOSxM0rNPM", URL: "https://www.youtube.com/watch?v=bpOSxM0rNPM", Duration: 272},
 		{Title: "Tame Impala - The Less I Know The Better", VideoID: "sBzrzS1Ag_g", URL: "https://www.youtube.com/watch?v=sBzrzS1Ag_g"},
 		{Title: "Radiohead - Creep", VideoID: "XFkzRNyygfk", URL: "https://www.youtube.com/watch?v=XFkzRNyygfk"},
 	}
 
 	for i, entry := range entries {
-		if entry != want[i] {
+		if !reflect.DeepEqual(entry, want[i]) {
 			t.Errorf("entry[%d] = %+v, want %+v", i, entry, want[i])
 		}
 	}
@@ -94,3 +95,209 @@ func TestGetPlaylistContextCanceled(t *testing.T) {
 		t.Fatal("expected error for canceled context, got nil")
 	}
 }
+
+func TestGetPlaylistTopicDescriptions(t *testing.T) {
+	tmpDir := t.TempDir()
+	fakeBin := filepath.Join(tmpDir, "yt-dlp")
+
+	// The flat listing has no descriptions; the auto-generated upload is
+	// fetched again on its own.
+	script := `#!/bin/sh
+case "$*" in
+*--flat-playlist*)
+	echo '{"title":"Creep","id":"topic1","channel":"Radiohead - Topic","playlist_title":"Mix"}'
+	echo '{"title":"Radiohead - Karma Police","id":"video2","channel":"Radiohead","playlist_title":"Mix"}'
+	;;
+*--no-playlist*watch?v=topic1)
+	printf '%s\n' '{"title":"Creep","id":"topic1","channel":"Radiohead - Topic","description":"Provided to YouTube by XL\n\nCreep · Radiohead\n\nPablo Honey\n\nISRC: GBAYE9200070"}'
+	;;
+*)
+	exit 1
+	;;
+esac
+`
+	if err := os.WriteFile(fakeBin, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &CommandClient{BinaryPath: fakeBin}
+	entries, err := client.GetPlaylist(context.Background(), "https://www.youtube.com/playlist?list=test")
+	if err != nil {
+		t.Fatalf("GetPlaylist() error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+
+	topic := entries[0]
+	if topic.Metadata == nil || topic.Metadata.ISRC != "GBAYE9200070" || topic.Metadata.Album != "Pablo Honey" {
+		t.Errorf("entry[0] metadata = %+v, want the description's album and ISRC", topic.Metadata)
+	}
+	if topic.PlaylistTitle != "Mix" {
+		t.Errorf("entry[0] playlist title = %q, want it kept from the flat listing", topic.PlaylistTitle)
+	}
+	if entries[1].Metadata != nil || entries[1].VideoID != "video2" {
+		t.Errorf("entry[1] = %+v, want the flat entry without metadata", entries[1])
+	}
+}
+
+func TestStreamPlaylist(t *testing.T) {
+	tmpDir := t.TempDir()
+	fakeBin := filepath.Join(tmpDir, "yt-dlp")
+	signal := filepath.Join(tmpDir, "first-entry-seen")
+
+	// The second entry is only printed once the first has been handled.
+	script := `#!/bin/sh
+echo '{"title":"Radiohead - Creep","id":"one"}'
+for i in $(seq 50); do
+	[ -f ` + signal + ` ] && break
+	sleep 0.1
+done
+echo '{"title":"Muse - Uprising","id":"two"}'
+`
+	if err := os.WriteFile(fakeBin, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &CommandClient{BinaryPath: fakeBin}
+	var ids []string
+	err := client.StreamPlaylist(context.Background(), "https://www.youtube.com/playlist?list=test", func(e PlaylistEntry) {
+		if len(ids) == 0 {
+			os.WriteFile(signal, nil, 0644)
+		}
+		ids = append(ids, e.VideoID)
+	})
+	if err != nil {
+		t.Fatalf("StreamPlaylist() error = %v", err)
+	}
+	if !reflect.DeepEqual(ids, []string{"one", "two"}) {
+		t.Errorf("ids = %v, want one, two", ids)
+	}
+	if _, err := os.Stat(signal); err != nil {
+		t.Error("first entry was not passed on before yt-dlp finished")
+	}
+}
+
+func TestStreamYouTubeMusicPlaylist(t *testing.T) {
+	tmpDir := t.TempDir()
+	fakeBin := filepath.Join(tmpDir, "yt-dlp")
+
+	// The full pass fails for the second video.
+	script := `#!/bin/sh
+case "$*" in
+*--flat-playlist*)
+	echo '{"title":"Creep","id":"a"}'
+	echo '{"title":"Uprising","id":"b"}'
+	echo '{"title":"Song 2","id":"c"}'
+	;;
+*)
+	echo '{"title":"Creep","id":"a","artist":"Radiohead"}'
+	echo '{"title":"Song 2","id":"c","artist":"Blur"}'
+	echo "ERROR: [youtube] b: Video unavailable" >&2
+	exit 1
+	;;
+esac
+`
+	if err := os.WriteFile(fakeBin, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &CommandClient{BinaryPath: fakeBin}
+	entries, err := client.GetPlaylist(context.Background(), "https://music.youtube.com/playlist?list=test")
+	if err != nil {
+		t.Fatalf("GetPlaylist() error = %v", err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.VideoID+":"+e.Artist)
+	}
+	if want := []string{"a:Radiohead", "b:", "c:Blur"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %v, want %v", got, want)
+	}
+}
+
+func TestGetPlaylistUnavailable(t *testing.T) {
+	tmpDir := t.TempDir()
+	fakeBin := filepath.Join(tmpDir, "yt-dlp")
+
+	// The private video's error comes from fetching it on its own; the
+	// deleted one fails without a message.
+	script := `#!/bin/sh
+case "$*" in
+*--flat-playlist*)
+	echo '{"title":"Radiohead - Creep","id":"one"}'
+	echo '{"title":"[Private video]","id":"private1"}'
+	echo 'not json'
+	echo '{"title":"[Deleted video]","id":"deleted1"}'
+	echo '{"title":"Muse - Uprising","id":"two"}'
+	;;
+*)
+	echo "ERROR: [youtube] private1: Private video. Sign in if you've been granted access to this video" >&2
+	exit 1
+	;;
+esac
+`
+	if err := os.WriteFile(fakeBin, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &CommandClient{BinaryPath: fakeBin}
+	entries, err := client.GetPlaylist(context.Background(), "https://www.youtube.com/playlist?list=test")
+	if err != nil {
+		t.Fatalf("GetPlaylist() error = %v", err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.VideoID+":"+e.Unavailable)
+	}
+	want := []string{
+		"one:",
+		"private1:Private video. Sign in if you've been granted access to this video",
+		"deleted1:Deleted video",
+		"two:",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %q, want %q", got, want)
+	}
+}
+
+func TestGetPlaylistLateError(t *testing.T) {
+	tmpDir := t.TempDir()
+	fakeBin := filepath.Join(tmpDir, "yt-dlp")
+
+	// The private video's error is read after the next video's metadata.
+	script := `#!/bin/sh
+case "$*" in
+*--flat-playlist*)
+	echo '{"title":"[Private video]","id":"private1"}'
+	echo '{"title":"Uprising","id":"two","channel":"Muse - Topic"}'
+	;;
+*)
+	echo '{"title":"Uprising","id":"two","artist":"Muse"}'
+	sleep 0.2
+	echo "ERROR: [youtube] private1: Private video. Sign in if you've been granted access to this video" >&2
+	exit 1
+	;;
+esac
+`
+	if err := os.WriteFile(fakeBin, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &CommandClient{BinaryPath: fakeBin}
+	entries, err := client.GetPlaylist(context.Background(), "https://www.youtube.com/playlist?list=test")
+	if err != nil {
+		t.Fatalf("GetPlaylist() error = %v", err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.VideoID+":"+e.Artist+":"+e.Unavailable)
+	}
+	want := []string{
+		"private1::Private video. Sign in if you've been granted access to this video",
+		"two:Muse:",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,128 @@
+package ytdlp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrCookiesRequired is returned for a personal list or a video that
+	// needs a signed-in account when no cookies file is configured.
+	ErrCookiesRequired = errors.New("YouTube requires signing in: configure a cookies file")
+	// ErrCookiesExpired is returned when YouTube rejects the configured
+	// cookies, because they expired or the browser rotated them since they
+	// were exported.
+	ErrCookiesExpired = errors.New("YouTube rejected the cookies, they have probably expired: export them again")
+)
+
+// personalLists are the list IDs YouTube gives each account's own lists.
+// They only exist for a signed-in account.
+var personalLists = map[string]string{
+	"LL": "Liked videos",
+	"LM": "Liked music",
+	"WL": "Watch later",
+}
+
+// PersonalList reports whether a URL points to one of the signed-in
+// account's own lists, such as Liked videos, and returns that list's name.
+func PersonalList(playlistURL string) (string, bool) {
+	u, err := url.Parse(playlistURL)
+	if err != nil {
+		return "", false
+	}
+	name, ok := personalLists[u.Query().Get("list")]
+	return name, ok
+}
+
+type cookiesKey struct{}
+
+// WithCookies returns a context that makes CommandClient use the cookies
+// file at path, in Netscape format, instead of its CookiesFile. The
+// pipeline passes the context on to every fetch of a session, so one
+// analyze request can use its own account.
+func WithCookies(ctx context.Context, path string) context.Context {
+	return context.WithValue(ctx, cookiesKey{}, path)
+}
+
+// HasCookies reports whether ctx carries a cookies file from WithCookies.
+func HasCookies(ctx context.Context) bool {
+	path, ok := ctx.Value(cookiesKey{}).(string)
+	return ok && path != ""
+}
+
+// cookies returns the cookies file for a call: the context's, if any, or
+// the client's. Empty for anonymous access.
+func (c *CommandClient) cookies(ctx context.Context) string {
+	if path, ok := ctx.Value(cookiesKey{}).(string); ok && path != "" {
+		return path
+	}
+	return c.CookiesFile
+}
+
+// command builds a yt-dlp command, signed in with the call's cookies file
+// if there is one. yt-dlp writes the cookies back when it exits, so each
+// run gets its own copy of the file, which done removes once the command
+// has finished.
+func (c *CommandClient) command(ctx context.Context, bin string, args ...string) (cmd *exec.Cmd, done func(), err error) {
+	done = func() {}
+	if cookies := c.cookies(ctx); cookies != "" {
+		path, err := copyCookies(cookies)
+		if err != nil {
+			return nil, done, fmt.Errorf("copying cookies: %w", err)
+		}
+		done = func() { os.Remove(path) }
+		args = append([]string{"--cookies", path}, args...)
+	}
+	return exec.CommandContext(ctx, bin, args...), done, nil
+}
+
+// copyCookies copies a cookies file to a new temporary file, only readable
+// by the server's user.
+func copyCookies(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp("", "ytdlp-cookies-*.txt")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// authFailure matches yt-dlp errors that mean YouTube wants a signed-in
+// account, or no longer accepts the one the cookies belong to.
+var authFailure = regexp.MustCompile(`(?i)\bsign in\b|cookies are no longer valid|login required|this playlist is private`)
+
+// fetchError describes a failed yt-dlp run. If stderr shows YouTube asked
+// for an account, it wraps ErrCookiesRequired or, when cookies were sent,
+// ErrCookiesExpired. Signed out, a personal list "does not exist".
+func fetchError(playlistURL, cookies string, runErr error, stderr string) error {
+	_, personal := PersonalList(playlistURL)
+	auth := authFailure.MatchString(stderr) || (personal && strings.Contains(stderr, "does not exist"))
+	if !auth {
+		return fmt.Errorf("yt-dlp failed: %w: %s", runErr, stderr)
+	}
+	reason := strings.TrimSpace(stderr)
+	if i := strings.LastIndex(reason, "ERROR: "); i >= 0 {
+		reason, _, _ = strings.Cut(reason[i+len("ERROR: "):], "\n")
+	}
+	if cookies == "" {
+		return fmt.Errorf("%w (%s)", ErrCookiesRequired, reason)
+	}
+	return fmt.Errorf("%w (%s)", ErrCookiesExpired, reason)
+}
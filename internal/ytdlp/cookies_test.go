@@ -0,0 +1,115 @@
+package ytdlp
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPersonalList(t *testing.T) {
+	tests := []struct {
+		url  string
+		name string
+	}{
+		{"https://www.youtube.com/playlist?list=LL", "Liked videos"},
+		{"https://music.youtube.com/playlist?list=LM", "Liked music"},
+		{"https://www.youtube.com/playlist?list=WL", "Watch later"},
+		{"https://www.youtube.com/watch?v=abc&list=WL", "Watch later"},
+		{"https://www.youtube.com/playlist?list=PLxyz", ""},
+		{"https://www.youtube.com/watch?v=LL", ""},
+	}
+	for _, tt := range tests {
+		name, ok := PersonalList(tt.url)
+		if name != tt.name || ok != (tt.name != "") {
+			t.Errorf("PersonalList(%q) = %q, %v, want %q", tt.url, name, ok, tt.name)
+		}
+	}
+}
+
+// writeCookiesScript writes a fake yt-dlp that prints an entry titled
+// after the content of the cookies file it was given, and fails like a
+// signed-out request for the Liked videos list without one.
+func writeCookiesScript(t *testing.T) string {
+	t.Helper()
+	fakeBin := filepath.Join(t.TempDir(), "yt-dlp")
+	script := `#!/bin/sh
+if [ "$1" = "--cookies" ]; then
+	case "$*" in
+	*list=LL*)
+		echo "ERROR: [youtube:tab] LL: The playlist does not exist." >&2
+		exit 1
+		;;
+	esac
+	echo "{\"title\":\"$(cat "$2")\",\"id\":\"one\"}"
+	exit 0
+fi
+echo "ERROR: [youtube] one: Sign in to confirm you're not a bot" >&2
+exit 1
+`
+	if err := os.WriteFile(fakeBin, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return fakeBin
+}
+
+// writeCookies writes a cookies file holding content.
+func writeCookies(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCookies(t *testing.T) {
+	fakeBin := writeCookiesScript(t)
+	videoURL := "https://www.youtube.com/watch?v=one"
+
+	client := &CommandClient{BinaryPath: fakeBin, CookiesFile: writeCookies(t, "server")}
+	entries, err := client.GetPlaylist(context.Background(), videoURL)
+	if err != nil || len(entries) != 1 || entries[0].Title != "server" {
+		t.Fatalf("GetPlaylist() = %+v, %v, want the server's cookies used", entries, err)
+	}
+
+	ctx := WithCookies(context.Background(), writeCookies(t, "request"))
+	entries, err = client.GetPlaylist(ctx, videoURL)
+	if err != nil || len(entries) != 1 || entries[0].Title != "request" {
+		t.Fatalf("GetPlaylist() = %+v, %v, want the request's cookies used", entries, err)
+	}
+
+	// Each run signs in with its own copy, removed afterwards.
+	copies, _ := filepath.Glob(filepath.Join(os.TempDir(), "ytdlp-cookies-*.txt"))
+	for _, path := range copies {
+		if data, _ := os.ReadFile(path); string(data) == "server" || string(data) == "request" {
+			t.Errorf("copy %s left behind", path)
+		}
+	}
+}
+
+func TestCookiesErrors(t *testing.T) {
+	fakeBin := writeCookiesScript(t)
+	anonymous := &CommandClient{BinaryPath: fakeBin}
+	signedIn := &CommandClient{BinaryPath: fakeBin, CookiesFile: writeCookies(t, "expired")}
+
+	tests := []struct {
+		name   string
+		client *CommandClient
+		url    string
+		want   error
+	}{
+		{"personal list without cookies", anonymous, "https://www.youtube.com/playlist?list=LL", ErrCookiesRequired},
+		{"sign-in wall without cookies", anonymous, "https://www.youtube.com/watch?v=one", ErrCookiesRequired},
+		{"personal list with expired cookies", signedIn, "https://www.youtube.com/playlist?list=LL", ErrCookiesExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.client.GetPlaylist(context.Background(), tt.url)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("GetPlaylist() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package ytdlp
+
+import (
+	"regexp"
+	"strings"
+)
+
+// TrackMetadata is what the machine-written description of an
+// auto-generated upload states about the track. Labels and distributors
+// upload these to "Artist - Topic" channels; the description starts with
+// "Provided to YouTube by" and looks like:
+//
+//	Provided to YouTube by Universal Music Group
+//
+//	Sunflower · Post Malone · Swae Lee
+//
+//	Hollywood's Bleeding
+//
+//	℗ 2019 Republic Records
+//
+//	Released on: 2019-09-06
+//
+//	ISRC: USUM71814888
+type TrackMetadata struct {
+	Title string `json:"title"`
+	// Artists are every credited artist, main and featured, in order.
+	Artists  []string `json:"artists"`
+	Album    string   `json:"album,omitempty"`
+	Label    string   `json:"label,omitempty"`
+	Released string   `json:"released,omitempty"` // YYYY-MM-DD
+	// ISRC is the recording's International Standard Recording Code,
+	// upper case without dashes. Not every description has one.
+	ISRC string `json:"isrc,omitempty"`
+}
+
+const providedPrefix = "Provided to YouTube by"
+
+// isrcPattern matches a normalized ISRC: country, registrant, year and
+// designation code.
+var isrcPattern = regexp.MustCompile(`^[A-Z]{2}[A-Z0-9]{3}\d{7}$`)
+
+// ParseDescription reads the metadata block of an auto-generated upload's
+// description. Returns nil if the description has none.
+func ParseDescription(description string) *TrackMetadata {
+	var lines []string
+	for _, line := range strings.Split(description, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	start := -1
+	for i, line := range lines {
+		if strings.HasPrefix(line, providedPrefix) {
+			start = i
+			break
+		}
+	}
+	// The block needs the "Title · Artist" line after the header.
+	if start < 0 || start+1 >= len(lines) || !strings.Contains(lines[start+1], " · ") {
+		return nil
+	}
+
+	credits := strings.Split(lines[start+1], " · ")
+	m := &TrackMetadata{
+		Title: strings.TrimSpace(credits[0]),
+		Label: strings.TrimSpace(strings.TrimPrefix(lines[start], providedPrefix)),
+	}
+	for _, artist := range credits[1:] {
+		if artist = strings.TrimSpace(artist); artist != "" {
+			m.Artists = append(m.Artists, artist)
+		}
+	}
+	if m.Title == "" || len(m.Artists) == 0 {
+		return nil
+	}
+
+	// The album follows, unless the release has none and the next line is
+	// already a copyright or a field.
+	rest := lines[start+2:]
+	if len(rest) > 0 && !isDescriptionField(rest[0]) {
+		m.Album = rest[0]
+	}
+	for _, line := range rest {
+		if value, ok := strings.CutPrefix(line, "Released on:"); ok {
+			m.Released = strings.TrimSpace(value)
+		}
+		if value, ok := strings.CutPrefix(line, "ISRC:"); ok {
+			isrc := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(value), "-", ""))
+			if isrcPattern.MatchString(isrc) {
+				m.ISRC = isrc
+			}
+		}
+	}
+	return m
+}
+
+// descriptionFields start the lines that can follow the credits when the
+// release has no album title.
+var descriptionFields = []string{"℗", "©", "Released on:", "ISRC:", "Auto-generated by YouTube."}
+
+// isDescriptionField reports whether line is one of the block's labelled
+// lines rather than an album title.
+func isDescriptionField(line string) bool {
+	for _, prefix := range descriptionFields {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package ytdlp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDescription(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		want        *TrackMetadata
+	}{
+		{
+			name: "full block",
+			description: `Provided to YouTube by Universal Music Group
+
+Sunflower (Spider-Man: Into the Spider-Verse) · Post Malone · Swae Lee
+
+Hollywood's Bleeding
+
+℗ 2019 Republic Records
+
+Released on: 2019-09-06
+
+Producer: Louis Bell
+Composer  Lyricist: Austin Post
+
+ISRC: USUM7-18-14888
+
+Auto-generated by YouTube.`,
+			want: &TrackMetadata{
+				Title:    "Sunflower (Spider-Man: Into the Spider-Verse)",
+				Artists:  []string{"Post Malone", "Swae Lee"},
+				Album:    "Hollywood's Bleeding",
+				Label:    "Universal Music Group",
+				Released: "2019-09-06",
+				ISRC:     "USUM71814888",
+			},
+		},
+		{
+			name: "no album or ISRC",
+			description: `Provided to YouTube by DistroKid
+
+Creep · Radiohead
+
+℗ 1992 XL Recordings
+
+Released on: 1992-09-21`,
+			want: &TrackMetadata{
+				Title:    "Creep",
+				Artists:  []string{"Radiohead"},
+				Label:    "DistroKid",
+				Released: "1992-09-21",
+			},
+		},
+		{
+			name: "invalid ISRC",
+			description: `Provided to YouTube by CDBaby
+
+Song · Artist
+
+ISRC: not-an-isrc`,
+			want: &TrackMetadata{Title: "Song", Artists: []string{"Artist"}, Label: "CDBaby"},
+		},
+		{
+			name:        "ordinary description",
+			description: "Official video for Creep.\nSubscribe for more!",
+		},
+		{
+			name:        "header without credits",
+			description: "Provided to YouTube by Sony Music\n\nThanks for listening",
+		},
+		{name: "empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseDescription(tt.description)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDescription() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -9,6 +9,26 @@ type PlaylistEntry struct {
 	Artist  string `json:"artist,omitempty"`
 	Track   string `json:"track,omitempty"`
 	Channel string `json:"channel,omitempty"`
+	// PlaylistTitle is the title of the playlist the entry was listed in.
+	PlaylistTitle string `json:"playlist_title,omitempty"`
+	// Duration is the video length in seconds. Zero when yt-dlp doesn't report it.
+	Duration float64 `json:"duration,omitempty"`
+	// Description is the video description. Flat playlist listings leave it
+	// out, except for auto-generated uploads and long videos, which are
+	// fetched in full.
+	Description string `json:"description,omitempty"`
+	// Chapters are the video's chapters or, without any, the timestamped
+	// tracklist in its description. Like Description, they are missing
+	// from flat listings of short videos.
+	Chapters []Chapter `json:"chapters,omitempty"`
+	// Metadata is read from Description when it is the "Provided to YouTube
+	// by" block of an auto-generated upload, and nil otherwise.
+	Metadata *TrackMetadata `json:"-"`
+	// Unavailable is why yt-dlp could not fetch a private, deleted or
+	// otherwise unavailable video that the playlist still lists: its error
+	// message, or the placeholder title the listing gives. Empty for
+	// every other entry. Such entries have no usable title.
+	Unavailable string `json:"-"`
 }
 
 // ChannelPlaylist represents a playlist found on a YouTube channel.
@@ -3,16 +3,22 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gndm/ytToDeemix/internal/deemix"
 	"github.com/gndm/ytToDeemix/internal/navidrome"
+	"github.com/gndm/ytToDeemix/internal/ratelimit"
 	"github.com/gndm/ytToDeemix/internal/sync"
 	"github.com/gndm/ytToDeemix/internal/ytdlp"
 )
@@ -21,10 +27,109 @@ var version = "dev"
 var startTime = time.Now()
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "sync" {
+		os.Exit(runSync(os.Args[2:]))
+	}
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
+
+	pipeline, c := newPipeline()
+	navidromeSkipDefault := os.Getenv("NAVIDROME_SKIP_DEFAULT") == "true"
+
+	// Optional session and watch persistence.
+	var watchStore sync.WatchStore
+	if dataDir := os.Getenv("DATA_DIR"); dataDir != "" {
+		store, err := sync.NewFileStore(filepath.Join(dataDir, "sessions"))
+		if err != nil {
+			log.Fatalf("Session store: %v", err)
+		}
+		// Sessions used to be stored in DATA_DIR itself.
+		if n, err := store.Migrate(dataDir); err != nil {
+			log.Printf("WARNING: failed to move sessions to %s: %v", store.Dir, err)
+		} else if n > 0 {
+			log.Printf("Moved %d sessions to %s", n, store.Dir)
+		}
+		pipeline.SetStore(store)
+		if _, err := pipeline.Restore(); err != nil {
+			log.Printf("WARNING: failed to restore sessions: %v", err)
+		}
+		watchStore = &sync.FileWatchStore{Path: filepath.Join(dataDir, "watches.json")}
+		log.Printf("Persisting sessions to %s", dataDir)
+	}
+
+	watcher := sync.NewWatcher(pipeline, watchStore)
+	if err := watcher.Load(); err != nil {
+		log.Printf("WARNING: failed to restore watches: %v", err)
+	}
+	go watcher.Run(context.Background())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/analyze", handleAnalyze(pipeline, c.yt.CookiesFile != ""))
+	mux.HandleFunc("GET /api/sessions", handleListSessions(pipeline))
+	mux.HandleFunc("GET /api/session/{id}", handleGetSession(pipeline))
+	mux.HandleFunc("GET /api/session/{id}/events", handleSessionEvents(pipeline))
+	mux.HandleFunc("GET /api/session/{id}/export", handleExport(pipeline))
+	mux.HandleFunc("DELETE /api/session/{id}", handleDeleteSession(pipeline))
+	mux.HandleFunc("POST /api/session/{id}/download", handleDownload(pipeline))
+	mux.HandleFunc("POST /api/session/{id}/pause", handlePause(pipeline))
+	mux.HandleFunc("POST /api/session/{id}/resume", handleResume(pipeline))
+	mux.HandleFunc("POST /api/session/{id}/cancel", handleCancel(pipeline))
+	mux.HandleFunc("POST /api/session/{id}/playlist", handleSyncPlaylist(pipeline))
+	mux.HandleFunc("POST /api/session/{id}/priority", handleSetPriority(pipeline))
+	mux.HandleFunc("POST /api/session/{id}/track/{index}/select", handleSelectTrack(pipeline))
+	mux.HandleFunc("POST /api/session/{id}/track/{index}/search", handleSearchTrack(pipeline))
+	mux.HandleFunc("POST /api/session/{id}/track/{index}/candidates", handleTrackCandidates(pipeline))
+	mux.HandleFunc("POST /api/session/{id}/track/{index}/match", handleSetTrackMatch(pipeline))
+	mux.HandleFunc("GET /api/watches", handleListWatches(watcher))
+	mux.HandleFunc("POST /api/watches", handleAddWatch(watcher))
+	mux.HandleFunc("DELETE /api/watch/{id}", handleRemoveWatch(watcher))
+	mux.HandleFunc("POST /api/watch/{id}/run", handleRunWatch(watcher))
+	mux.HandleFunc("GET /api/channel/playlists", handleChannelPlaylists(c.yt))
+	mux.HandleFunc("GET /api/url/info", handleURLInfo(c.yt))
+	mux.HandleFunc("GET /api/stats", handleStats)
+	mux.HandleFunc("GET /api/navidrome/status", handleNavidromeStatus(c.navidromeConfigured, navidromeSkipDefault))
+	mux.HandleFunc("GET /api/deemix/status", handleDeemixStatus(c.deemix))
+	mux.HandleFunc("GET /api/cache", handleCacheInfo(c.cache))
+	mux.HandleFunc("DELETE /api/cache", handlePurgeCache(c.cache))
+	mux.Handle("GET /", staticHandler())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: ":" + port, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		log.Printf("Shutting down")
+		// Event streams stay open, so don't wait for them for long.
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("Starting server on :%s", port)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	if err := c.cache.Flush(); err != nil {
+		log.Printf("WARNING: failed to save search cache: %v", err)
+	}
+}
+
+// clients holds the adapters newPipeline creates, for handlers that use
+// them directly.
+type clients struct {
+	yt                  *ytdlp.CommandClient
+	deemix              *deemix.HTTPClient
+	cache               *sync.SearchCache
+	navidromeConfigured bool
+	createPlaylists     bool
+}
+
+// newPipeline creates the clients and the pipeline from environment variables.
+func newPipeline() (*sync.Pipeline, clients) {
 	deemixURL := os.Getenv("DEEMIX_URL")
 	if deemixURL == "" {
 		deemixURL = "http://localhost:6595"
@@ -36,7 +141,15 @@ func main() {
 
 	// Initialize clients.
 	ytClient := ytdlp.NewClient()
+	if cookies := os.Getenv("YTDLP_COOKIES"); cookies != "" {
+		if _, err := os.Stat(cookies); err != nil {
+			log.Fatalf("YTDLP_COOKIES: %v", err)
+		}
+		ytClient.CookiesFile = cookies
+		log.Printf("yt-dlp signs in with cookies from %s", cookies)
+	}
 	dxClient := deemix.NewClient(deemixURL, arl)
+	dxClient.HTTPClient.Transport = newTransport("DEEMIX", 5)
 
 	// Login to Deemix.
 	ctx := context.Background()
@@ -58,13 +171,11 @@ func main() {
 			User:      navUser,
 			Password:  navPass,
 			MatchMode: navMatchMode,
+			Client:    &http.Client{Transport: newTransport("NAVIDROME", 10)},
 		}
 		log.Printf("Navidrome integration enabled at %s (match: %s)", navURL, effectiveMatchMode(navMatchMode))
 	}
 
-	navidromeConfigured := navClient != nil
-	navidromeSkipDefault := os.Getenv("NAVIDROME_SKIP_DEFAULT") == "true"
-
 	pipeline := sync.NewPipeline(ytClient, dxClient, navClient)
 
 	// Optional confidence threshold.
@@ -75,24 +186,99 @@ func main() {
 		}
 	}
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("POST /api/analyze", handleAnalyze(pipeline))
-	mux.HandleFunc("GET /api/session/{id}", handleGetSession(pipeline))
-	mux.HandleFunc("POST /api/session/{id}/download", handleDownload(pipeline))
-	mux.HandleFunc("POST /api/session/{id}/pause", handlePause(pipeline))
-	mux.HandleFunc("POST /api/session/{id}/resume", handleResume(pipeline))
-	mux.HandleFunc("POST /api/session/{id}/cancel", handleCancel(pipeline))
-	mux.HandleFunc("POST /api/session/{id}/track/{index}/select", handleSelectTrack(pipeline))
-	mux.HandleFunc("POST /api/session/{id}/track/{index}/search", handleSearchTrack(pipeline))
-	mux.HandleFunc("GET /api/channel/playlists", handleChannelPlaylists(ytClient))
-	mux.HandleFunc("GET /api/url/info", handleURLInfo(ytClient))
-	mux.HandleFunc("GET /api/stats", handleStats)
-	mux.HandleFunc("GET /api/navidrome/status", handleNavidromeStatus(navidromeConfigured, navidromeSkipDefault))
-	mux.Handle("GET /", staticHandler())
+	// Optional cap on sessions running at once.
+	if maxStr := os.Getenv("MAX_ACTIVE_SESSIONS"); maxStr != "" {
+		if n, err := strconv.Atoi(maxStr); err == nil {
+			pipeline.SetMaxActive(n)
+			log.Printf("Running at most %d sessions at once", n)
+		}
+	}
 
-	log.Printf("Starting server on :%s", port)
-	if err := http.ListenAndServe(":"+port, mux); err != nil {
-		log.Fatal(err)
+	// Optional search concurrency.
+	if workersStr := os.Getenv("SEARCH_WORKERS"); workersStr != "" {
+		if workers, err := strconv.Atoi(workersStr); err == nil && workers > 0 {
+			pipeline.SetWorkers(workers)
+			log.Printf("Searching %d tracks at a time", workers)
+		}
+	}
+
+	// Optional keyword lists for demoting karaoke, cover and tribute results.
+	quality := sync.DefaultQualityFilter
+	quality.Artist = keywordList("NEGATIVE_ARTIST_KEYWORDS", quality.Artist)
+	quality.Title = keywordList("NEGATIVE_TITLE_KEYWORDS", quality.Title)
+	quality.Album = keywordList("NEGATIVE_ALBUM_KEYWORDS", quality.Album)
+	pipeline.SetQualityFilter(quality)
+
+	// Search cache, kept on disk when DATA_DIR is set.
+	ttl := sync.DefaultCacheTTL
+	if ttlStr := os.Getenv("SEARCH_CACHE_TTL"); ttlStr != "" {
+		if d, err := time.ParseDuration(ttlStr); err == nil && d > 0 {
+			ttl = d
+		} else {
+			log.Printf("WARNING: invalid SEARCH_CACHE_TTL %q, using %s", ttlStr, ttl)
+		}
+	}
+	var cachePath string
+	if dataDir := os.Getenv("DATA_DIR"); dataDir != "" {
+		cachePath = filepath.Join(dataDir, "search-cache.json")
+	}
+	cache := sync.NewSearchCache(cachePath, ttl)
+	if err := cache.Load(); err != nil {
+		log.Printf("WARNING: failed to load search cache: %v", err)
+	}
+	pipeline.SetSearchCache(cache)
+
+	// Optional Navidrome playlist sync.
+	createPlaylists := navClient != nil && os.Getenv("NAVIDROME_CREATE_PLAYLISTS") == "true"
+	if createPlaylists {
+		pipeline.SetCreatePlaylists(true)
+		log.Printf("Navidrome playlists will be created after downloads")
+	}
+
+	return pipeline, clients{yt: ytClient, deemix: dxClient, cache: cache, navidromeConfigured: navClient != nil, createPlaylists: createPlaylists}
+}
+
+// keywordList reads a comma-separated list from the environment, or returns
+// defaults if the variable is unset. "none" gives an empty list.
+func keywordList(name string, defaults []string) []string {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaults
+	}
+	var keywords []string
+	for _, k := range strings.Split(value, ",") {
+		if k = strings.TrimSpace(k); k != "" && k != "none" {
+			keywords = append(keywords, k)
+		}
+	}
+	return keywords
+}
+
+// newTransport returns a rate-limited, retrying transport for one backend,
+// configured by <prefix>_RATE_LIMIT (requests per second, 0 for no limit)
+// and <prefix>_MAX_RETRIES.
+func newTransport(prefix string, defaultRate float64) *ratelimit.Transport {
+	rate := defaultRate
+	if s := os.Getenv(prefix + "_RATE_LIMIT"); s != "" {
+		if r, err := strconv.ParseFloat(s, 64); err == nil && r >= 0 {
+			rate = r
+		} else {
+			log.Printf("WARNING: invalid %s_RATE_LIMIT %q, using %g", prefix, s, defaultRate)
+		}
+	}
+
+	policy := ratelimit.DefaultPolicy
+	if s := os.Getenv(prefix + "_MAX_RETRIES"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n >= 0 {
+			policy.MaxAttempts = n + 1
+		} else {
+			log.Printf("WARNING: invalid %s_MAX_RETRIES %q, using %d", prefix, s, policy.MaxAttempts-1)
+		}
+	}
+
+	return &ratelimit.Transport{
+		Limiter: ratelimit.NewLimiter(rate, max(1, int(rate))),
+		Policy:  policy,
 	}
 }
 
@@ -100,13 +286,20 @@ type analyzeRequest struct {
 	URL            string `json:"url"`
 	Bitrate        int    `json:"bitrate"`
 	CheckNavidrome bool   `json:"check_navidrome"`
+	Priority       int    `json:"priority"`
+	// Cookies is a cookies.txt in Netscape format to fetch the playlist
+	// with, in place of the server's YTDLP_COOKIES.
+	Cookies string `json:"cookies"`
 }
 
 type analyzeResponse struct {
 	SessionID string `json:"session_id"`
 }
 
-func handleAnalyze(pipeline *sync.Pipeline) http.HandlerFunc {
+// handleAnalyze starts analysing a URL. serverCookies reports whether
+// YTDLP_COOKIES is set; without it, the account's own lists need cookies
+// sent with the request.
+func handleAnalyze(pipeline *sync.Pipeline, serverCookies bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req analyzeRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -121,12 +314,39 @@ func handleAnalyze(pipeline *sync.Pipeline) http.HandlerFunc {
 			http.Error(w, `{"error":"invalid YouTube URL"}`, http.StatusBadRequest)
 			return
 		}
+		if _, personal := ytdlp.PersonalList(req.URL); personal && !serverCookies && req.Cookies == "" {
+			http.Error(w, `{"error":"this list is only visible to its account: set YTDLP_COOKIES or send a cookies.txt"}`, http.StatusBadRequest)
+			return
+		}
 		if req.Bitrate == 0 {
 			req.Bitrate = deemix.Bitrate128
 		}
 
-		id := pipeline.Analyze(context.Background(), req.URL, req.Bitrate, req.CheckNavidrome)
+		ctx := context.Background()
+		var cookiesPath string
+		if req.Cookies != "" {
+			path, err := writeCookies(req.Cookies)
+			if err != nil {
+				log.Printf("[analyze] failed to save cookies: %v", err)
+				http.Error(w, `{"error":"failed to save cookies"}`, http.StatusInternalServerError)
+				return
+			}
+			cookiesPath = path
+			ctx = ytdlp.WithCookies(ctx, path)
+		}
+
+		if req.Priority != 0 {
+			ctx = sync.WithPriority(ctx, req.Priority)
+		}
 
+		id := pipeline.Analyze(ctx, req.URL, req.Bitrate, req.CheckNavidrome)
+		if cookiesPath != "" {
+			// The playlist is fetched by the time the session settles.
+			go func() {
+				pipeline.WaitSettled(context.Background(), id)
+				os.Remove(cookiesPath)
+			}()
+		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(analyzeResponse{SessionID: id})
 	}
@@ -146,6 +366,143 @@ func handleGetSession(pipeline *sync.Pipeline) http.HandlerFunc {
 	}
 }
 
+// sseKeepAlive is how often an idle event stream sends a comment line, so
+// proxies don't close the connection.
+const sseKeepAlive = 15 * time.Second
+
+// handleSessionEvents streams session changes as Server-Sent Events. The
+// first event is a "snapshot" with the whole session; after that only
+// "status", "track" and "progress" events are sent. The stream ends when the
+// subscriber falls behind, and the browser's EventSource reconnects and gets
+// a fresh snapshot.
+func handleSessionEvents(pipeline *sync.Pipeline) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, `{"error":"streaming not supported"}`, http.StatusInternalServerError)
+			return
+		}
+
+		// Subscribe before taking the snapshot so no change is missed.
+		events, unsubscribe, err := pipeline.Subscribe(id)
+		if err != nil {
+			http.Error(w, `{"error":"session not found"}`, http.StatusNotFound)
+			return
+		}
+		defer unsubscribe()
+
+		session, ok := pipeline.GetSession(id)
+		if !ok {
+			http.Error(w, `{"error":"session not found"}`, http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		writeEvent(w, "snapshot", session)
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(sseKeepAlive)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case ev, ok := <-events:
+				if !ok {
+					return
+				}
+				writeEvent(w, ev.Type, ev.Data)
+				flusher.Flush()
+			case <-keepAlive.C:
+				w.Write([]byte(": keep-alive\n\n"))
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// writeEvent writes one SSE event with a JSON payload.
+func writeEvent(w http.ResponseWriter, event string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("[sse] failed to encode %s event: %v", event, err)
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
+
+func handleExport(pipeline *sync.Pipeline) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		format := r.URL.Query().Get("format")
+		var contentType string
+		switch format {
+		case "csv":
+			contentType = "text/csv; charset=utf-8"
+		case "json":
+			contentType = "application/json"
+		case "m3u8":
+			contentType = "audio/x-mpegurl; charset=utf-8"
+		default:
+			http.Error(w, `{"error":"format must be csv, json or m3u8"}`, http.StatusBadRequest)
+			return
+		}
+
+		session, ok := pipeline.GetSession(id)
+		if !ok {
+			http.Error(w, `{"error":"session not found"}`, http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="session-%s.%s"`, id, format))
+		var err error
+		switch format {
+		case "csv":
+			err = sync.WriteCSV(w, session)
+		case "json":
+			err = json.NewEncoder(w).Encode(sync.ExportRows(session))
+		case "m3u8":
+			err = sync.WriteM3U8(w, session)
+		}
+		if err != nil {
+			log.Printf("[export] session %s: %v", id, err)
+		}
+	}
+}
+
+func handleListSessions(pipeline *sync.Pipeline) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(pipeline.ListSessions())
+	}
+}
+
+func handleDeleteSession(pipeline *sync.Pipeline) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := r.PathValue("id")
+
+		if err := pipeline.DeleteSession(sessionID); err != nil {
+			switch err {
+			case sync.ErrSessionNotFound:
+				http.Error(w, `{"error":"session not found"}`, http.StatusNotFound)
+			case sync.ErrSessionActive:
+				http.Error(w, `{"error":"session is still active"}`, http.StatusBadRequest)
+			default:
+				http.Error(w, `{"error":"failed to delete session"}`, http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"deleted"}`))
+	}
+}
+
 type statsResponse struct {
 	Version    string  `json:"version"`
 	MemoryMB   float64 `json:"memory_mb"`
@@ -180,6 +537,51 @@ func handleNavidromeStatus(configured, skipDefault bool) http.HandlerFunc {
 	}
 }
 
+// authReporter reports the state of the Deemix login.
+type authReporter interface {
+	Auth() deemix.AuthStatus
+}
+
+func handleDeemixStatus(dx authReporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(dx.Auth())
+	}
+}
+
+func handleCacheInfo(cache *sync.SearchCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(cache.Info())
+	}
+}
+
+// handlePurgeCache empties the search cache, or drops a single entry given
+// by the query or video parameter.
+func handlePurgeCache(cache *sync.SearchCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("query")
+		video := r.URL.Query().Get("video")
+		switch {
+		case query != "":
+			if !cache.PurgeQuery(query) {
+				http.Error(w, `{"error":"query not cached"}`, http.StatusNotFound)
+				return
+			}
+		case video != "":
+			if !cache.PurgeVideo(video) {
+				http.Error(w, `{"error":"video not cached"}`, http.StatusNotFound)
+				return
+			}
+		default:
+			cache.Purge()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"purged"}`))
+	}
+}
+
 func effectiveMatchMode(mode string) string {
 	if mode == "" {
 		return navidrome.MatchSubstring
@@ -187,6 +589,25 @@ func effectiveMatchMode(mode string) string {
 	return mode
 }
 
+// writeCookies saves cookies sent with a request to a temporary file, only
+// readable by this process's user, as yt-dlp needs a path.
+func writeCookies(cookies string) (string, error) {
+	f, err := os.CreateTemp("", "ytdlp-cookies-*.txt")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.WriteString(cookies); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
 func isValidYouTubeURL(url string) bool {
 	return strings.Contains(url, "youtube.com") || strings.Contains(url, "youtu.be")
 }
@@ -251,9 +672,11 @@ func handleURLInfo(ytClient *ytdlp.CommandClient) http.HandlerFunc {
 		title, err := ytClient.GetURLInfo(r.Context(), url)
 		if err != nil {
 			log.Printf("[url-info] failed to fetch info for %s: %v", url, err)
-			// Return URL as title fallback instead of error
+			// Return URL as title fallback instead of error; the account's
+			// own lists have well-known names.
+			name, _ := ytdlp.PersonalList(url)
 			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(urlInfoResponse{URL: url, Title: ""})
+			json.NewEncoder(w).Encode(urlInfoResponse{URL: url, Title: name})
 			return
 		}
 
@@ -285,6 +708,62 @@ func handleDownload(pipeline *sync.Pipeline) http.HandlerFunc {
 	}
 }
 
+type priorityRequest struct {
+	Priority int `json:"priority"`
+}
+
+func handleSetPriority(pipeline *sync.Pipeline) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := r.PathValue("id")
+
+		var req priorityRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+
+		if err := pipeline.SetPriority(sessionID, req.Priority); err != nil {
+			http.Error(w, `{"error":"session not found"}`, http.StatusNotFound)
+			return
+		}
+
+		session, _ := pipeline.GetSession(sessionID)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(session)
+	}
+}
+
+type playlistResponse struct {
+	PlaylistID string `json:"playlist_id"`
+}
+
+func handleSyncPlaylist(pipeline *sync.Pipeline) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := r.PathValue("id")
+
+		if err := pipeline.SyncPlaylist(r.Context(), sessionID); err != nil {
+			switch err {
+			case sync.ErrSessionNotFound:
+				http.Error(w, `{"error":"session not found"}`, http.StatusNotFound)
+			case sync.ErrSessionNotReady:
+				http.Error(w, `{"error":"session has not finished downloading"}`, http.StatusConflict)
+			case sync.ErrNoPlaylistSupport:
+				http.Error(w, `{"error":"navidrome not configured"}`, http.StatusServiceUnavailable)
+			case sync.ErrNoPlaylistTitle:
+				http.Error(w, `{"error":"session has no playlist title"}`, http.StatusUnprocessableEntity)
+			default:
+				log.Printf("[navidrome] playlist sync failed: %v", err)
+				http.Error(w, `{"error":"playlist sync failed"}`, http.StatusBadGateway)
+			}
+			return
+		}
+
+		session, _ := pipeline.GetSession(sessionID)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(playlistResponse{PlaylistID: session.NavidromePlaylist})
+	}
+}
+
 type selectRequest struct {
 	Selected bool `json:"selected"`
 }
@@ -317,6 +796,8 @@ func handleSelectTrack(pipeline *sync.Pipeline) http.HandlerFunc {
 				http.Error(w, `{"error":"session is not ready for modifications"}`, http.StatusBadRequest)
 			case sync.ErrTrackNotFound:
 				http.Error(w, `{"error":"track not found"}`, http.StatusNotFound)
+			case sync.ErrTrackUnavailable:
+				http.Error(w, `{"error":"video is unavailable"}`, http.StatusConflict)
 			default:
 				http.Error(w, `{"error":"failed to update track selection"}`, http.StatusInternalServerError)
 			}
@@ -372,6 +853,8 @@ func handleSearchTrack(pipeline *sync.Pipeline) http.HandlerFunc {
 				http.Error(w, `{"error":"session is not ready for modifications"}`, http.StatusBadRequest)
 			case sync.ErrTrackNotFound:
 				http.Error(w, `{"error":"track not found"}`, http.StatusNotFound)
+			case sync.ErrTrackUnavailable:
+				http.Error(w, `{"error":"video is unavailable"}`, http.StatusConflict)
 			default:
 				http.Error(w, `{"error":"search failed"}`, http.StatusInternalServerError)
 			}
@@ -380,29 +863,118 @@ func handleSearchTrack(pipeline *sync.Pipeline) http.HandlerFunc {
 
 		// Get updated session to return the new match
 		session, _ := pipeline.GetSession(sessionID)
-		track := session.Tracks[index]
-
-		resp := searchResponse{
-			Confidence: track.Confidence,
-			Status:     track.Status,
-			Selected:   track.Selected,
-		}
-		if track.DeezerMatch != nil {
-			resp.DeezerMatch = &struct {
-				ID     int64  `json:"id"`
-				Title  string `json:"title"`
-				Artist string `json:"artist"`
-				Link   string `json:"link"`
-			}{
-				ID:     track.DeezerMatch.ID,
-				Title:  track.DeezerMatch.Title,
-				Artist: track.DeezerMatch.Artist,
-				Link:   track.DeezerMatch.Link,
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(newSearchResponse(session.Tracks[index]))
+	}
+}
+
+func newSearchResponse(track sync.Track) searchResponse {
+	resp := searchResponse{
+		Confidence: track.Confidence,
+		Status:     track.Status,
+		Selected:   track.Selected,
+	}
+	if track.DeezerMatch != nil {
+		resp.DeezerMatch = &struct {
+			ID     int64  `json:"id"`
+			Title  string `json:"title"`
+			Artist string `json:"artist"`
+			Link   string `json:"link"`
+		}{
+			ID:     track.DeezerMatch.ID,
+			Title:  track.DeezerMatch.Title,
+			Artist: track.DeezerMatch.Artist,
+			Link:   track.DeezerMatch.Link,
+		}
+	}
+	return resp
+}
+
+func handleTrackCandidates(pipeline *sync.Pipeline) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := r.PathValue("id")
+		index, err := strconv.Atoi(r.PathValue("index"))
+		if err != nil {
+			http.Error(w, `{"error":"invalid track index"}`, http.StatusBadRequest)
+			return
+		}
+
+		// The body is optional; without a query the parsed track is searched.
+		var req searchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+
+		candidates, err := pipeline.TrackCandidates(r.Context(), sessionID, index, req.Query)
+		if err != nil {
+			switch err {
+			case sync.ErrSessionNotFound:
+				http.Error(w, `{"error":"session not found"}`, http.StatusNotFound)
+			case sync.ErrSessionNotReady:
+				http.Error(w, `{"error":"session is not ready for modifications"}`, http.StatusBadRequest)
+			case sync.ErrTrackNotFound:
+				http.Error(w, `{"error":"track not found"}`, http.StatusNotFound)
+			case sync.ErrTrackUnavailable:
+				http.Error(w, `{"error":"video is unavailable"}`, http.StatusConflict)
+			default:
+				http.Error(w, `{"error":"search failed"}`, http.StatusInternalServerError)
 			}
+			return
+		}
+		if candidates == nil {
+			candidates = []sync.Candidate{}
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(resp)
+		json.NewEncoder(w).Encode(candidates)
+	}
+}
+
+type matchRequest struct {
+	DeezerID int64 `json:"deezer_id"`
+}
+
+func handleSetTrackMatch(pipeline *sync.Pipeline) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID := r.PathValue("id")
+		index, err := strconv.Atoi(r.PathValue("index"))
+		if err != nil {
+			http.Error(w, `{"error":"invalid track index"}`, http.StatusBadRequest)
+			return
+		}
+
+		var req matchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+		if req.DeezerID == 0 {
+			http.Error(w, `{"error":"deezer_id is required"}`, http.StatusBadRequest)
+			return
+		}
+
+		if err := pipeline.SetTrackMatch(r.Context(), sessionID, index, req.DeezerID); err != nil {
+			switch err {
+			case sync.ErrSessionNotFound:
+				http.Error(w, `{"error":"session not found"}`, http.StatusNotFound)
+			case sync.ErrSessionNotReady:
+				http.Error(w, `{"error":"session is not ready for modifications"}`, http.StatusBadRequest)
+			case sync.ErrTrackNotFound:
+				http.Error(w, `{"error":"track not found"}`, http.StatusNotFound)
+			case sync.ErrCandidateNotFound:
+				http.Error(w, `{"error":"deezer_id is not a candidate for this track"}`, http.StatusBadRequest)
+			default:
+				http.Error(w, `{"error":"failed to set match"}`, http.StatusInternalServerError)
+			}
+			return
+		}
+
+		session, _ := pipeline.GetSession(sessionID)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(newSearchResponse(session.Tracks[index]))
 	}
 }
 
@@ -439,6 +1011,8 @@ func handleResume(pipeline *sync.Pipeline) http.HandlerFunc {
 				http.Error(w, `{"error":"session not found"}`, http.StatusNotFound)
 			case sync.ErrSessionNotPaused:
 				http.Error(w, `{"error":"session is not paused"}`, http.StatusBadRequest)
+			case sync.ErrCookiesGone:
+				http.Error(w, `{"error":"the cookies sent with this session are not kept across restarts: analyze the playlist again with them"}`, http.StatusConflict)
 			default:
 				http.Error(w, `{"error":"failed to resume session"}`, http.StatusInternalServerError)
 			}
@@ -470,3 +1044,76 @@ func handleCancel(pipeline *sync.Pipeline) http.HandlerFunc {
 		w.Write([]byte(`{"status":"canceled"}`))
 	}
 }
+
+type watchRequest struct {
+	URL            string `json:"url"`
+	Interval       string `json:"interval"`
+	Bitrate        int    `json:"bitrate"`
+	CheckNavidrome bool   `json:"check_navidrome"`
+	AutoDownload   bool   `json:"auto_download"`
+}
+
+func handleListWatches(watcher *sync.Watcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(watcher.List())
+	}
+}
+
+func handleAddWatch(watcher *sync.Watcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req watchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+		if req.URL == "" {
+			http.Error(w, `{"error":"url is required"}`, http.StatusBadRequest)
+			return
+		}
+		if !isValidYouTubeURL(req.URL) {
+			http.Error(w, `{"error":"invalid YouTube URL"}`, http.StatusBadRequest)
+			return
+		}
+		if req.Interval == "" {
+			req.Interval = "@daily"
+		}
+		if req.Bitrate == 0 {
+			req.Bitrate = deemix.Bitrate128
+		}
+
+		watch, err := watcher.Add(req.URL, req.Interval, req.Bitrate, req.CheckNavidrome, req.AutoDownload)
+		if err != nil {
+			http.Error(w, `{"error":"invalid interval (use a duration of at least 5m, @hourly, @daily or @weekly)"}`, http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(watch)
+	}
+}
+
+func handleRemoveWatch(watcher *sync.Watcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := watcher.Remove(r.PathValue("id")); err != nil {
+			http.Error(w, `{"error":"watch not found"}`, http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"removed"}`))
+	}
+}
+
+func handleRunWatch(watcher *sync.Watcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// The check outlives the request, so it must not use r.Context().
+		if err := watcher.Trigger(context.Background(), r.PathValue("id")); err != nil {
+			http.Error(w, `{"error":"watch not found"}`, http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"checking"}`))
+	}
+}
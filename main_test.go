@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -41,7 +42,7 @@ func testPipeline() *sync.Pipeline {
 
 func TestHandleAnalyzeValid(t *testing.T) {
 	pipeline := testPipeline()
-	handler := handleAnalyze(pipeline)
+	handler := handleAnalyze(pipeline, false)
 
 	body := `{"url":"https://youtube.com/playlist?list=test","bitrate":3}`
 	req := httptest.NewRequest(http.MethodPost, "/api/analyze", bytes.NewBufferString(body))
@@ -65,7 +66,7 @@ func TestHandleAnalyzeValid(t *testing.T) {
 
 func TestHandleAnalyzeMissingURL(t *testing.T) {
 	pipeline := testPipeline()
-	handler := handleAnalyze(pipeline)
+	handler := handleAnalyze(pipeline, false)
 
 	body := `{"bitrate":3}`
 	req := httptest.NewRequest(http.MethodPost, "/api/analyze", bytes.NewBufferString(body))
@@ -80,7 +81,7 @@ func TestHandleAnalyzeMissingURL(t *testing.T) {
 
 func TestHandleAnalyzeInvalidURL(t *testing.T) {
 	pipeline := testPipeline()
-	handler := handleAnalyze(pipeline)
+	handler := handleAnalyze(pipeline, false)
 
 	body := `{"url":"https://example.com/not-youtube"}`
 	req := httptest.NewRequest(http.MethodPost, "/api/analyze", bytes.NewBufferString(body))
@@ -93,9 +94,35 @@ func TestHandleAnalyzeInvalidURL(t *testing.T) {
 	}
 }
 
+func TestHandleAnalyzePersonalList(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		serverCookies bool
+		want          int
+	}{
+		{"no cookies", `{"url":"https://www.youtube.com/playlist?list=LL"}`, false, http.StatusBadRequest},
+		{"server cookies", `{"url":"https://www.youtube.com/playlist?list=LL"}`, true, http.StatusOK},
+		{"request cookies", `{"url":"https://music.youtube.com/playlist?list=LM","cookies":"# Netscape HTTP Cookie File\n"}`, false, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := handleAnalyze(testPipeline(), tt.serverCookies)
+			req := httptest.NewRequest(http.MethodPost, "/api/analyze", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			handler(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
 func TestHandleAnalyzeInvalidBody(t *testing.T) {
 	pipeline := testPipeline()
-	handler := handleAnalyze(pipeline)
+	handler := handleAnalyze(pipeline, false)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/analyze", bytes.NewBufferString("not json"))
 	w := httptest.NewRecorder()
@@ -151,6 +178,141 @@ func TestHandleGetSessionNotFound(t *testing.T) {
 	}
 }
 
+func TestHandleSessionEvents(t *testing.T) {
+	pipeline := testPipeline()
+	id := pipeline.Analyze(context.Background(), "https://youtube.com/playlist?list=test", deemix.Bitrate320, false)
+	pipeline.WaitSettled(context.Background(), id)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/api/session/"+id+"/events", nil).WithContext(ctx)
+	req.SetPathValue("id", id)
+	w := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		handleSessionEvents(pipeline)(w, req)
+		close(done)
+	}()
+
+	// Give the handler time to subscribe, then change a track.
+	time.Sleep(50 * time.Millisecond)
+	pipeline.SetTrackSelected(id, 0, true)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+	body := w.Body.String()
+	for _, want := range []string{"event: snapshot\ndata: {", "event: track\ndata: {\"index\":0", "event: progress\n"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("stream missing %q, got:\n%s", want, body)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/session/missing/events", nil)
+	req.SetPathValue("id", "missing")
+	w = httptest.NewRecorder()
+	handleSessionEvents(pipeline)(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing session status = %d, want 404", w.Code)
+	}
+}
+
+func TestHandleExport(t *testing.T) {
+	pipeline := testPipeline()
+	id := pipeline.Analyze(context.Background(), "https://youtube.com/playlist?list=test", deemix.Bitrate320, false)
+	pipeline.WaitSettled(context.Background(), id)
+
+	tests := []struct {
+		format      string
+		code        int
+		contentType string
+		body        string
+	}{
+		{"csv", http.StatusOK, "text/csv; charset=utf-8", "youtube_title,parsed_artist"},
+		{"json", http.StatusOK, "application/json", `"youtube_title":"Artist - Song"`},
+		{"m3u8", http.StatusOK, "audio/x-mpegurl; charset=utf-8", "#EXTM3U"},
+		{"xml", http.StatusBadRequest, "", "format must be"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/session/"+id+"/export?format="+tt.format, nil)
+		req.SetPathValue("id", id)
+		w := httptest.NewRecorder()
+		handleExport(pipeline)(w, req)
+
+		if w.Code != tt.code {
+			t.Errorf("%s: status = %d, want %d", tt.format, w.Code, tt.code)
+		}
+		if tt.contentType != "" && w.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("%s: Content-Type = %q, want %q", tt.format, w.Header().Get("Content-Type"), tt.contentType)
+		}
+		if !strings.Contains(w.Body.String(), tt.body) {
+			t.Errorf("%s: body = %q, want it to contain %q", tt.format, w.Body.String(), tt.body)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/session/missing/export?format=csv", nil)
+	req.SetPathValue("id", "missing")
+	w := httptest.NewRecorder()
+	handleExport(pipeline)(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing session status = %d, want 404", w.Code)
+	}
+}
+
+func TestHandleListSessions(t *testing.T) {
+	pipeline := testPipeline()
+	id := pipeline.Analyze(context.Background(), "https://youtube.com/playlist?list=test", deemix.Bitrate320, false)
+
+	handler := handleListSessions(pipeline)
+	req := httptest.NewRequest(http.MethodGet, "/api/sessions", nil)
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+
+	var sessions []sync.Session
+	if err := json.NewDecoder(w.Body).Decode(&sessions); err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != id {
+		t.Errorf("sessions = %+v, want one session %q", sessions, id)
+	}
+}
+
+func TestHandleDeleteSession(t *testing.T) {
+	pipeline := testPipeline()
+	id := pipeline.Analyze(context.Background(), "https://youtube.com/playlist?list=test", deemix.Bitrate320, false)
+
+	// Wait for ready.
+	time.Sleep(100 * time.Millisecond)
+
+	handler := handleDeleteSession(pipeline)
+	req := httptest.NewRequest(http.MethodDelete, "/api/session/"+id, nil)
+	req.SetPathValue("id", id)
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200, body: %s", w.Code, w.Body.String())
+	}
+	if _, ok := pipeline.GetSession(id); ok {
+		t.Error("session still exists after delete")
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("second delete status = %d, want 404", w.Code)
+	}
+}
+
 func TestHandleStats(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/stats", nil)
 	w := httptest.NewRecorder()
@@ -421,3 +583,211 @@ func TestHandleResumeNotPaused(t *testing.T) {
 		t.Fatalf("status = %d, want 400", w.Code)
 	}
 }
+
+func TestHandleWatches(t *testing.T) {
+	watcher := sync.NewWatcher(testPipeline(), nil)
+
+	// Invalid interval.
+	req := httptest.NewRequest(http.MethodPost, "/api/watches", bytes.NewBufferString(`{"url":"https://youtube.com/playlist?list=test","interval":"10s"}`))
+	w := httptest.NewRecorder()
+	handleAddWatch(watcher)(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("short interval status = %d, want 400", w.Code)
+	}
+
+	// Valid watch with default interval.
+	req = httptest.NewRequest(http.MethodPost, "/api/watches", bytes.NewBufferString(`{"url":"https://youtube.com/playlist?list=test"}`))
+	w = httptest.NewRecorder()
+	handleAddWatch(watcher)(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200, body: %s", w.Code, w.Body.String())
+	}
+	var watch sync.Watch
+	if err := json.NewDecoder(w.Body).Decode(&watch); err != nil {
+		t.Fatal(err)
+	}
+	if watch.Interval != "@daily" || watch.Bitrate != deemix.Bitrate128 {
+		t.Errorf("watch = %+v, want daily 128kbps defaults", watch)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/watches", nil)
+	w = httptest.NewRecorder()
+	handleListWatches(watcher)(w, req)
+	var list []sync.Watch
+	json.NewDecoder(w.Body).Decode(&list)
+	if len(list) != 1 {
+		t.Fatalf("list has %d watches, want 1", len(list))
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/watch/"+watch.ID, nil)
+	req.SetPathValue("id", watch.ID)
+	w = httptest.NewRecorder()
+	handleRemoveWatch(watcher)(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("remove status = %d, want 200", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	handleRunWatch(watcher)(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("run removed watch status = %d, want 404", w.Code)
+	}
+}
+
+func TestHandleTrackCandidatesAndMatch(t *testing.T) {
+	pipeline := testPipeline()
+	id := pipeline.Analyze(context.Background(), "https://youtube.com/playlist?list=test", deemix.Bitrate320, false)
+	pipeline.WaitSettled(context.Background(), id)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/session/"+id+"/track/0/candidates", nil)
+	req.SetPathValue("id", id)
+	req.SetPathValue("index", "0")
+	w := httptest.NewRecorder()
+	handleTrackCandidates(pipeline)(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("candidates status = %d, want 200, body: %s", w.Code, w.Body.String())
+	}
+	var candidates []sync.Candidate
+	if err := json.NewDecoder(w.Body).Decode(&candidates); err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 1 || candidates[0].ID != 1 {
+		t.Fatalf("candidates = %+v, want track 1", candidates)
+	}
+
+	// Unknown candidate.
+	req = httptest.NewRequest(http.MethodPost, "/api/session/"+id+"/track/0/match", bytes.NewBufferString(`{"deezer_id":42}`))
+	req.SetPathValue("id", id)
+	req.SetPathValue("index", "0")
+	w = httptest.NewRecorder()
+	handleSetTrackMatch(pipeline)(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown candidate status = %d, want 400", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/session/"+id+"/track/0/match", bytes.NewBufferString(`{"deezer_id":1}`))
+	req.SetPathValue("id", id)
+	req.SetPathValue("index", "0")
+	w = httptest.NewRecorder()
+	handleSetTrackMatch(pipeline)(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("match status = %d, want 200, body: %s", w.Code, w.Body.String())
+	}
+	var resp searchResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.DeezerMatch == nil || resp.DeezerMatch.ID != 1 || resp.Status != sync.TrackFound || !resp.Selected {
+		t.Errorf("response = %+v, want found and selected track 1", resp)
+	}
+}
+
+func TestHandleSyncPlaylistWithoutNavidrome(t *testing.T) {
+	pipeline := testPipeline()
+	id := pipeline.Analyze(context.Background(), "https://youtube.com/playlist?list=test", deemix.Bitrate320, false)
+	pipeline.WaitSettled(context.Background(), id)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/session/"+id+"/playlist", nil)
+	req.SetPathValue("id", id)
+	w := httptest.NewRecorder()
+	handleSyncPlaylist(pipeline)(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", w.Code)
+	}
+}
+
+type staticAuth deemix.AuthStatus
+
+func (a staticAuth) Auth() deemix.AuthStatus { return deemix.AuthStatus(a) }
+
+func TestHandleDeemixStatus(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/deemix/status", nil)
+	w := httptest.NewRecorder()
+	handleDeemixStatus(staticAuth{State: deemix.AuthExpired, Error: "deemix is not logged in"})(w, req)
+
+	var resp deemix.AuthStatus
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.State != deemix.AuthExpired || resp.Error == "" {
+		t.Errorf("response = %+v, want expired with an error", resp)
+	}
+}
+
+func TestHandleSetPriority(t *testing.T) {
+	pipeline := testPipeline()
+	id := pipeline.Analyze(context.Background(), "https://youtube.com/playlist?list=test", deemix.Bitrate320, false)
+	pipeline.WaitSettled(context.Background(), id)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/session/"+id+"/priority", bytes.NewBufferString(`{"priority":3}`))
+	req.SetPathValue("id", id)
+	w := httptest.NewRecorder()
+	handleSetPriority(pipeline)(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	var session sync.Session
+	if err := json.NewDecoder(w.Body).Decode(&session); err != nil {
+		t.Fatal(err)
+	}
+	if session.Priority != 3 {
+		t.Errorf("priority = %d, want 3", session.Priority)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/session/missing/priority", bytes.NewBufferString(`{"priority":3}`))
+	req.SetPathValue("id", "missing")
+	w = httptest.NewRecorder()
+	handleSetPriority(pipeline)(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing session status = %d, want 404", w.Code)
+	}
+}
+
+func TestHandleCache(t *testing.T) {
+	cache := sync.NewSearchCache("", time.Hour)
+	client := sync.NewCachedClient(&mockDX{}, cache)
+	client.Search(context.Background(), "Radiohead Creep")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/cache", nil)
+	w := httptest.NewRecorder()
+	handleCacheInfo(cache)(w, req)
+
+	var info sync.CacheInfo
+	if err := json.NewDecoder(w.Body).Decode(&info); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(info.Queries) != 1 || info.Queries[0].Key != "radiohead creep" {
+		t.Fatalf("queries = %+v, want one for radiohead creep", info.Queries)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/cache?query=Radiohead+Creep", nil)
+	w = httptest.NewRecorder()
+	handlePurgeCache(cache)(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("purge status = %d, want 200", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/cache?query=Radiohead+Creep", nil)
+	w = httptest.NewRecorder()
+	handlePurgeCache(cache)(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("second purge status = %d, want 404", w.Code)
+	}
+}
+
+func TestKeywordList(t *testing.T) {
+	defaults := []string{"karaoke"}
+	if got := keywordList("TEST_KEYWORDS", defaults); len(got) != 1 || got[0] != "karaoke" {
+		t.Errorf("unset = %v, want defaults", got)
+	}
+	t.Setenv("TEST_KEYWORDS", " tribute , cover band,")
+	if got := keywordList("TEST_KEYWORDS", defaults); len(got) != 2 || got[0] != "tribute" || got[1] != "cover band" {
+		t.Errorf("list = %q, want tribute and cover band", got)
+	}
+	t.Setenv("TEST_KEYWORDS", "none")
+	if got := keywordList("TEST_KEYWORDS", defaults); len(got) != 0 {
+		t.Errorf("none = %v, want empty", got)
+	}
+}
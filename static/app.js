@@ -10,6 +10,7 @@
   var downloadBtn = document.getElementById("downloadBtn");
   var cancelBtn = document.getElementById("cancelBtn");
   var errorMsg = document.getElementById("errorMsg");
+  var authMsg = document.getElementById("authMsg");
   var progressEl = document.getElementById("progress");
   var phaseEl = document.getElementById("phase");
   var countSearched = document.getElementById("countSearched");
@@ -18,8 +19,13 @@
   var countSkipped = document.getElementById("countSkipped");
   var countReview = document.getElementById("countReview");
   var countNotFound = document.getElementById("countNotFound");
+  var countUnavailable = document.getElementById("countUnavailable");
   var countTotal = document.getElementById("countTotal");
   var navToggle = document.getElementById("navCheck");
+  var cookiesToggle = document.getElementById("cookiesToggle");
+  var cookiesInput = document.getElementById("cookiesInput");
+  // cookiesText is the chosen cookies.txt, sent with each analyze request.
+  var cookiesText = "";
   var trackContainer = document.getElementById("trackContainer");
   var trackTable = document.getElementById("trackTable");
   var trackBody = document.getElementById("trackBody");
@@ -30,21 +36,24 @@
   var uptimeEl = document.getElementById("uptime");
 
   var urlQueue = [];
-  var pollTimer = null;
+  var streams = [];
+  var streamGen = 0;
   var sessionIds = [];
   var currentSessionId = null;
   var syncIndex = 0;
   var isAnalyzing = false;
   var isReady = false;
   var isPaused = false;
+  var isRestoring = false;
   var currentTracks = [];
-  var totalProgress = { searched: 0, selected: 0, queued: 0, skipped: 0, needs_review: 0, not_found: 0, total: 0 };
+  var totalProgress = { searched: 0, selected: 0, queued: 0, skipped: 0, needs_review: 0, not_found: 0, unavailable: 0, total: 0 };
   var sortColumn = null;
   var sortAsc = true;
   var activeFilter = "all";
   var filterTabs = document.getElementById("filterTabs");
   var previousTabCounts = {};
   var emptyState = document.getElementById("emptyState");
+  var exportLinks = document.getElementById("exportLinks");
 
   // Theme toggle.
   var savedTheme = localStorage.getItem("theme") || "light";
@@ -363,14 +372,29 @@
       "not_found": 1,
       "error": 2,
       "found": 3,
-      "downloaded": 4,
-      "skipped": 5,
-      "searching": 6,
-      "pending": 7
+      "queued": 4,
+      "downloading": 5,
+      "downloaded": 6,
+      "skipped": 7,
+      "unavailable": 8,
+      "searching": 9,
+      "pending": 10
     };
     return order[status] !== undefined ? order[status] : 99;
   }
 
+  cookiesInput.addEventListener("change", function () {
+    var file = cookiesInput.files[0];
+    if (!file) return;
+    var reader = new FileReader();
+    reader.onload = function () {
+      cookiesText = reader.result;
+      cookiesToggle.classList.add("active");
+      cookiesToggle.title = "Signed in to YouTube with " + file.name;
+    };
+    reader.readAsText(file);
+  });
+
   // Select all checkbox.
   selectAllCheckbox.addEventListener("change", function () {
     if (!isReady) return;
@@ -390,11 +414,12 @@
     isAnalyzing = true;
     isReady = false;
     isPaused = false;
+    isRestoring = false;
     syncIndex = 0;
     sessionIds = [];
     currentSessionId = null;
     currentTracks = [];
-    totalProgress = { searched: 0, selected: 0, queued: 0, skipped: 0, needs_review: 0, not_found: 0, total: 0 };
+    totalProgress = { searched: 0, selected: 0, queued: 0, skipped: 0, needs_review: 0, not_found: 0, unavailable: 0, total: 0 };
     sortColumn = null;
     sortAsc = true;
     activeFilter = "all";
@@ -430,10 +455,13 @@
 
     phaseEl.textContent = "analyzing " + (syncIndex + 1) + "/" + urlQueue.length;
 
+    var body = { url: item.url, bitrate: bitrate, check_navidrome: navEnabled };
+    if (cookiesText) body.cookies = cookiesText;
+
     fetch("/api/analyze", {
       method: "POST",
       headers: { "Content-Type": "application/json" },
-      body: JSON.stringify({ url: item.url, bitrate: bitrate, check_navidrome: navEnabled }),
+      body: JSON.stringify(body),
     })
       .then(function (resp) {
         if (!resp.ok) return resp.json().then(function (d) { throw new Error(d.error); });
@@ -487,100 +515,156 @@
   }
 
   function startMultiSessionPolling() {
-    if (pollTimer) clearInterval(pollTimer);
-    pollTimer = setInterval(pollAllSessions, 800);
-    pollAllSessions();
+    streamSessions(sessionIds, renderAllSessions, function () {
+      closeStreams();
+      isAnalyzing = false;
+      isPaused = false;
+      
+      addBtn.disabled = false;
+      updateControlButtons();
+    });
   }
 
-  function pollAllSessions() {
-    var promises = sessionIds.map(function (sid) {
-      return fetch("/api/session/" + sid).then(function (r) { return r.json(); });
+  // Stream every session over SSE. onChange receives the latest state of all
+  // sessions once each has sent its snapshot, and again after every change
+  // (batched). onFail runs if a stream is closed for good, e.g. the session
+  // no longer exists. Dropped connections reconnect and resend a snapshot.
+  function streamSessions(ids, onChange, onFail) {
+    closeStreams();
+    var gen = streamGen;
+    var state = {};
+    var pending = null;
+
+    function changed() {
+      if (pending) return;
+      pending = setTimeout(function () {
+        pending = null;
+        if (gen !== streamGen) return;
+        var sessions = ids.map(function (sid) { return state[sid]; });
+        if (sessions.some(function (s) { return !s; })) return;
+        onChange(sessions);
+      }, 100);
+    }
+
+    ids.forEach(function (sid) {
+      var es = new EventSource("/api/session/" + sid + "/events");
+      es.addEventListener("snapshot", function (e) {
+        state[sid] = JSON.parse(e.data);
+        changed();
+      });
+      es.addEventListener("status", function (e) {
+        if (!state[sid]) return;
+        var d = JSON.parse(e.data);
+        state[sid].status = d.status;
+        state[sid].error = d.error;
+        state[sid].queue_position = d.queue_position;
+        state[sid].title = d.title;
+        state[sid].fetching = d.fetching;
+        state[sid].navidrome_playlist = d.navidrome_playlist;
+        state[sid].interrupted = d.interrupted;
+        changed();
+      });
+      es.addEventListener("progress", function (e) {
+        if (!state[sid]) return;
+        state[sid].progress = JSON.parse(e.data);
+        changed();
+      });
+      es.addEventListener("track", function (e) {
+        if (!state[sid]) return;
+        var d = JSON.parse(e.data);
+        // Tracks of a playlist still being fetched arrive one by one.
+        if (!state[sid].tracks) state[sid].tracks = [];
+        if (d.index < state[sid].tracks.length) state[sid].tracks[d.index] = d.track;
+        else if (d.index === state[sid].tracks.length) state[sid].tracks.push(d.track);
+        changed();
+      });
+      es.onerror = function () {
+        if (es.readyState === EventSource.CLOSED && gen === streamGen && onFail) onFail();
+      };
+      streams.push(es);
     });
+  }
 
-    Promise.all(promises)
-      .then(function (sessions) {
-        // Check if all sessions are done or canceled
-        var allDone = sessions.every(function (s) {
-          return s.status === "done" || s.status === "error" || s.status === "canceled";
-        });
+  function closeStreams() {
+    streamGen++;
+    streams.forEach(function (es) { es.close(); });
+    streams = [];
+  }
 
-        // Check if any session is paused
-        var anyPaused = sessions.some(function (s) {
-          return s.status === "paused";
-        });
+  function renderAllSessions(sessions) {
+    // Check if all sessions are done or canceled
+    var allDone = sessions.every(function (s) {
+      return s.status === "done" || s.status === "error" || s.status === "canceled";
+    });
 
-        // Update progress from all sessions
-        var totals = { searched: 0, selected: 0, queued: 0, skipped: 0, needs_review: 0, not_found: 0, total: 0 };
-        var allTracks = [];
-        sessions.forEach(function (s) {
-          totals.searched += s.progress.searched;
-          totals.selected += s.progress.selected;
-          totals.queued += s.progress.queued;
-          totals.skipped += s.progress.skipped;
-          totals.needs_review += s.progress.needs_review;
-          totals.not_found += s.progress.not_found;
-          totals.total += s.progress.total;
-          if (s.tracks) {
-            for (var i = 0; i < s.tracks.length; i++) {
-              s.tracks[i]._originalIndex = i;
-              s.tracks[i]._sessionId = s.id;
-              allTracks.push(s.tracks[i]);
-            }
-          }
-        });
+    // Check if any session is paused
+    var anyPaused = sessions.some(function (s) {
+      return s.status === "paused";
+    });
 
-        countSearched.textContent = totals.searched;
-        countSelected.textContent = totals.selected;
-        countQueued.textContent = totals.queued;
-        countSkipped.textContent = totals.skipped;
-        countReview.textContent = totals.needs_review;
-        countNotFound.textContent = totals.not_found;
-        countTotal.textContent = totals.total;
+    // Update progress from all sessions
+    var totals = { searched: 0, selected: 0, queued: 0, skipped: 0, needs_review: 0, not_found: 0, unavailable: 0, total: 0 };
+    var allTracks = [];
+    sessions.forEach(function (s) {
+      totals.searched += s.progress.searched;
+      totals.selected += s.progress.selected;
+      totals.queued += s.progress.queued;
+      totals.skipped += s.progress.skipped;
+      totals.needs_review += s.progress.needs_review;
+      totals.not_found += s.progress.not_found;
+      totals.unavailable += s.progress.unavailable;
+      totals.total += s.progress.total;
+      if (s.tracks) {
+        for (var i = 0; i < s.tracks.length; i++) {
+          s.tracks[i]._originalIndex = i;
+          s.tracks[i]._sessionId = s.id;
+          allTracks.push(s.tracks[i]);
+        }
+      }
+    });
 
-        currentTracks = allTracks;
-        totalProgress = totals;
-        renderTracks(sortTracks(currentTracks), null, false);
+    countSearched.textContent = totals.searched;
+    countSelected.textContent = totals.selected;
+    countQueued.textContent = totals.queued;
+    countSkipped.textContent = totals.skipped;
+    countReview.textContent = totals.needs_review;
+    countNotFound.textContent = totals.not_found;
+    countUnavailable.textContent = totals.unavailable;
+    countTotal.textContent = totals.total;
 
-        isPaused = anyPaused;
-        updateControlButtons();
+    currentTracks = allTracks;
+    totalProgress = totals;
+    renderTracks(sortTracks(currentTracks), null, false);
 
-        if (anyPaused) {
-          phaseEl.textContent = "downloading (paused)";
-          phaseEl.classList.add("paused");
-        } else {
-          phaseEl.classList.remove("paused");
-        }
+    isPaused = anyPaused;
+    updateControlButtons();
 
-        if (allDone) {
-          clearInterval(pollTimer);
-          pollTimer = null;
-          phaseEl.textContent = "done";
-          phaseEl.classList.remove("paused");
-          downloadBtn.classList.remove("active");
-          isAnalyzing = false;
-          isPaused = false;
-          
-          addBtn.disabled = false;
-          updateControlButtons();
+    if (anyPaused) {
+      phaseEl.textContent = "downloading (paused)";
+      phaseEl.classList.add("paused");
+    } else {
+      phaseEl.classList.remove("paused");
+    }
 
-          // Check for errors
-          var errors = sessions.filter(function (s) { return s.status === "error"; });
-          if (errors.length > 0) {
-            showError("Some downloads failed");
-          }
-        } else if (!anyPaused) {
-          phaseEl.textContent = "downloading";
-        }
-      })
-      .catch(function () {
-        clearInterval(pollTimer);
-        pollTimer = null;
-        isAnalyzing = false;
-        isPaused = false;
-        
-        addBtn.disabled = false;
-        updateControlButtons();
-      });
+    if (allDone) {
+      closeStreams();
+      phaseEl.textContent = "done";
+      phaseEl.classList.remove("paused");
+      downloadBtn.classList.remove("active");
+      isAnalyzing = false;
+      isPaused = false;
+      
+      addBtn.disabled = false;
+      updateControlButtons();
+
+      // Check for errors
+      var errors = sessions.filter(function (s) { return s.status === "error"; });
+      if (errors.length > 0) {
+        showError("Some downloads failed");
+      }
+    } else if (!anyPaused) {
+      phaseEl.textContent = "downloading";
+    }
   }
 
   function pauseAllSessions() {
@@ -616,7 +700,104 @@
     Promise.all(promises).then(function () {
       isPaused = false;
       updateControlButtons();
+      if (isRestoring) pollRestoredSessions();
+    });
+  }
+
+  // Restore sessions the server kept across a restart: ready sessions go
+  // straight back to review, interrupted ones wait for the resume button.
+  function restoreSessions() {
+    fetch("/api/sessions")
+      .then(function (resp) { return resp.json(); })
+      .then(function (list) {
+        var restorable = list.filter(function (s) {
+          return s.status === "ready" || s.status === "interrupted";
+        });
+        if (restorable.length === 0 || sessionIds.length > 0) return;
+
+        sessionIds = restorable.map(function (s) { return s.id; });
+        isRestoring = true;
+        progressEl.classList.add("active");
+
+        var interrupted = restorable.filter(function (s) { return s.status === "interrupted"; });
+        if (interrupted.length > 0) {
+          isAnalyzing = true;
+          isPaused = true;
+          phaseEl.textContent = interrupted.length + " interrupted";
+          phaseEl.classList.add("paused");
+          updateControlButtons();
+          return;
+        }
+        pollRestoredSessions();
+      })
+      .catch(function () {});
+  }
+
+  function pollRestoredSessions() {
+    streamSessions(sessionIds, function (sessions) {
+      var busy = sessions.some(function (s) {
+        return s.status !== "ready" && s.status !== "done" && s.status !== "error" && s.status !== "canceled";
+      });
+      if (busy) {
+        phaseEl.classList.remove("paused");
+        phaseEl.textContent = "resuming";
+        return;
+      }
+      closeStreams();
+      finishRestore();
+    }, function () {
+      closeStreams();
+      isRestoring = false;
+    });
+  }
+
+  // Load the settled restored sessions in full and show them for review.
+  function finishRestore() {
+    var promises = sessionIds.map(function (sid) {
+      return fetch("/api/session/" + sid).then(function (r) { return r.json(); });
     });
+
+    Promise.all(promises)
+      .then(function (sessions) {
+        isRestoring = false;
+        currentTracks = [];
+        totalProgress = { searched: 0, selected: 0, queued: 0, skipped: 0, needs_review: 0, not_found: 0, unavailable: 0, total: 0 };
+        sessions = sessions.filter(function (s) { return s.status === "ready"; });
+        sessionIds = sessions.map(function (s) { return s.id; });
+        sessions.forEach(function (s) {
+          for (var i = 0; i < s.tracks.length; i++) {
+            s.tracks[i]._originalIndex = i;
+            s.tracks[i]._sessionId = s.id;
+            currentTracks.push(s.tracks[i]);
+          }
+          totalProgress.searched += s.progress.searched;
+          totalProgress.selected += s.progress.selected;
+          totalProgress.queued += s.progress.queued;
+          totalProgress.skipped += s.progress.skipped;
+          totalProgress.needs_review += s.progress.needs_review;
+          totalProgress.not_found += s.progress.not_found;
+          totalProgress.unavailable += s.progress.unavailable;
+          totalProgress.total += s.progress.total;
+        });
+
+        isAnalyzing = false;
+        isPaused = false;
+        updateControlButtons();
+        if (sessions.length === 0) {
+          phaseEl.textContent = "";
+          progressEl.classList.remove("active");
+          return;
+        }
+
+        isReady = true;
+        downloadBtn.classList.add("active");
+        downloadBtn.disabled = false;
+        renderSession({ tracks: currentTracks, progress: totalProgress, status: "ready", id: null }, true);
+        renderTracks(sortTracks(currentTracks), null, true);
+      })
+      .catch(function () {
+        isRestoring = false;
+      });
   }
 
   function cancelAllSessions() {
@@ -632,11 +813,8 @@
     });
 
     Promise.all(promises).then(function () {
-      // Stop polling and reset state
-      if (pollTimer) {
-        clearInterval(pollTimer);
-        pollTimer = null;
-      }
+      // Stop streaming and reset state
+      closeStreams();
       isAnalyzing = false;
       isReady = false;
       isPaused = false;
@@ -665,111 +843,120 @@
   }
 
   function startPolling() {
-    if (pollTimer) clearInterval(pollTimer);
-    pollTimer = setInterval(pollSession, 800);
-    pollSession();
+    var sid = currentSessionId;
+    streamSessions([sid], function (sessions) {
+      var status = sessions[0].status;
+      if (status === "ready" || status === "done" || status === "error" || status === "canceled") {
+        // Tracks are not streamed while parsing, so load the final session once.
+        closeStreams();
+        fetch("/api/session/" + sid)
+          .then(function (resp) { return resp.json(); })
+          .then(handleSession)
+          .catch(handleSessionFailure);
+        return;
+      }
+      handleSession(sessions[0]);
+    }, function () {
+      closeStreams();
+      handleSessionFailure();
+    });
   }
 
-  function pollSession() {
-    if (!currentSessionId) return;
-
-    fetch("/api/session/" + currentSessionId)
-      .then(function (resp) { return resp.json(); })
-      .then(function (session) {
-        // Handle paused state
-        if (session.status === "paused") {
-          isPaused = true;
-          updateControlButtons();
-          phaseEl.classList.add("paused");
-          var prefix = urlQueue.length > 1 ? "(" + (syncIndex + 1) + "/" + urlQueue.length + ") " : "";
-          phaseEl.textContent = prefix + "paused";
-          return;
-        } else {
-          isPaused = false;
-          phaseEl.classList.remove("paused");
-          updateControlButtons();
-        }
-
-        renderSession(session, false);
-        if (session.status === "ready") {
-          clearInterval(pollTimer);
-          pollTimer = null;
+  function handleSession(session) {
+    // Handle paused state
+    if (session.status === "paused") {
+      isPaused = true;
+      updateControlButtons();
+      phaseEl.classList.add("paused");
+      var prefix = urlQueue.length > 1 ? "(" + (syncIndex + 1) + "/" + urlQueue.length + ") " : "";
+      phaseEl.textContent = prefix + "paused";
+      return;
+    } else {
+      isPaused = false;
+      phaseEl.classList.remove("paused");
+      updateControlButtons();
+    }
 
-          // Accumulate tracks from this session
-          for (var i = 0; i < session.tracks.length; i++) {
-            session.tracks[i]._originalIndex = i;
-            session.tracks[i]._sessionId = session.id;
-            currentTracks.push(session.tracks[i]);
-          }
+    renderSession(session, false);
+    if (session.status === "ready") {
+      // Accumulate tracks from this session
+      for (var i = 0; i < session.tracks.length; i++) {
+        session.tracks[i]._originalIndex = i;
+        session.tracks[i]._sessionId = session.id;
+        currentTracks.push(session.tracks[i]);
+      }
 
-          // Accumulate progress stats
-          totalProgress.searched += session.progress.searched;
-          totalProgress.selected += session.progress.selected;
-          totalProgress.queued += session.progress.queued;
-          totalProgress.skipped += session.progress.skipped;
-          totalProgress.needs_review += session.progress.needs_review;
-          totalProgress.not_found += session.progress.not_found;
-          totalProgress.total += session.progress.total;
-
-          syncIndex++;
-          if (syncIndex < urlQueue.length) {
-            // More URLs to analyze - continue
-            analyzeNext();
-          } else {
-            // All done - show accumulated results
-            isReady = true;
-            isAnalyzing = false;
-            isPaused = false;
-            
-            downloadBtn.classList.add("active");
-            downloadBtn.disabled = false;
-            addBtn.disabled = false;
-            phaseEl.textContent = "ready";
-            updateControlButtons();
-            renderSession({ tracks: currentTracks, progress: totalProgress, status: "ready", id: null }, true);
-            renderTracks(sortTracks(currentTracks), null, true);
-          }
-        } else if (session.status === "done" || session.status === "error" || session.status === "canceled") {
-          clearInterval(pollTimer);
-          pollTimer = null;
-          isReady = false;
-          isPaused = false;
-          downloadBtn.classList.remove("active");
-          updateControlButtons();
-          if (session.status === "error") {
-            showError(session.error || "Failed for: " + (urlQueue[syncIndex] ? urlQueue[syncIndex].url : currentSessionId));
-          }
-          if (session.status === "canceled") {
-            phaseEl.textContent = "canceled";
-          }
-          syncIndex++;
-          if (isAnalyzing && syncIndex < urlQueue.length) {
-            analyzeNext();
-          } else {
-            isAnalyzing = false;
-            addBtn.disabled = false;
-            updateControlButtons();
-          }
-        }
-      })
-      .catch(function () {
-        clearInterval(pollTimer);
-        pollTimer = null;
-        syncIndex++;
+      // Accumulate progress stats
+      totalProgress.searched += session.progress.searched;
+      totalProgress.selected += session.progress.selected;
+      totalProgress.queued += session.progress.queued;
+      totalProgress.skipped += session.progress.skipped;
+      totalProgress.needs_review += session.progress.needs_review;
+      totalProgress.not_found += session.progress.not_found;
+      totalProgress.unavailable += session.progress.unavailable;
+      totalProgress.total += session.progress.total;
+
+      syncIndex++;
+      if (syncIndex < urlQueue.length) {
+        // More URLs to analyze - continue
+        analyzeNext();
+      } else {
+        // All done - show accumulated results
+        isReady = true;
+        isAnalyzing = false;
         isPaused = false;
-        if (isAnalyzing && syncIndex < urlQueue.length) {
-          analyzeNext();
-        } else {
-          isAnalyzing = false;
-          addBtn.disabled = false;
-          updateControlButtons();
-        }
-      });
+        
+        downloadBtn.classList.add("active");
+        downloadBtn.disabled = false;
+        addBtn.disabled = false;
+        phaseEl.textContent = "ready";
+        updateControlButtons();
+        renderSession({ tracks: currentTracks, progress: totalProgress, status: "ready", id: null }, true);
+        renderTracks(sortTracks(currentTracks), null, true);
+      }
+    } else if (session.status === "done" || session.status === "error" || session.status === "canceled") {
+      isReady = false;
+      isPaused = false;
+      downloadBtn.classList.remove("active");
+      updateControlButtons();
+      if (session.status === "error") {
+        showError(session.error || "Failed for: " + (urlQueue[syncIndex] ? urlQueue[syncIndex].url : currentSessionId));
+      }
+      if (session.status === "canceled") {
+        phaseEl.textContent = "canceled";
+      }
+      syncIndex++;
+      if (isAnalyzing && syncIndex < urlQueue.length) {
+        analyzeNext();
+      } else {
+        isAnalyzing = false;
+        addBtn.disabled = false;
+        updateControlButtons();
+      }
+    }
+  }
+
+  function handleSessionFailure() {
+    syncIndex++;
+    isPaused = false;
+    if (isAnalyzing && syncIndex < urlQueue.length) {
+      analyzeNext();
+    } else {
+      isAnalyzing = false;
+      addBtn.disabled = false;
+      updateControlButtons();
+    }
   }
 
   function renderSession(session, isFinal) {
     var prefix = urlQueue.length > 1 ? "(" + (syncIndex + 1) + "/" + urlQueue.length + ") " : "";
-    phaseEl.textContent = prefix + session.status;
+    if (session.status === "queued" && session.queue_position) {
+      phaseEl.textContent = prefix + "queued (#" + session.queue_position + ")";
+    } else if (session.status === "fetching" && session.progress && session.progress.fetched) {
+      phaseEl.textContent = prefix + "fetching (" + session.progress.fetched + " found)";
+    } else {
+      phaseEl.textContent = prefix + session.status;
+    }
 
     // For in-progress sessions, show current session stats
     // For final render, show accumulated totals
@@ -780,6 +967,7 @@
       countSkipped.textContent = totalProgress.skipped;
       countReview.textContent = totalProgress.needs_review;
       countNotFound.textContent = totalProgress.not_found;
+      countUnavailable.textContent = totalProgress.unavailable;
       countTotal.textContent = totalProgress.total;
     } else {
       // Show current session progress + accumulated from previous sessions
@@ -789,6 +977,7 @@
       countSkipped.textContent = totalProgress.skipped + session.progress.skipped;
       countReview.textContent = totalProgress.needs_review + session.progress.needs_review;
       countNotFound.textContent = totalProgress.not_found + session.progress.not_found;
+      countUnavailable.textContent = totalProgress.unavailable + session.progress.unavailable;
       countTotal.textContent = totalProgress.total + session.progress.total;
     }
 
@@ -838,7 +1027,7 @@
       checkbox.checked = t.selected;
       checkbox.dataset.index = t._originalIndex !== undefined ? t._originalIndex : i;
       checkbox.dataset.sid = trackSid;
-      checkbox.disabled = !editable || t.status === "downloaded";
+      checkbox.disabled = !editable || t.status === "downloaded" || t.status === "unavailable";
       checkbox.addEventListener("change", function () {
         toggleTrackSelection(this.dataset.sid, parseInt(this.dataset.index, 10), this.checked);
       });
@@ -857,6 +1046,9 @@
       songSpan.textContent = t.parsed_song || t.youtube_title;
       tdTitle.appendChild(songSpan);
       tdTitle.title = t.youtube_title;
+      if (t.segment) {
+        tdTitle.title += "\nFrom " + t.segment.video_title + " at " + formatTimestamp(t.segment.start);
+      }
 
       var tdMatched = document.createElement("td");
       tdMatched.className = "matched-as";
@@ -898,8 +1090,23 @@
           });
           tdResult.appendChild(searchBtn);
         }
-      } else if (editable && t.status === "not_found") {
-        // Show search input for not found tracks
+
+        // Let the user pick another ranked candidate
+        if (editable && t.status !== "downloaded" && t.candidates && t.candidates.length > 1) {
+          var altBtn = document.createElement("button");
+          altBtn.className = "search-btn";
+          altBtn.textContent = "\u25BE"; // down triangle
+          altBtn.title = "Pick another candidate";
+          altBtn.dataset.index = t._originalIndex !== undefined ? t._originalIndex : i;
+          altBtn.dataset.sid = trackSid;
+          altBtn._track = t;
+          altBtn.addEventListener("click", function () {
+            showCandidateSelect(this.parentElement, this.dataset.sid, parseInt(this.dataset.index, 10), this._track);
+          });
+          tdResult.appendChild(altBtn);
+        }
+      } else if (editable && (t.status === "not_found" || t.status === "error")) {
+        // Show search input for not found tracks and failed searches
         createSearchInput(tdResult, trackSid, t._originalIndex !== undefined ? t._originalIndex : i);
       } else {
         tdResult.textContent = "\u2014";
@@ -909,6 +1116,14 @@
       tdConfidence.className = "col-confidence";
       if (t.confidence > 0) {
         tdConfidence.textContent = t.confidence + "%";
+        var hints = [];
+        if (t.match_strategy) {
+          hints.push("Found by " + strategyLabel(t.match_strategy) + " search");
+        }
+        if (t.duration_delta) {
+          hints.push("Deezer track is " + Math.abs(t.duration_delta) + "s " + (t.duration_delta > 0 ? "longer" : "shorter"));
+        }
+        tdConfidence.title = hints.join("\n");
       } else {
         tdConfidence.textContent = "\u2014";
       }
@@ -916,7 +1131,7 @@
       var tdStatus = document.createElement("td");
       tdStatus.className = "status-icon status-" + t.status;
       tdStatus.textContent = statusIcon(t.status);
-      tdStatus.title = statusTooltip(t.status);
+      tdStatus.title = t.error ? statusTooltip(t.status) + ": " + t.error : statusTooltip(t.status);
 
       tr.appendChild(tdSelect);
       tr.appendChild(tdTitle);
@@ -929,6 +1144,32 @@
 
     // Update select all checkbox state
     updateSelectAllState();
+    renderExportLinks();
+  }
+
+  // Show export links for every session with tracks on screen.
+  function renderExportLinks() {
+    clearElement(exportLinks);
+    var seen = {};
+    var sids = [];
+    for (var i = 0; i < currentTracks.length; i++) {
+      var sid = currentTracks[i]._sessionId;
+      if (sid && !seen[sid]) {
+        seen[sid] = true;
+        sids.push(sid);
+      }
+    }
+    sids.forEach(function (sid, n) {
+      var label = document.createElement("span");
+      label.textContent = sids.length > 1 ? "export #" + (n + 1) + ":" : "export:";
+      exportLinks.appendChild(label);
+      ["csv", "json", "m3u8"].forEach(function (format) {
+        var a = document.createElement("a");
+        a.href = "/api/session/" + sid + "/export?format=" + format;
+        a.textContent = format;
+        exportLinks.appendChild(a);
+      });
+    });
   }
 
   function toggleTrackSelection(sid, index, selected) {
@@ -987,6 +1228,62 @@
     input.focus();
   }
 
+  function showCandidateSelect(container, sid, index, track) {
+    clearElement(container);
+    var select = document.createElement("select");
+    select.className = "search-input";
+    for (var i = 0; i < track.candidates.length; i++) {
+      var c = track.candidates[i];
+      var opt = document.createElement("option");
+      opt.value = c.id;
+      opt.textContent = c.confidence + "% " + c.artist + " - " + c.title + (c.album ? " (" + c.album + ")" : "");
+      if (track.deezer_match && track.deezer_match.id === c.id) opt.selected = true;
+      select.appendChild(opt);
+    }
+    select.addEventListener("change", function () {
+      setTrackMatch(sid, index, parseInt(this.value, 10));
+    });
+    container.appendChild(select);
+    select.focus();
+  }
+
+  function setTrackMatch(sid, index, deezerId) {
+    fetch("/api/session/" + sid + "/track/" + index + "/match", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ deezer_id: deezerId }),
+    })
+      .then(function (resp) {
+        if (!resp.ok) return resp.json().then(function (d) { throw new Error(d.error); });
+        return resp.json();
+      })
+      .then(function (data) {
+        updateTrackMatch(sid, index, data);
+      })
+      .catch(function (err) {
+        showError(err.message || "Failed to set match");
+      });
+  }
+
+  function updateTrackMatch(sid, index, data) {
+    for (var i = 0; i < currentTracks.length; i++) {
+      if (currentTracks[i]._sessionId === sid && currentTracks[i]._originalIndex === index) {
+        var t = currentTracks[i];
+        if (t.selected !== data.selected) {
+          totalProgress.selected += data.selected ? 1 : -1;
+          countSelected.textContent = totalProgress.selected;
+        }
+        t.deezer_match = data.deezer_match;
+        t.confidence = data.confidence;
+        t.match_strategy = data.match_strategy;
+        t.status = data.status;
+        t.selected = data.selected;
+        break;
+      }
+    }
+    renderTracks(sortTracks(currentTracks), null, isReady);
+  }
+
   function searchTrack(sid, index, query) {
     if (!query.trim()) return;
 
@@ -1005,6 +1302,7 @@
           if (currentTracks[i]._sessionId === sid && currentTracks[i]._originalIndex === index) {
             currentTracks[i].deezer_match = data.deezer_match;
             currentTracks[i].confidence = data.confidence;
+            currentTracks[i].match_strategy = data.match_strategy;
             currentTracks[i].status = data.status;
             currentTracks[i].selected = data.selected;
             break;
@@ -1032,11 +1330,14 @@
     switch (status) {
       case "searching": return "\u22EF";
       case "found": return "\u2713";
+      case "queued": return "\u2026";
+      case "downloading": return "\u21E3";
       case "downloaded": return "\u2B07";
       case "skipped": return "\u2205";
       case "needs_review": return "?";
       case "not_found": return "\u2717";
       case "error": return "!";
+      case "unavailable": return "\u29B8";
       default: return "\u2014";
     }
   }
@@ -1045,15 +1346,30 @@
     switch (status) {
       case "searching": return "Searching...";
       case "found": return "Found on Deezer";
+      case "queued": return "Queued in Deemix";
+      case "downloading": return "Downloading...";
       case "downloaded": return "Downloaded";
       case "skipped": return "Already in library";
       case "needs_review": return "Low confidence - review match";
       case "not_found": return "Not found on Deezer";
       case "error": return "Error";
+      case "unavailable": return "Unavailable on YouTube";
       default: return "";
     }
   }
 
+  function strategyLabel(strategy) {
+    switch (strategy) {
+      case "isrc": return "ISRC";
+      case "structured": return "artist and title";
+      case "combined": return "free-text";
+      case "song_only": return "title-only";
+      case "cleaned": return "cleaned-up";
+      case "manual": return "manual";
+      default: return strategy;
+    }
+  }
+
   function truncate(str, len) {
     if (str.length <= len) return str;
     return str.substring(0, len - 1) + "\u2026";
@@ -1105,6 +1421,7 @@
     countSkipped.textContent = "0";
     countReview.textContent = "0";
     countNotFound.textContent = "0";
+    countUnavailable.textContent = "0";
     countTotal.textContent = "0";
   }
 
@@ -1130,6 +1447,30 @@
       .catch(function () {});
   }
 
+  function fetchDeemixStatus() {
+    fetch("/api/deemix/status")
+      .then(function (resp) { return resp.json(); })
+      .then(function (data) {
+        var msg = "";
+        if (data.state === "expired") {
+          msg = "Deemix session expired and logging in again failed. Downloads will fail until Deemix is reachable.";
+        } else if (data.state === "invalid") {
+          msg = "Deezer rejected the ARL token. Update DEEMIX_ARL and restart.";
+        }
+        authMsg.textContent = msg;
+        authMsg.classList.toggle("active", msg !== "");
+      })
+      .catch(function () {});
+  }
+
+  function formatTimestamp(sec) {
+    var h = Math.floor(sec / 3600);
+    var m = Math.floor((sec % 3600) / 60);
+    var s = sec % 60;
+    var mm = (h > 0 && m < 10 ? "0" : "") + m;
+    return (h > 0 ? h + ":" : "") + mm + ":" + (s < 10 ? "0" : "") + s;
+  }
+
   function formatUptime(sec) {
     var h = Math.floor(sec / 3600);
     var m = Math.floor((sec % 3600) / 60);
@@ -1154,6 +1495,11 @@
     })
     .catch(function () {});
 
+  restoreSessions();
   fetchStats();
-  setInterval(fetchStats, 10000);
+  fetchDeemixStatus();
+  setInterval(function () {
+    fetchStats();
+    fetchDeemixStatus();
+  }, 10000);
 })();